@@ -31,6 +31,8 @@ func main() {
 	inputDir := "./sample_migrations"
 	outputDir := "./output"
 	verify := false
+	strict := false
+	createRoles := false
 
 	// Parse command line arguments
 	args := []string{}
@@ -44,6 +46,10 @@ func main() {
 			os.Exit(0)
 		} else if arg == "-v" || arg == "--verify" {
 			verify = true
+		} else if arg == "--strict" {
+			strict = true
+		} else if arg == "--create-roles" {
+			createRoles = true
 		} else {
 			args = append(args, arg)
 		}
@@ -84,6 +90,8 @@ func main() {
 
 	// Run consolidation
 	c := consolidator.NewConsolidator(inputDir, outputDir, false)
+	c.SetStrict(strict)
+	c.SetCreateRoles(createRoles)
 	if err := c.Consolidate(false); err != nil {
 		printError(fmt.Sprintf("Consolidation failed: %v", err))
 		os.Exit(1)
@@ -131,6 +139,8 @@ func printUsage() {
 	fmt.Printf("\n%sOptions:%s\n", colorBold, colorReset)
 	fmt.Printf("  %s-V, --version%s  Show version information\n", colorYellow, colorReset)
 	fmt.Printf("  %s-v, --verify%s   Verify consolidated migrations with PostgreSQL (requires Docker)\n", colorYellow, colorReset)
+	fmt.Printf("  %s--strict%s       Fail if any statement is skipped or only partly understood\n", colorYellow, colorReset)
+	fmt.Printf("  %s--create-roles%s Create the roles that own objects or are granted privileges\n", colorYellow, colorReset)
 	fmt.Printf("  %s-h, --help%s     Show this help message\n", colorYellow, colorReset)
 	fmt.Printf("\n%sArguments:%s\n", colorBold, colorReset)
 	fmt.Printf("  %sinput_dir%s   Directory containing migration files (default: ./sample_migrations)\n", colorYellow, colorReset)
@@ -139,6 +149,8 @@ func printUsage() {
 	fmt.Printf("  %sschemactor%s\n", colorGray, colorReset)
 	fmt.Printf("  %sschemactor --version%s\n", colorGray, colorReset)
 	fmt.Printf("  %sschemactor --verify%s\n", colorGray, colorReset)
+	fmt.Printf("  %sschemactor --strict ./migrations ./consolidated%s\n", colorGray, colorReset)
+	fmt.Printf("  %sschemactor --verify --create-roles ./migrations ./consolidated%s\n", colorGray, colorReset)
 	fmt.Printf("  %sschemactor ./migrations ./consolidated%s\n", colorGray, colorReset)
 	fmt.Printf("  %sschemactor --verify ./migrations ./consolidated%s\n", colorGray, colorReset)
 	fmt.Println()
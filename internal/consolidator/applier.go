@@ -13,6 +13,9 @@ import (
 type Applier struct {
 	state            *state.DatabaseState
 	currentMigration int
+	current          *parser.Statement
+	diagnostics      []parser.Diagnostic
+	searchPath       []string // Set by SET search_path, nil for the default path
 }
 
 // NewApplier creates a new applier
@@ -23,12 +26,100 @@ func NewApplier(dbState *state.DatabaseState) *Applier {
 }
 
 // SetCurrentMigration sets the current migration number being processed
+// Each migration file runs in a session of its own, so the search path is reset to the default
 func (a *Applier) SetCurrentMigration(migrationNumber int) {
 	a.currentMigration = migrationNumber
+	a.searchPath = nil
+}
+
+// Diagnostics returns the statements that were only partly applied
+func (a *Applier) Diagnostics() []parser.Diagnostic {
+	return a.diagnostics
+}
+
+// warn records a diagnostic for the statement being applied
+func (a *Applier) warn(format string, args ...interface{}) {
+	a.diagnostics = append(a.diagnostics, parser.NewDiagnostic(a.current, format, args...))
+}
+
+// schemaPath returns the schemas of the search path that unqualified names are looked up in
+// "$user" and the system schemas never hold objects of the migrations, so they are left out
+func (a *Applier) schemaPath() []string {
+	if a.searchPath == nil {
+		return []string{state.DefaultSchema}
+	}
+
+	var path []string
+	for _, schema := range a.searchPath {
+		if schema != "$user" && schema != "pg_catalog" && schema != "pg_temp" {
+			path = append(path, schema)
+		}
+	}
+	return path
+}
+
+// resolveSchema returns the schema an object name lands in
+// Unqualified names land in the first schema of the search path that exists
+func (a *Applier) resolveSchema(schema string) string {
+	if schema != "" {
+		return schema
+	}
+
+	path := a.schemaPath()
+	for _, candidate := range path {
+		if a.state.HasSchema(candidate) {
+			return candidate
+		}
+	}
+	if len(path) > 0 {
+		return path[0]
+	}
+	return state.DefaultSchema
+}
+
+// qualify returns the state key for a possibly unqualified object name
+// Unqualified names resolve to the first schema of the search path holding such an object,
+// or else to the schema a new object of that name would land in
+func (a *Applier) qualify(schema, name string) string {
+	if schema == "" {
+		for _, candidate := range a.schemaPath() {
+			if key := state.QualifiedName(candidate, name); a.state.HasObject(key) {
+				return key
+			}
+		}
+	}
+	return state.QualifiedName(a.resolveSchema(schema), name)
+}
+
+// qualifyReference returns the state key for a referenced name as written in SQL
+func (a *Applier) qualifyReference(ref string) string {
+	schema, name := parser.SplitQualifiedName(strings.TrimSpace(ref))
+	return a.qualify(schema, name)
+}
+
+// resolveType qualifies a type as written when the search path finds it outside the default schema
+func (a *Applier) resolveType(typ string) string {
+	return a.state.QualifyType(typ, a.qualifyReference)
+}
+
+// resolveDefault qualifies the sequences of a default's nextval() calls found outside the default schema
+func (a *Applier) resolveDefault(expr string) string {
+	return a.state.QualifyNextval(expr, a.qualifyReference)
 }
 
 // Apply applies a statement to the database state
+// Failures are returned as *ApplyError, carrying the statement's position
 func (a *Applier) Apply(stmt *parser.Statement) error {
+	a.current = stmt
+
+	if err := a.apply(stmt); err != nil {
+		return &ApplyError{Pos: stmt.Pos, Statement: stmt.Original, Err: err}
+	}
+	return nil
+}
+
+// apply dispatches a statement to the handler for its type
+func (a *Applier) apply(stmt *parser.Statement) error {
 	switch stmt.Type {
 	case parser.CreateTable:
 		return a.applyCreateTable(stmt)
@@ -44,16 +135,69 @@ func (a *Applier) Apply(stmt *parser.Statement) error {
 		return a.applyDropType(stmt)
 	case parser.CreateDomain:
 		return a.applyCreateDomain(stmt)
+	case parser.AlterDomain:
+		return a.applyAlterDomain(stmt)
 	case parser.DropDomain:
 		return a.applyDropDomain(stmt)
 	case parser.CreateView:
 		return a.applyCreateView(stmt)
 	case parser.DropView:
 		return a.applyDropView(stmt)
+	case parser.CreateMaterializedView:
+		return a.applyCreateMaterializedView(stmt)
+	case parser.DropMaterializedView:
+		return a.applyDropMaterializedView(stmt)
+	case parser.RefreshMaterializedView:
+		// Refreshing changes only the rows, not the schema
+		return nil
 	case parser.CreateIndex:
 		return a.applyCreateIndex(stmt)
 	case parser.DropIndex:
 		return a.applyDropIndex(stmt)
+	case parser.AlterIndex:
+		return a.applyAlterIndex(stmt)
+	case parser.CreateSequence:
+		return a.applyCreateSequence(stmt)
+	case parser.AlterSequence:
+		return a.applyAlterSequence(stmt)
+	case parser.DropSequence:
+		return a.applyDropSequence(stmt)
+	case parser.CreateFunction:
+		return a.applyCreateFunction(stmt)
+	case parser.DropFunction:
+		return a.applyDropFunction(stmt)
+	case parser.CreateTrigger:
+		return a.applyCreateTrigger(stmt)
+	case parser.AlterTrigger:
+		return a.applyAlterTrigger(stmt)
+	case parser.DropTrigger:
+		return a.applyDropTrigger(stmt)
+	case parser.CreatePolicy:
+		return a.applyCreatePolicy(stmt)
+	case parser.AlterPolicy:
+		return a.applyAlterPolicy(stmt)
+	case parser.DropPolicy:
+		return a.applyDropPolicy(stmt)
+	case parser.Grant, parser.Revoke:
+		return a.applyGrant(stmt)
+	case parser.AlterDefaultPrivileges:
+		return a.applyAlterDefaultPrivileges(stmt)
+	case parser.AlterOwner:
+		return a.applyAlterOwner(stmt)
+	case parser.CreateExtension:
+		return a.applyCreateExtension(stmt)
+	case parser.AlterExtension:
+		return a.applyAlterExtension(stmt)
+	case parser.DropExtension:
+		return a.applyDropExtension(stmt)
+	case parser.CreateSchema:
+		return a.applyCreateSchema(stmt)
+	case parser.AlterSchema:
+		return a.applyAlterSchema(stmt)
+	case parser.DropSchema:
+		return a.applyDropSchema(stmt)
+	case parser.SetSearchPath:
+		return a.applySetSearchPath(stmt)
 	case parser.Comment:
 		return a.applyComment(stmt)
 	case parser.DoBlock:
@@ -69,20 +213,50 @@ func (a *Applier) applyCreateTable(stmt *parser.Statement) error {
 		return fmt.Errorf("invalid CREATE TABLE details")
 	}
 
-	table := state.NewTable(details.TableName)
+	table := state.NewTable(a.resolveSchema(details.Schema), details.TableName)
 	table.CreatedIn = a.currentMigration
+	table.Privileges = a.state.NewPrivileges("TABLE", table.Schema)
+	table.PartitionStrategy = details.PartitionStrategy
+	table.PartitionKey = details.PartitionKey
+	table.Unlogged = details.Unlogged
+	table.AccessMethod = details.AccessMethod
+	table.StorageParams = details.StorageParams
+	table.Tablespace = details.Tablespace
+	for _, parent := range details.Inherits {
+		parentName := a.qualifyReference(parent)
+		if _, exists := a.state.GetTable(parentName); !exists {
+			a.warn("INHERITS %s, which no earlier migration creates", parentName)
+		}
+		table.Inherits = append(table.Inherits, parentName)
+	}
 
-	// Parse the table definition to extract columns, constraints, etc.
-	a.parseTableDefinition(table, details.Definition)
+	if details.PartitionOf == "" {
+		// Parse the table definition to extract columns, constraints, etc.
+		a.parseTableDefinition(table, details.Definition)
+		a.state.AddOrUpdateTable(table)
+		return nil
+	}
 
+	// A partition takes its columns from the parent, only its own constraints are tracked
+	for _, part := range parser.SplitTopLevel(details.Definition, ",") {
+		if part = strings.TrimSpace(part); part != "" && !a.parseTableConstraint(table, part) {
+			a.warn("column options of partition %s are not tracked: %s", table.Name, part)
+		}
+	}
 	a.state.AddOrUpdateTable(table)
 
+	parent := a.qualify(details.ParentSchema, details.PartitionOf)
+	if _, exists := a.state.GetTable(parent); !exists {
+		a.warn("PARTITION OF %s, which no earlier migration creates", parent)
+	}
+	a.state.AttachPartition(parent, table.QualifiedName(), details.PartitionBound)
+
 	return nil
 }
 
 func (a *Applier) parseTableDefinition(table *state.Table, definition string) {
 	// Split by comma at top level (not within parentheses)
-	parts := splitTopLevel(definition, ',')
+	parts := parser.SplitTopLevel(definition, ",")
 
 	for _, part := range parts {
 		part = strings.TrimSpace(part)
@@ -90,191 +264,432 @@ func (a *Applier) parseTableDefinition(table *state.Table, definition string) {
 			continue
 		}
 
-		// Check if it's a constraint or column
-		upperPart := strings.ToUpper(part)
+		// Check if it's a LIKE clause, a constraint or a column
+		if !a.applyLike(table, part) && !a.parseTableConstraint(table, part) {
+			a.parseColumnDefinition(table, part)
+		}
+	}
+}
+
+// applyLike copies the columns of the table named by a LIKE clause, along with what its INCLUDING options ask for
+// Returns false if the definition is not a LIKE clause
+func (a *Applier) applyLike(table *state.Table, def string) bool {
+	likeRe := regexp.MustCompile(`(?is)^LIKE\s+(` + parser.NamePattern + `)((?:\s+(?:INCLUDING|EXCLUDING)\s+\w+)*)\s*$`)
+	matches := likeRe.FindStringSubmatch(def)
+	if matches == nil {
+		return false
+	}
+
+	sourceName := a.qualifyReference(matches[1])
+	source, exists := a.state.GetTable(sourceName)
+	if !exists {
+		a.warn("LIKE %s, which no earlier migration creates", sourceName)
+		return true
+	}
 
-		if strings.HasPrefix(upperPart, "PRIMARY KEY") {
-			a.parsePrimaryKey(table, part)
-		} else if strings.HasPrefix(upperPart, "FOREIGN KEY") {
-			a.parseForeignKey(table, part)
-		} else if strings.HasPrefix(upperPart, "UNIQUE") {
-			a.parseUnique(table, part)
-		} else if strings.HasPrefix(upperPart, "CHECK") {
-			a.parseCheck(table, part)
+	// Later options override earlier ones, e.g. INCLUDING ALL EXCLUDING COMMENTS
+	including := make(map[string]bool)
+	optionRe := regexp.MustCompile(`(?i)(INCLUDING|EXCLUDING)\s+(\w+)`)
+	for _, option := range optionRe.FindAllStringSubmatch(matches[2], -1) {
+		include := strings.EqualFold(option[1], "INCLUDING")
+		if what := strings.ToUpper(option[2]); what == "ALL" {
+			for _, each := range []string{"COMMENTS", "COMPRESSION", "CONSTRAINTS", "DEFAULTS", "GENERATED", "IDENTITY", "INDEXES", "STORAGE"} {
+				including[each] = include
+			}
 		} else {
-			// It's a column definition
-			a.parseColumnDefinition(table, part)
+			including[what] = include
+		}
+	}
+
+	// Columns always keep their type, collation and NOT NULL
+	root := a.state.PartitionRoot(source)
+	for _, colName := range root.ColumnOrder {
+		col := root.Columns[colName]
+		if _, serial := state.SerialBaseType(col.Type); serial && including["DEFAULTS"] {
+			// The copied default takes values from the source column's sequence
+			a.expandSerial(root, col)
+		}
+
+		copied := &state.Column{Name: col.Name, Type: col.Type, Collation: col.Collation, Nullable: col.Nullable}
+		if baseType, serial := state.SerialBaseType(col.Type); serial {
+			copied.Type, copied.Nullable = baseType, false
+		}
+		if including["DEFAULTS"] {
+			copied.Default = col.Default
+		}
+		if including["GENERATED"] {
+			copied.Generated = col.Generated
+		}
+		if including["IDENTITY"] && col.Identity != "" {
+			copied.Identity = col.Identity
+			copied.IdentityOptions = append([]string(nil), col.IdentityOptions...)
+		}
+		if including["STORAGE"] {
+			copied.Storage = col.Storage
+		}
+		if including["COMPRESSION"] {
+			copied.Compression = col.Compression
+		}
+		if comment, ok := root.ColumnComments[colName]; ok && including["COMMENTS"] {
+			table.SetColumnComment(colName, comment)
+		}
+		table.AddColumn(copied)
+	}
+
+	if including["CONSTRAINTS"] {
+		for _, check := range root.Checks {
+			copied := *check
+			table.AddCheck(&copied)
+		}
+	}
+
+	// Indexes and the constraints backed by them are named after the new table
+	if including["INDEXES"] {
+		if source.PrimaryKey != nil {
+			table.SetPrimaryKey(&state.PrimaryKey{Columns: append([]string(nil), source.PrimaryKey.Columns...)})
+		}
+		for _, unique := range source.Uniques {
+			table.AddUnique(&state.UniqueConstraint{Columns: append([]string(nil), unique.Columns...)})
+		}
+		for _, idx := range source.Indexes {
+			copied := idx.Copy()
+			copied.Schema = table.Schema
+			copied.Name = a.chooseIndexName(table.Schema, copied.DefaultName(table.Name))
+			a.state.AddIndex(copied)
+			table.AddIndex(copied)
 		}
 	}
+
+	return true
 }
 
-func (a *Applier) parseColumnDefinition(table *state.Table, def string) {
-	parts := strings.Fields(def)
-	if len(parts) < 2 {
+// chooseIndexName returns the given index name, with a number appended when an index of that name exists,
+// as PostgreSQL does for indexes it names itself
+func (a *Applier) chooseIndexName(schema, name string) string {
+	candidate := name
+	for i := 1; ; i++ {
+		if _, exists := a.state.GetIndex(state.QualifiedName(schema, candidate)); !exists {
+			return candidate
+		}
+		candidate = fmt.Sprintf("%s%d", name, i)
+	}
+}
+
+// parseTableConstraint parses a table-level constraint, optionally named with CONSTRAINT name
+// Used for both CREATE TABLE definitions and ALTER TABLE ADD CONSTRAINT
+// Returns false if the definition is not a table constraint
+func (a *Applier) parseTableConstraint(table *state.Table, def string) bool {
+	var name string
+	nameRe := regexp.MustCompile(`(?i)^CONSTRAINT\s+(` + parser.IdentPattern + `)\s+`)
+	if matches := nameRe.FindStringSubmatch(def); len(matches) >= 2 {
+		name = parser.NormalizeIdentifier(matches[1])
+		def = def[len(matches[0]):]
+	}
+
+	kindRe := regexp.MustCompile(`(?i)^(PRIMARY\s+KEY|FOREIGN\s+KEY|UNIQUE|CHECK|EXCLUDE)\b`)
+	kind := kindRe.FindString(def)
+	if kind == "" {
+		return false
+	}
+
+	if strings.EqualFold(kind, "EXCLUDE") {
+		a.warn("EXCLUDE constraint on %s skipped, exclusion constraints are not supported", table.Name)
+		return true
+	}
+
+	// PRIMARY KEY/UNIQUE USING INDEX promote an existing index to a constraint
+	usingIndexRe := regexp.MustCompile(`(?i)USING\s+INDEX\s+(` + parser.IdentPattern + `)`)
+	if matches := usingIndexRe.FindStringSubmatch(def); len(matches) >= 2 {
+		a.promoteIndex(table, strings.ToUpper(kind), parser.NormalizeIdentifier(matches[1]), name)
+		return true
+	}
+
+	switch strings.ToUpper(strings.Join(strings.Fields(kind), " ")) {
+	case "PRIMARY KEY":
+		a.parsePrimaryKey(table, def, name)
+	case "FOREIGN KEY":
+		a.parseForeignKey(table, def, name)
+	case "UNIQUE":
+		a.parseUnique(table, def, name)
+	case "CHECK":
+		a.parseCheck(table, def, name)
+	}
+
+	return true
+}
+
+// promoteIndex turns an existing unique index into a PRIMARY KEY or UNIQUE constraint
+// The constraint takes over the index, which takes the constraint name
+func (a *Applier) promoteIndex(table *state.Table, kind, indexName, name string) {
+	idx, exists := a.state.GetIndex(state.QualifiedName(table.Schema, indexName))
+	if !exists {
+		a.warn("constraint on %s uses unknown index %s, skipped", table.Name, indexName)
+		return
+	}
+	columns, ok := idx.ColumnNames()
+	if !ok {
+		a.warn("constraint on %s uses expression index %s, skipped", table.Name, indexName)
 		return
 	}
+	if name == "" {
+		name = indexName
+	}
 
-	col := &state.Column{
-		Name:     parts[0],
-		Nullable: true,
+	if strings.HasPrefix(kind, "PRIMARY") {
+		table.SetPrimaryKey(&state.PrimaryKey{Name: name, Columns: columns})
+	} else {
+		table.AddUnique(&state.UniqueConstraint{Name: name, Columns: columns})
 	}
 
-	// Parse type
-	typeIdx := 1
-	colType := parts[typeIdx]
-
-	// Handle multi-word types (double precision, character varying, etc.)
-	if typeIdx+1 < len(parts) {
-		nextWord := strings.ToLower(parts[typeIdx+1])
-		// Check if next word is part of a multi-word type
-		if nextWord == "precision" || nextWord == "varying" {
-			colType += " " + parts[typeIdx+1]
-			typeIdx++
-		} else if nextWord == "with" || nextWord == "without" {
-			// timestamp with time zone, timestamp without time zone
-			if typeIdx+2 < len(parts) && strings.ToLower(parts[typeIdx+2]) == "time" {
-				if typeIdx+3 < len(parts) && strings.ToLower(parts[typeIdx+3]) == "zone" {
-					colType += " " + parts[typeIdx+1] + " " + parts[typeIdx+2] + " " + parts[typeIdx+3]
-					typeIdx += 3
-				}
-			} else if typeIdx+2 < len(parts) && strings.ToLower(parts[typeIdx+2]) == "zone" {
-				// time with zone, time without zone
-				colType += " " + parts[typeIdx+1] + " " + parts[typeIdx+2]
-				typeIdx += 2
-			}
+	a.state.DropIndex(idx.QualifiedName())
+	var remainingIndexes []*state.Index
+	for _, existing := range table.Indexes {
+		if existing != idx {
+			remainingIndexes = append(remainingIndexes, existing)
 		}
 	}
+	table.Indexes = remainingIndexes
+}
+
+func (a *Applier) parseColumnDefinition(table *state.Table, def string) {
+	// The column name may be quoted and contain spaces, so read it first
+	name, rest := parser.ReadIdentifier(def)
+	if name == "" {
+		a.warn("could not parse column definition on %s: %s", table.Name, def)
+		return
+	}
+
+	// Parse type, which may span several words as in double precision or varchar(255)
+	colType, remaining := parser.ReadDataType(rest)
+	if colType == "" {
+		a.warn("could not parse the type of column %s on %s: %s", name, table.Name, def)
+		return
+	}
 
-	// Handle types with parameters like varchar(255)
-	if typeIdx+1 < len(parts) && strings.HasPrefix(parts[typeIdx+1], "(") {
-		colType += " " + parts[typeIdx+1]
-		typeIdx++
-	} else if strings.Contains(colType, "(") {
-		// Type already includes parameters
+	col := &state.Column{
+		Name:     name,
+		Type:     a.resolveType(colType),
+		Nullable: true,
 	}
-	col.Type = colType
 
 	// Parse modifiers
-	remaining := strings.Join(parts[typeIdx+1:], " ")
+	remaining = strings.TrimSpace(remaining)
+
+	// Literals and parenthesized expressions are masked so keywords inside them are ignored
+	masked := maskNested(remaining)
 
 	// Check for NOT NULL
-	if strings.Contains(strings.ToUpper(remaining), "NOT NULL") {
+	if strings.Contains(strings.ToUpper(masked), "NOT NULL") {
 		col.Nullable = false
 	}
 
-	// Extract DEFAULT
-	defaultRe := regexp.MustCompile(`(?i)DEFAULT\s+([^\s,]+(?:\([^)]*\))?)`)
-	if matches := defaultRe.FindStringSubmatch(remaining); len(matches) >= 2 {
-		col.Default = matches[1]
+	// Extract DEFAULT, which runs until the next column constraint keyword
+	defaultRe := regexp.MustCompile(`(?i)\bDEFAULT\s`)
+	if loc := defaultRe.FindStringIndex(masked); loc != nil {
+		end := len(remaining)
+		endRe := regexp.MustCompile(`(?i)\s(?:NOT\s+NULL|NULL|CONSTRAINT|PRIMARY\s+KEY|UNIQUE|CHECK|REFERENCES|GENERATED|COLLATE)\b`)
+		if endLoc := endRe.FindStringIndex(masked[loc[1]:]); endLoc != nil {
+			end = loc[1] + endLoc[0]
+		}
+		col.Default = a.resolveDefault(strings.TrimSpace(remaining[loc[1]:end]))
+	}
+
+	// Extract COLLATE
+	collateRe := regexp.MustCompile(`(?i)\bCOLLATE\s+`)
+	if loc := collateRe.FindStringIndex(masked); loc != nil {
+		nameRe := regexp.MustCompile(`^` + parser.NamePattern)
+		col.Collation = nameRe.FindString(remaining[loc[1]:])
+	}
+
+	// Extract GENERATED ALWAYS AS (expression) STORED
+	generatedRe := regexp.MustCompile(`(?i)\bGENERATED\s+ALWAYS\s+AS\s*\(`)
+	if loc := generatedRe.FindStringIndex(masked); loc != nil {
+		col.Generated = strings.TrimSpace(parser.ExtractParenthesesContent(remaining[loc[1]-1:]))
 	}
 
-	// Check for PRIMARY KEY inline
-	if strings.Contains(strings.ToUpper(remaining), "PRIMARY KEY") {
-		table.PrimaryKey = &state.PrimaryKey{
-			Columns: []string{col.Name},
+	// Extract GENERATED {ALWAYS|BY DEFAULT} AS IDENTITY [(sequence options)]
+	identityRe := regexp.MustCompile(`(?i)\bGENERATED\s+(ALWAYS|BY\s+DEFAULT)\s+AS\s+IDENTITY\b`)
+	if loc := identityRe.FindStringSubmatchIndex(masked); loc != nil {
+		col.Identity = strings.ToUpper(strings.Join(strings.Fields(remaining[loc[2]:loc[3]]), " "))
+		col.Nullable = false
+		if rest := strings.TrimSpace(remaining[loc[1]:]); strings.HasPrefix(rest, "(") {
+			col.IdentityOptions = parser.SplitSequenceOptions(parser.ExtractParenthesesContent(rest))
 		}
 	}
 
-	// Check for inline REFERENCES
-	referencesRe := regexp.MustCompile(`(?i)REFERENCES\s+(\w+)\s*\((\w+)\)(?:\s+ON\s+DELETE\s+(\w+(?:\s+\w+)?))?(?:\s+ON\s+UPDATE\s+(\w+(?:\s+\w+)?))?`)
-	if matches := referencesRe.FindStringSubmatch(remaining); len(matches) >= 3 {
-		fk := &state.ForeignKey{
-			Columns:           []string{col.Name},
-			ReferencedTable:   matches[1],
-			ReferencedColumns: []string{matches[2]},
+	// Inline constraints, each optionally named with CONSTRAINT name
+	constraintRe := regexp.MustCompile(`(?i)(?:\bCONSTRAINT\s+(` + parser.IdentPattern + `)\s+)?\b(PRIMARY\s+KEY|UNIQUE|CHECK|REFERENCES)\b`)
+	locs := constraintRe.FindAllStringSubmatchIndex(masked, -1)
+	for i, loc := range locs {
+		end := len(remaining)
+		if i+1 < len(locs) {
+			end = locs[i+1][0]
 		}
-		if len(matches) >= 4 && matches[3] != "" {
-			fk.OnDelete = matches[3]
+		segment := remaining[loc[4]:end]
+
+		var name string
+		if loc[2] != -1 {
+			name = parser.NormalizeIdentifier(remaining[loc[2]:loc[3]])
 		}
-		if len(matches) >= 5 && matches[4] != "" {
-			fk.OnUpdate = matches[4]
+
+		switch strings.ToUpper(remaining[loc[4] : loc[4]+1]) {
+		case "P":
+			table.SetPrimaryKey(&state.PrimaryKey{
+				Name:    name,
+				Columns: []string{col.Name},
+			})
+		case "U":
+			table.AddUnique(&state.UniqueConstraint{
+				Name:    name,
+				Columns: []string{col.Name},
+			})
+		case "C":
+			if name == "" {
+				name = table.DefaultColumnCheckName(col.Name)
+			}
+			table.AddCheck(&state.CheckConstraint{
+				Name:       name,
+				Expression: ExtractCheckExpression(segment),
+			})
+		case "R":
+			a.parseReferences(table, []string{col.Name}, segment, name)
 		}
-		table.AddForeignKey(fk)
 	}
 
 	table.AddColumn(col)
 }
 
-func (a *Applier) parsePrimaryKey(table *state.Table, def string) {
+func (a *Applier) parsePrimaryKey(table *state.Table, def string, name string) {
 	// Extract columns from PRIMARY KEY (col1, col2, ...)
-	re := regexp.MustCompile(`PRIMARY\s+KEY\s*\(([^)]+)\)`)
+	re := regexp.MustCompile(`(?i)PRIMARY\s+KEY\s*\(([^)]+)\)`)
 	matches := re.FindStringSubmatch(def)
 	if len(matches) >= 2 {
-		cols := strings.Split(matches[1], ",")
-		var columns []string
-		for _, col := range cols {
-			columns = append(columns, strings.TrimSpace(col))
-		}
-		table.PrimaryKey = &state.PrimaryKey{
-			Columns: columns,
-		}
+		table.SetPrimaryKey(&state.PrimaryKey{
+			Name:    name,
+			Columns: parser.SplitIdentifierList(matches[1]),
+		})
+	} else {
+		a.warn("could not parse PRIMARY KEY constraint on %s: %s", table.Name, def)
 	}
 }
 
-func (a *Applier) parseForeignKey(table *state.Table, def string) {
+func (a *Applier) parseForeignKey(table *state.Table, def string, name string) {
 	// FOREIGN KEY (col1, col2) REFERENCES other_table (col1, col2) ON DELETE CASCADE
-	fkRe := regexp.MustCompile(`FOREIGN\s+KEY\s*\(([^)]+)\)\s+REFERENCES\s+(\w+)\s*\(([^)]+)\)`)
+	fkRe := regexp.MustCompile(`(?i)FOREIGN\s+KEY\s*\(([^)]+)\)\s*`)
 	matches := fkRe.FindStringSubmatch(def)
-	if len(matches) >= 4 {
-		cols := strings.Split(matches[1], ",")
-		var columns []string
-		for _, col := range cols {
-			columns = append(columns, strings.TrimSpace(col))
-		}
+	if len(matches) >= 2 {
+		a.parseReferences(table, parser.SplitIdentifierList(matches[1]), def[len(matches[0]):], name)
+	} else {
+		a.warn("could not parse FOREIGN KEY constraint on %s: %s", table.Name, def)
+	}
+}
 
-		refCols := strings.Split(matches[3], ",")
-		var refColumns []string
-		for _, col := range refCols {
-			refColumns = append(refColumns, strings.TrimSpace(col))
-		}
+// parseReferences parses a REFERENCES clause for the given local columns and adds the foreign key
+// The referenced column list may be omitted, in which case the referenced primary key is used
+func (a *Applier) parseReferences(table *state.Table, columns []string, def string, name string) {
+	refRe := regexp.MustCompile(`(?i)^REFERENCES\s+(` + parser.NamePattern + `)(?:\s*\(([^)]+)\))?`)
+	matches := refRe.FindStringSubmatch(strings.TrimSpace(def))
+	if len(matches) < 2 {
+		a.warn("could not parse REFERENCES clause on %s: %s", table.Name, def)
+		return
+	}
 
-		fk := &state.ForeignKey{
-			Columns:           columns,
-			ReferencedTable:   matches[2],
-			ReferencedColumns: refColumns,
-		}
+	fk := &state.ForeignKey{
+		Name:            name,
+		Columns:         columns,
+		ReferencedTable: a.qualifyReference(matches[1]),
+	}
+	if matches[2] != "" {
+		fk.ReferencedColumns = parser.SplitIdentifierList(matches[2])
+	} else if referenced, exists := a.state.GetTable(fk.ReferencedTable); exists && referenced.PrimaryKey != nil {
+		fk.ReferencedColumns = referenced.PrimaryKey.Columns
+	}
 
-		// Check for ON DELETE
-		onDeleteRe := regexp.MustCompile(`ON\s+DELETE\s+(\w+(?:\s+\w+)?)`)
-		if onDeleteMatches := onDeleteRe.FindStringSubmatch(def); len(onDeleteMatches) >= 2 {
-			fk.OnDelete = onDeleteMatches[1]
-		}
+	actionPattern := `(CASCADE|RESTRICT|NO\s+ACTION|SET\s+NULL|SET\s+DEFAULT)`
 
-		// Check for ON UPDATE
-		onUpdateRe := regexp.MustCompile(`ON\s+UPDATE\s+(\w+(?:\s+\w+)?)`)
-		if onUpdateMatches := onUpdateRe.FindStringSubmatch(def); len(onUpdateMatches) >= 2 {
-			fk.OnUpdate = onUpdateMatches[1]
-		}
+	// Check for ON DELETE
+	onDeleteRe := regexp.MustCompile(`(?i)ON\s+DELETE\s+` + actionPattern)
+	if onDeleteMatches := onDeleteRe.FindStringSubmatch(def); len(onDeleteMatches) >= 2 {
+		fk.OnDelete = onDeleteMatches[1]
+	}
+
+	// Check for ON UPDATE
+	onUpdateRe := regexp.MustCompile(`(?i)ON\s+UPDATE\s+` + actionPattern)
+	if onUpdateMatches := onUpdateRe.FindStringSubmatch(def); len(onUpdateMatches) >= 2 {
+		fk.OnUpdate = onUpdateMatches[1]
+	}
 
-		table.AddForeignKey(fk)
+	// Check for DEFERRABLE [INITIALLY DEFERRED|IMMEDIATE]
+	deferrableRe := regexp.MustCompile(`(?i)\b(?:NOT\s+)?DEFERRABLE(?:\s+INITIALLY\s+(?:DEFERRED|IMMEDIATE))?`)
+	if deferrable := deferrableRe.FindString(def); deferrable != "" {
+		fk.Deferrable = deferrable
 	}
+
+	table.AddForeignKey(fk)
 }
 
-func (a *Applier) parseUnique(table *state.Table, def string) {
+func (a *Applier) parseUnique(table *state.Table, def string, name string) {
 	// UNIQUE (col1, col2)
-	re := regexp.MustCompile(`UNIQUE\s*\(([^)]+)\)`)
+	re := regexp.MustCompile(`(?i)UNIQUE\s*\(([^)]+)\)`)
 	matches := re.FindStringSubmatch(def)
 	if len(matches) >= 2 {
-		cols := strings.Split(matches[1], ",")
-		var columns []string
-		for _, col := range cols {
-			columns = append(columns, strings.TrimSpace(col))
-		}
 		table.AddUnique(&state.UniqueConstraint{
-			Columns: columns,
+			Name:    name,
+			Columns: parser.SplitIdentifierList(matches[1]),
 		})
+	} else {
+		a.warn("could not parse UNIQUE constraint on %s: %s", table.Name, def)
 	}
 }
 
-func (a *Applier) parseCheck(table *state.Table, def string) {
+func (a *Applier) parseCheck(table *state.Table, def string, name string) {
 	// CHECK (expression)
-	re := regexp.MustCompile(`CHECK\s*\((.+)\)`)
-	matches := re.FindStringSubmatch(def)
-	if len(matches) >= 2 {
+	if expression := ExtractCheckExpression(def); expression != "" {
 		table.AddCheck(&state.CheckConstraint{
-			Expression: matches[1],
+			Name:       name,
+			Expression: expression,
 		})
+	} else {
+		a.warn("could not parse CHECK constraint on %s: %s", table.Name, def)
+	}
+}
+
+// ExtractCheckExpression extracts the expression of a CHECK (expression) clause
+func ExtractCheckExpression(def string) string {
+	re := regexp.MustCompile(`(?i)CHECK\s*\(`)
+	loc := re.FindStringIndex(def)
+	if loc == nil {
+		return ""
+	}
+	return strings.TrimSpace(parser.ExtractParenthesesContent(def[loc[1]-1:]))
+}
+
+// maskNested blanks out string literals, quoted identifiers and parenthesized text
+// The result has the same length as the input so positions can be mapped back
+func maskNested(s string) string {
+	masked := []byte(s)
+	depth := 0
+
+	for _, tok := range parser.Tokenize(s) {
+		blank := depth > 0 || tok.IsLiteral() ||
+			tok.Type == parser.TokenQuotedIdentifier || tok.Type == parser.TokenComment
+		switch {
+		case tok.IsOperator("("):
+			depth++
+			blank = false
+		case tok.IsOperator(")"):
+			depth--
+			blank = depth > 0
+		}
+
+		if blank {
+			for i := tok.Offset; i < tok.Offset+len(tok.Text); i++ {
+				masked[i] = ' '
+			}
+		}
 	}
+
+	return string(masked)
 }
 
 func (a *Applier) applyAlterTable(stmt *parser.Statement) error {
@@ -283,10 +698,16 @@ func (a *Applier) applyAlterTable(stmt *parser.Statement) error {
 		return fmt.Errorf("invalid ALTER TABLE details")
 	}
 
-	table, exists := a.state.GetTable(details.TableName)
+	tableKey := a.qualify(details.Schema, details.TableName)
+	table, exists := a.state.GetTable(tableKey)
+	if !exists && (a.applyAlterTableRename(tableKey, details.Operations) || a.applyAlterTableOwner(tableKey, details.Operations)) {
+		return nil
+	}
 	if !exists {
 		// Table doesn't exist yet - create it
-		table = state.NewTable(details.TableName)
+		a.warn("ALTER TABLE on %s, which no earlier migration creates", tableKey)
+		table = state.NewTable(a.resolveSchema(details.Schema), details.TableName)
+		table.Privileges = a.state.NewPrivileges("TABLE", table.Schema)
 		a.state.AddOrUpdateTable(table)
 	}
 
@@ -294,154 +715,564 @@ func (a *Applier) applyAlterTable(stmt *parser.Statement) error {
 		switch op.Type {
 		case parser.AddColumn:
 			a.applyAddColumn(table, op)
+			a.applyToPartitions(table, op)
 		case parser.DropColumn:
+			dependents, ambiguous := a.state.ColumnDependents(table.QualifiedName(), op.ColumnName, a.qualifyReference)
+			for _, view := range ambiguous {
+				a.warn("%s may use column %s of %s without qualifying it, kept", view, op.ColumnName, table.QualifiedName())
+			}
+			a.dropDependents("column "+op.ColumnName+" of "+table.QualifiedName(), dependents, op.Cascade, nil)
+			a.applyToPartitions(table, op)
 			a.applyDropColumn(table, op)
 		case parser.AlterColumn:
 			a.applyAlterColumn(table, op)
+			a.applyToPartitions(table, op)
+		case parser.AddConstraint:
+			a.parseTableConstraint(table, op.Details)
+		case parser.DropConstraint:
+			a.dropDependents("constraint "+op.ConstraintName+" of "+table.QualifiedName(), a.state.ConstraintDependents(table.QualifiedName(), op.ConstraintName), op.Cascade, nil)
+			table.DropConstraint(op.ConstraintName)
+		case parser.RenameColumn:
+			a.applyToPartitions(table, op)
+			a.renameColumn(table, op)
+		case parser.RenameTable:
+			a.state.RenameTable(table.QualifiedName(), op.NewName, a.qualifyReference)
+		case parser.RenameConstraint:
+			table.RenameConstraint(op.ConstraintName, op.NewName)
+		case parser.SetTriggerEnabled:
+			if !table.SetTriggerEnabled(op.TriggerName, op.Value) && op.TriggerName != "ALL" && op.TriggerName != "USER" {
+				a.warn("trigger %s on %s not found", op.TriggerName, table.Name)
+			}
+		case parser.SetRowSecurity:
+			table.SetRowSecurity(op.Value)
+		case parser.SetOwner:
+			table.Privileges.Owner = op.Value
+		case parser.AttachPartition:
+			partition := a.qualifyReference(op.Partition)
+			if _, exists := a.state.GetTable(partition); !exists {
+				a.warn("ATTACH PARTITION %s, which no earlier migration creates", partition)
+			}
+			a.state.AttachPartition(table.QualifiedName(), partition, op.Value)
+		case parser.DetachPartition:
+			a.state.DetachPartition(table.QualifiedName(), a.qualifyReference(op.Partition))
+		case parser.SetStorageParameters:
+			table.SetStorageParams(op.Options)
+		case parser.ResetStorageParameters:
+			table.ResetStorageParams(op.Options)
+		case parser.SetPersistence:
+			table.Unlogged = op.Value == "UNLOGGED"
+		case parser.SetTablespace:
+			table.Tablespace = op.Value
+		case parser.SetAccessMethod:
+			table.AccessMethod = op.Value
+		case parser.SetReplicaIdentity:
+			table.SetReplicaIdentity(op.Value, op.ConstraintName)
 		}
 	}
 
 	return nil
 }
 
-func (a *Applier) applyAddColumn(table *state.Table, op parser.AlterOperation) {
-	col := &state.Column{
-		Name:     op.ColumnName,
-		Type:     op.DataType,
-		Nullable: true,
+// renameColumn renames a column of a table, and its references in views reading from the table
+func (a *Applier) renameColumn(table *state.Table, op parser.AlterOperation) {
+	for _, view := range a.state.RenameColumn(table.QualifiedName(), op.ColumnName, op.NewName, a.qualifyReference) {
+		a.warn("%s may use column %s of %s without qualifying it, left as it is", view, op.ColumnName, table.QualifiedName())
 	}
+}
 
-	// Parse the full details for additional info
-	details := op.Details
+// applyToPartitions repeats a column change on the partitions of a table
+// Partitions that were created on their own and attached later have columns of their own, which must match
+// the parent's; defaults, identity and other settings stay their own
+// Partitions without columns only lose what refers to a dropped column, they follow renames with the parent
+func (a *Applier) applyToPartitions(table *state.Table, op parser.AlterOperation) {
+	for _, partition := range a.state.AllPartitions(table) {
+		if len(partition.ColumnOrder) == 0 && op.Type != parser.DropColumn {
+			continue
+		}
 
-	// Check for NOT NULL
-	if strings.Contains(strings.ToUpper(details), "NOT NULL") {
-		col.Nullable = false
+		switch op.Type {
+		case parser.AddColumn:
+			col, ok := table.Columns[op.ColumnName]
+			if _, exists := partition.Columns[op.ColumnName]; !ok || exists {
+				continue
+			}
+			colType := col.Type
+			if base, serial := state.SerialBaseType(colType); serial {
+				colType = base
+			}
+			partition.AddColumn(&state.Column{Name: col.Name, Type: colType, Collation: col.Collation, Nullable: col.Nullable})
+		case parser.DropColumn:
+			a.applyDropColumn(partition, op)
+		case parser.RenameColumn:
+			a.renameColumn(partition, op)
+		case parser.AlterColumn:
+			switch op.ColumnAction {
+			case parser.SetDataType, parser.SetNotNull, parser.DropNotNull:
+				a.applyAlterColumn(partition, op)
+			}
+		}
 	}
+}
 
-	// Extract DEFAULT
-	defaultRe := regexp.MustCompile(`(?i)DEFAULT\s+([^\s,]+(?:\([^)]*\))?)`)
-	if matches := defaultRe.FindStringSubmatch(details); len(matches) >= 2 {
-		col.Default = matches[1]
+// applyAlterTableRename handles ALTER TABLE ... RENAME TO on an index, which PostgreSQL accepts
+// Returns false if the statement is not a rename of a known index
+func (a *Applier) applyAlterTableRename(name string, ops []parser.AlterOperation) bool {
+	if len(ops) != 1 || ops[0].Type != parser.RenameTable {
+		return false
+	}
+	if _, exists := a.state.GetIndex(name); !exists {
+		return false
 	}
 
-	table.AddColumn(col)
+	a.state.RenameIndex(name, ops[0].NewName)
+	return true
 }
 
-func (a *Applier) applyDropColumn(table *state.Table, op parser.AlterOperation) {
-	// Get indexes that will be removed before dropping the column
-	for _, idx := range table.Indexes {
-		for _, col := range idx.Columns {
-			if col == op.ColumnName {
-				// Remove from global index tracking
-				a.state.DropIndex(idx.Name)
-				break
-			}
+// applyAlterTableOwner handles ALTER TABLE ... OWNER TO on a view, materialized view or sequence,
+// which PostgreSQL accepts
+// Returns false if the statement is not an ownership change of such an object
+func (a *Applier) applyAlterTableOwner(name string, ops []parser.AlterOperation) bool {
+	if len(ops) == 0 {
+		return false
+	}
+	for _, op := range ops {
+		if op.Type != parser.SetOwner {
+			return false
 		}
 	}
+	privileges, _, found := a.state.ObjectPrivileges("TABLE", name)
+	if !found {
+		return false
+	}
 
-	table.DropColumn(op.ColumnName)
+	privileges.Owner = ops[len(ops)-1].Value
+	return true
 }
 
-func (a *Applier) applyAlterColumn(table *state.Table, op parser.AlterOperation) {
-	// Handle ALTER COLUMN TYPE
-	if op.DataType != "" {
-		table.AlterColumn(op.ColumnName, func(col *state.Column) {
-			col.Type = op.DataType
-		})
+func (a *Applier) applyAddColumn(table *state.Table, op parser.AlterOperation) {
+	// ADD COLUMN IF NOT EXISTS leaves an existing column untouched
+	if _, exists := table.Columns[op.ColumnName]; exists {
+		return
 	}
 
-	// Handle SET/DROP NOT NULL
-	if strings.Contains(strings.ToUpper(op.Details), "SET NOT NULL") {
-		table.AlterColumn(op.ColumnName, func(col *state.Column) {
-			col.Nullable = false
-		})
-	} else if strings.Contains(strings.ToUpper(op.Details), "DROP NOT NULL") {
-		table.AlterColumn(op.ColumnName, func(col *state.Column) {
-			col.Nullable = true
-		})
-	}
+	// The details hold a full column definition, including inline constraints
+	a.parseColumnDefinition(table, op.Details)
 }
 
-func (a *Applier) applyDropTable(stmt *parser.Statement) error {
-	a.state.DropTable(stmt.ObjectName)
-	return nil
+func (a *Applier) applyDropColumn(table *state.Table, op parser.AlterOperation) {
+	a.state.DropColumn(table.QualifiedName(), op.ColumnName)
 }
 
-func (a *Applier) applyCreateType(stmt *parser.Statement) error {
-	details, ok := stmt.Details.(*parser.CreateTypeDetails)
-	if !ok {
-		return fmt.Errorf("invalid CREATE TYPE details")
+// dropKeys returns the state keys of the objects a DROP statement names
+func (a *Applier) dropKeys(details *parser.DropDetails) []string {
+	keys := make([]string, len(details.Objects))
+	for i, object := range details.Objects {
+		keys[i] = a.qualify(object.Schema, object.Name)
 	}
+	return keys
+}
 
-	enum := state.NewEnum(details.TypeName)
-	enum.CreatedIn = a.currentMigration
-	for _, value := range details.Values {
-		enum.AddValue(value)
+// dropDependents drops the objects that depend on one being dropped
+// PostgreSQL refuses the drop without CASCADE when there are any, so they are dropped all the same and reported then
+// Objects the same statement drops, given by key in dropping, do not count as dependents
+func (a *Applier) dropDependents(object string, dependents []state.Dependent, cascade bool, dropping []string) {
+	var remaining []state.Dependent
+	for _, dependent := range dependents {
+		if !contains(dropping, dependent.Key) {
+			remaining = append(remaining, dependent)
+		}
+	}
+	dependents = remaining
+	if len(dependents) == 0 {
+		return
+	}
+	if !cascade {
+		names := make([]string, len(dependents))
+		for i, dependent := range dependents {
+			names[i] = dependent.String()
+		}
+		a.warn("%s is used by %s, dropping them too", object, strings.Join(names, ", "))
 	}
+	a.state.DropDependents(dependents, a.qualifyReference)
+}
 
-	a.state.AddOrUpdateEnum(enum)
+func (a *Applier) applyAlterColumn(table *state.Table, op parser.AlterOperation) {
+	table.AlterColumn(op.ColumnName, func(col *state.Column) {
+		switch op.ColumnAction {
+		case parser.SetDataType:
+			// A serial column changed to another integer type stays serial, any other type keeps its sequence
+			if serial, ok := state.SerialType(op.DataType); ok && a.isSerial(col) {
+				col.Type = serial
+			} else {
+				a.expandSerial(table, col)
+				col.Type = a.resolveType(op.DataType)
+			}
+			// A type change without COLLATE resets the column to the new type's default collation
+			col.Collation = op.Value
+		case parser.SetDefault:
+			a.expandSerial(table, col)
+			col.Default = a.resolveDefault(op.Value)
+		case parser.DropDefault:
+			a.expandSerial(table, col)
+			col.Default = ""
+		case parser.SetNotNull:
+			col.Nullable = false
+		case parser.DropNotNull:
+			col.Nullable = true
+		case parser.SetExpression:
+			col.Generated = op.Value
+		case parser.DropExpression:
+			// The column keeps its values but becomes a regular column
+			col.Generated = ""
+		case parser.AddIdentity:
+			col.Identity = op.Value
+			col.IdentityOptions = state.MergeSequenceOptions(nil, op.Options)
+			col.Nullable = false
+		case parser.SetIdentity:
+			if op.Value != "" {
+				col.Identity = op.Value
+			}
+			col.IdentityOptions = state.MergeSequenceOptions(col.IdentityOptions, op.Options)
+		case parser.DropIdentity:
+			col.Identity = ""
+			col.IdentityOptions = nil
+		case parser.SetStatistics:
+			col.Statistics = op.Value
+			if strings.EqualFold(op.Value, "DEFAULT") || op.Value == "-1" {
+				col.Statistics = ""
+			}
+		case parser.SetAttributeOptions:
+			col.SetOptions(op.Options)
+		case parser.ResetAttributeOptions:
+			col.ResetOptions(op.Options)
+		case parser.SetStorage:
+			col.Storage = op.Value
+			if op.Value == "DEFAULT" {
+				col.Storage = ""
+			}
+		case parser.SetCompression:
+			col.Compression = op.Value
+			if op.Value == "default" {
+				col.Compression = ""
+			}
+		}
+	})
+}
 
-	return nil
+// isSerial reports whether a column was declared with a serial pseudo-type
+func (a *Applier) isSerial(col *state.Column) bool {
+	_, ok := state.SerialBaseType(col.Type)
+	return ok
 }
 
-func (a *Applier) applyAlterType(stmt *parser.Statement) error {
-	details, ok := stmt.Details.(*parser.AlterTypeDetails)
+// expandSerial turns a serial column into its integer type with a nextval() default,
+// and records the sequence PostgreSQL created for it
+// Needed once the column's default or sequence is changed, which the serial shorthand cannot express
+func (a *Applier) expandSerial(table *state.Table, col *state.Column) *state.Sequence {
+	baseType, ok := state.SerialBaseType(col.Type)
 	if !ok {
-		return fmt.Errorf("invalid ALTER TYPE details")
+		return nil
 	}
 
-	enum, exists := a.state.GetEnum(details.TypeName)
-	if !exists {
-		enum = state.NewEnum(details.TypeName)
-		enum.CreatedIn = a.currentMigration
-		a.state.AddOrUpdateEnum(enum)
+	seq := state.NewSequence(table.Schema, table.DefaultSequenceName(col.Name))
+	seq.CreatedIn = table.CreatedIn
+	seq.Privileges = a.state.NewPrivileges("SEQUENCE", seq.Schema)
+	if baseType != "bigint" {
+		seq.Options = append(seq.Options, "AS "+baseType)
 	}
+	seq.SetOwner(table.QualifiedName(), col.Name)
+	a.state.AddOrUpdateSequence(seq)
 
-	if details.NewValue != "" {
-		enum.AddValue(details.NewValue)
+	seqName := state.QuoteIdentifier(seq.Name)
+	if seq.Schema != state.DefaultSchema {
+		seqName = state.QuoteIdentifier(seq.Schema) + "." + seqName
 	}
+	col.Type = baseType
+	col.Nullable = false
+	col.Default = fmt.Sprintf("nextval('%s'::regclass)", strings.ReplaceAll(seqName, "'", "''"))
 
-	return nil
+	return seq
 }
 
-func (a *Applier) applyDropType(stmt *parser.Statement) error {
-	a.state.DropEnum(stmt.ObjectName)
+// serialSequence returns the implicit sequence of a serial column, looked up by its sequence name
+// Returns nil if no serial column in the schema has a sequence of that name
+func (a *Applier) serialSequence(schema, name string) *state.Sequence {
+	for _, table := range a.state.Tables {
+		if table.Schema != schema {
+			continue
+		}
+		for _, colName := range table.ColumnOrder {
+			col := table.Columns[colName]
+			if a.isSerial(col) && table.DefaultSequenceName(col.Name) == name {
+				return a.expandSerial(table, col)
+			}
+		}
+	}
 	return nil
 }
 
-func (a *Applier) applyCreateDomain(stmt *parser.Statement) error {
-	details, ok := stmt.Details.(*parser.CreateDomainDetails)
+func (a *Applier) applyDropTable(stmt *parser.Statement) error {
+	details, ok := stmt.Details.(*parser.DropDetails)
 	if !ok {
-		return fmt.Errorf("invalid CREATE DOMAIN details")
+		return fmt.Errorf("invalid DROP TABLE details")
 	}
 
-	domain := state.NewDomain(details.DomainName)
-	domain.CreatedIn = a.currentMigration
-	domain.BaseType = details.BaseType
-	domain.Default = details.Default
-	domain.Constraint = details.Constraint
-
-	a.state.AddOrUpdateDomain(domain)
-
-	return nil
-}
-
-func (a *Applier) applyDropDomain(stmt *parser.Statement) error {
-	a.state.DropDomain(stmt.ObjectName)
+	keys := a.dropKeys(details)
+	for _, key := range keys {
+		a.dropDependents("table "+key, a.state.TableDependents(key), details.Cascade, keys)
+		a.state.DropTable(key)
+	}
 	return nil
 }
 
-func (a *Applier) applyCreateView(stmt *parser.Statement) error {
-	details, ok := stmt.Details.(*parser.CreateViewDetails)
+func (a *Applier) applyCreateType(stmt *parser.Statement) error {
+	details, ok := stmt.Details.(*parser.CreateTypeDetails)
 	if !ok {
-		return fmt.Errorf("invalid CREATE VIEW details")
+		return fmt.Errorf("invalid CREATE TYPE details")
 	}
 
-	view := state.NewView(details.ViewName)
+	schema := a.resolveSchema(details.Schema)
+
+	switch details.Kind {
+	case "COMPOSITE":
+		ct := state.NewCompositeType(schema, details.TypeName)
+		ct.CreatedIn = a.currentMigration
+		ct.Privileges = a.state.NewPrivileges("TYPE", schema)
+		for _, attr := range details.Attributes {
+			ct.AddAttribute(&state.Attribute{Name: attr.Name, Type: a.resolveType(attr.DataType), Collation: attr.Collation})
+		}
+		a.state.AddOrUpdateCompositeType(ct)
+
+	case "RANGE":
+		rt := state.NewRangeType(schema, details.TypeName)
+		rt.CreatedIn = a.currentMigration
+		rt.Privileges = a.state.NewPrivileges("TYPE", schema)
+		rt.Options = details.RangeOptions
+		a.state.AddOrUpdateRangeType(rt)
+
+	default:
+		enum := state.NewEnum(schema, details.TypeName)
+		enum.CreatedIn = a.currentMigration
+		enum.Privileges = a.state.NewPrivileges("TYPE", schema)
+		for _, value := range details.Values {
+			enum.AddValue(value)
+		}
+		a.state.AddOrUpdateEnum(enum)
+	}
+
+	return nil
+}
+
+func (a *Applier) applyAlterType(stmt *parser.Statement) error {
+	details, ok := stmt.Details.(*parser.AlterTypeDetails)
+	if !ok {
+		return fmt.Errorf("invalid ALTER TYPE details")
+	}
+
+	key := a.qualify(details.Schema, details.TypeName)
+
+	switch {
+	case len(details.Attributes) > 0:
+		a.applyAlterAttributes(details)
+	case details.NewName != "":
+		if !a.state.RenameType(key, details.NewName, a.qualifyReference) {
+			a.warn("ALTER TYPE ... RENAME TO on %s, which no earlier migration creates", key)
+		}
+	case details.OldValue != "":
+		unchanged, ok := a.state.RenameEnumValue(key, details.OldValue, details.NewValue, a.qualifyReference)
+		if !ok {
+			a.warn("RENAME VALUE skipped, %s is not an enum with the value '%s'", key, details.OldValue)
+		}
+		for _, owner := range unchanged {
+			a.warn("'%s' in %s left as it is, it is not clearly a value of %s", details.OldValue, owner, key)
+		}
+	case details.NewValue != "":
+		a.addEnumValue(details.Schema, details.TypeName, details.NewValue, details.Before, details.After)
+	}
+
+	return nil
+}
+
+// addEnumValue adds a value to an enum, placed BEFORE or AFTER another value when one is given
+// An enum no earlier migration creates is created, so values added in DO blocks are not lost
+func (a *Applier) addEnumValue(schema, typeName, value, before, after string) {
+	enum, exists := a.state.GetEnum(a.qualify(schema, typeName))
+	if !exists {
+		enum = state.NewEnum(a.resolveSchema(schema), typeName)
+		enum.CreatedIn = a.currentMigration
+		enum.Privileges = a.state.NewPrivileges("TYPE", enum.Schema)
+		a.state.AddOrUpdateEnum(enum)
+	}
+
+	neighbor, placeAfter := before, false
+	if after != "" {
+		neighbor, placeAfter = after, true
+	}
+	if neighbor != "" && !enum.InsertValue(value, neighbor, placeAfter) {
+		a.warn("enum %s has no value '%s' to place '%s' next to, appended instead", enum.Name, neighbor, value)
+		neighbor = ""
+	}
+	if neighbor == "" {
+		enum.AddValue(value)
+	}
+}
+
+// applyAlterAttributes applies ADD, DROP, ALTER and RENAME ATTRIBUTE to a composite type
+func (a *Applier) applyAlterAttributes(details *parser.AlterTypeDetails) {
+	key := a.qualify(details.Schema, details.TypeName)
+	ct, exists := a.state.GetCompositeType(key)
+	if !exists {
+		a.warn("ALTER TYPE on %s, which no earlier migration creates as a composite type", key)
+		return
+	}
+
+	for _, op := range details.Attributes {
+		switch op.Action {
+		case parser.AddAttribute:
+			ct.AddAttribute(&state.Attribute{Name: op.Name, Type: a.resolveType(op.DataType), Collation: op.Collation})
+		case parser.DropAttribute:
+			ct.DropAttribute(op.Name)
+		case parser.SetAttributeType:
+			attr, ok := ct.GetAttribute(op.Name)
+			if !ok {
+				a.warn("attribute %s of type %s not found", op.Name, ct.Name)
+				continue
+			}
+			attr.Type = a.resolveType(op.DataType)
+			attr.Collation = op.Collation
+		case parser.RenameAttribute:
+			if !ct.RenameAttribute(op.Name, op.NewName) {
+				a.warn("attribute %s of type %s not found", op.Name, ct.Name)
+			}
+		}
+	}
+}
+
+func (a *Applier) applyDropType(stmt *parser.Statement) error {
+	details, ok := stmt.Details.(*parser.DropDetails)
+	if !ok {
+		return fmt.Errorf("invalid DROP TYPE details")
+	}
+
+	keys := a.dropKeys(details)
+	for _, key := range keys {
+		a.dropDependents("type "+key, a.state.TypeDependents(key, a.qualifyReference), details.Cascade, keys)
+
+		// DROP TYPE covers enums, composite and range types alike
+		switch {
+		case a.state.CompositeTypes[key] != nil:
+			a.state.DropCompositeType(key)
+		case a.state.RangeTypes[key] != nil:
+			a.state.DropRangeType(key)
+		default:
+			a.state.DropEnum(key)
+		}
+	}
+	return nil
+}
+
+func (a *Applier) applyCreateDomain(stmt *parser.Statement) error {
+	details, ok := stmt.Details.(*parser.CreateDomainDetails)
+	if !ok {
+		return fmt.Errorf("invalid CREATE DOMAIN details")
+	}
+
+	domain := state.NewDomain(a.resolveSchema(details.Schema), details.DomainName)
+	domain.CreatedIn = a.currentMigration
+	domain.Privileges = a.state.NewPrivileges("DOMAIN", domain.Schema)
+	domain.BaseType = a.resolveType(details.BaseType)
+	domain.Collation = details.Collation
+	domain.Default = details.Default
+	domain.NotNull = details.NotNull
+	for _, constraint := range details.Constraints {
+		domain.AddConstraint(&state.DomainConstraint{
+			Name:       constraint.Name,
+			Expression: constraint.Expression,
+			NotValid:   constraint.NotValid,
+		})
+	}
+
+	a.state.AddOrUpdateDomain(domain)
+
+	return nil
+}
+
+func (a *Applier) applyAlterDomain(stmt *parser.Statement) error {
+	details, ok := stmt.Details.(*parser.AlterDomainDetails)
+	if !ok {
+		return fmt.Errorf("invalid ALTER DOMAIN details")
+	}
+
+	key := a.qualify(details.Schema, details.DomainName)
+	domain, exists := a.state.GetDomain(key)
+	if !exists {
+		a.warn("ALTER DOMAIN on %s, which no earlier migration creates", key)
+		return nil
+	}
+
+	switch details.Action {
+	case parser.SetDomainDefault:
+		domain.Default = details.Default
+	case parser.DropDomainDefault:
+		domain.Default = ""
+	case parser.SetDomainNotNull:
+		domain.NotNull = true
+	case parser.DropDomainNotNull:
+		domain.NotNull = false
+	case parser.AddDomainConstraint:
+		domain.AddConstraint(&state.DomainConstraint{
+			Name:       details.Constraint.Name,
+			Expression: details.Constraint.Expression,
+			NotValid:   details.Constraint.NotValid,
+		})
+	case parser.DropDomainConstraint:
+		if !domain.DropConstraint(details.ConstraintName) {
+			a.warn("constraint %s on domain %s not found", details.ConstraintName, domain.Name)
+		}
+	case parser.RenameDomainConstraint, parser.ValidateDomainConstraint:
+		constraint, found := domain.GetConstraint(details.ConstraintName)
+		if !found {
+			a.warn("constraint %s on domain %s not found", details.ConstraintName, domain.Name)
+			break
+		}
+		if details.Action == parser.RenameDomainConstraint {
+			constraint.Name = details.NewName
+		} else {
+			constraint.NotValid = false
+		}
+	case parser.RenameDomain:
+		a.state.RenameType(key, details.NewName, a.qualifyReference)
+	}
+
+	return nil
+}
+
+func (a *Applier) applyDropDomain(stmt *parser.Statement) error {
+	details, ok := stmt.Details.(*parser.DropDetails)
+	if !ok {
+		return fmt.Errorf("invalid DROP DOMAIN details")
+	}
+
+	keys := a.dropKeys(details)
+	for _, key := range keys {
+		a.dropDependents("domain "+key, a.state.TypeDependents(key, a.qualifyReference), details.Cascade, keys)
+		a.state.DropDomain(key)
+	}
+	return nil
+}
+
+func (a *Applier) applyCreateView(stmt *parser.Statement) error {
+	details, ok := stmt.Details.(*parser.CreateViewDetails)
+	if !ok {
+		return fmt.Errorf("invalid CREATE VIEW details")
+	}
+
+	view := state.NewView(a.resolveSchema(details.Schema), details.ViewName)
 	view.CreatedIn = a.currentMigration
+	view.Privileges = a.state.NewPrivileges("VIEW", view.Schema)
 	view.Definition = details.Definition
-	view.ExtractDependencies()
+	view.SearchPath = a.searchPath
+	view.ExtractDependencies(a.qualifyReference)
 
 	a.state.AddOrUpdateView(view)
 
@@ -449,7 +1280,54 @@ func (a *Applier) applyCreateView(stmt *parser.Statement) error {
 }
 
 func (a *Applier) applyDropView(stmt *parser.Statement) error {
-	a.state.DropView(stmt.ObjectName)
+	details, ok := stmt.Details.(*parser.DropDetails)
+	if !ok {
+		return fmt.Errorf("invalid DROP VIEW details")
+	}
+
+	keys := a.dropKeys(details)
+	for _, key := range keys {
+		a.dropDependents("view "+key, a.state.TableDependents(key), details.Cascade, keys)
+		a.state.DropView(key)
+	}
+	return nil
+}
+
+func (a *Applier) applyCreateMaterializedView(stmt *parser.Statement) error {
+	details, ok := stmt.Details.(*parser.CreateMaterializedViewDetails)
+	if !ok {
+		return fmt.Errorf("invalid CREATE MATERIALIZED VIEW details")
+	}
+
+	// CREATE MATERIALIZED VIEW IF NOT EXISTS leaves an existing view and its indexes as they are
+	if _, exists := a.state.GetMaterializedView(a.qualify(details.Schema, details.ViewName)); exists {
+		return nil
+	}
+
+	mview := state.NewMaterializedView(a.resolveSchema(details.Schema), details.ViewName)
+	mview.CreatedIn = a.currentMigration
+	mview.Privileges = a.state.NewPrivileges("MATERIALIZED VIEW", mview.Schema)
+	mview.Definition = details.Definition
+	mview.WithData = details.WithData
+	mview.SearchPath = a.searchPath
+	mview.ExtractDependencies(a.qualifyReference)
+
+	a.state.AddOrUpdateMaterializedView(mview)
+
+	return nil
+}
+
+func (a *Applier) applyDropMaterializedView(stmt *parser.Statement) error {
+	details, ok := stmt.Details.(*parser.DropDetails)
+	if !ok {
+		return fmt.Errorf("invalid DROP MATERIALIZED VIEW details")
+	}
+
+	keys := a.dropKeys(details)
+	for _, key := range keys {
+		a.dropDependents("materialized view "+key, a.state.TableDependents(key), details.Cascade, keys)
+		a.state.DropMaterializedView(key)
+	}
 	return nil
 }
 
@@ -459,26 +1337,642 @@ func (a *Applier) applyCreateIndex(stmt *parser.Statement) error {
 		return fmt.Errorf("invalid CREATE INDEX details")
 	}
 
+	// The parser reports an index without keys, which could not be written back
+	if len(details.Elements) == 0 {
+		return nil
+	}
+
+	// Indexes are always created in the schema of their table
+	target := a.qualify(details.TableSchema, details.TableName)
+	schema, tableName := state.SplitQualifiedName(target)
 	idx := &state.Index{
-		Name:    details.IndexName,
-		Columns: details.Columns,
-		Unique:  details.Unique,
-		Where:   details.Where,
+		Schema:           schema,
+		Name:             details.IndexName,
+		Include:          details.Include,
+		Unique:           details.Unique,
+		NullsNotDistinct: details.NullsNotDistinct,
+		Method:           details.Method,
+		Params:           details.Params,
+		Tablespace:       details.Tablespace,
+		Where:            details.Where,
+	}
+	for _, element := range details.Elements {
+		idx.Elements = append(idx.Elements, state.IndexElement(element))
+	}
+
+	// An unnamed index is named after its table and keys
+	if idx.Name == "" {
+		idx.Name = a.chooseIndexName(schema, idx.DefaultName(tableName))
 	}
 
 	// Add to global index tracking
 	a.state.AddIndex(idx)
 
-	// Also add to the table
-	if table, exists := a.state.GetTable(details.TableName); exists {
+	// Also add to the table or materialized view
+	if table, exists := a.state.GetTable(target); exists {
 		table.AddIndex(idx)
+	} else if mview, exists := a.state.GetMaterializedView(target); exists {
+		mview.AddIndex(idx)
+	}
+
+	return nil
+}
+
+func (a *Applier) applyAlterIndex(stmt *parser.Statement) error {
+	details, ok := stmt.Details.(*parser.AlterIndexDetails)
+	if !ok {
+		return fmt.Errorf("invalid ALTER INDEX details")
+	}
+
+	name := a.qualify(details.Schema, details.IndexName)
+	if _, exists := a.state.GetIndex(name); exists {
+		a.state.RenameIndex(name, details.NewName)
+		return nil
+	}
+
+	// Renaming the index behind a PRIMARY KEY or UNIQUE constraint renames the constraint
+	for _, table := range a.state.Tables {
+		if schema, _ := state.SplitQualifiedName(name); table.Schema == schema && table.RenameConstraint(details.IndexName, details.NewName) {
+			break
+		}
 	}
 
 	return nil
 }
 
 func (a *Applier) applyDropIndex(stmt *parser.Statement) error {
-	a.state.DropIndex(stmt.ObjectName)
+	details, ok := stmt.Details.(*parser.DropDetails)
+	if !ok {
+		return fmt.Errorf("invalid DROP INDEX details")
+	}
+
+	for _, key := range a.dropKeys(details) {
+		a.state.DropIndex(key)
+	}
+	return nil
+}
+
+func (a *Applier) applyCreateSequence(stmt *parser.Statement) error {
+	details, ok := stmt.Details.(*parser.CreateSequenceDetails)
+	if !ok {
+		return fmt.Errorf("invalid CREATE SEQUENCE details")
+	}
+
+	seq := state.NewSequence(a.resolveSchema(details.Schema), details.SequenceName)
+	seq.CreatedIn = a.currentMigration
+	seq.Privileges = a.state.NewPrivileges("SEQUENCE", seq.Schema)
+	seq.Options = state.MergeSequenceOptions(seq.Options, details.Options)
+	if details.OwnedBy != "" {
+		a.setSequenceOwner(seq, details.OwnedBy)
+	}
+
+	a.state.AddOrUpdateSequence(seq)
+
+	return nil
+}
+
+func (a *Applier) applyAlterSequence(stmt *parser.Statement) error {
+	details, ok := stmt.Details.(*parser.AlterSequenceDetails)
+	if !ok {
+		return fmt.Errorf("invalid ALTER SEQUENCE details")
+	}
+
+	key := a.qualify(details.Schema, details.SequenceName)
+	seq, exists := a.state.GetSequence(key)
+	if !exists {
+		seq = a.serialSequence(a.resolveSchema(details.Schema), details.SequenceName)
+	}
+	if seq == nil {
+		a.warn("ALTER SEQUENCE on %s, which no earlier migration creates", key)
+		return nil
+	}
+
+	if details.NewName != "" {
+		a.state.RenameSequence(key, details.NewName, a.qualifyReference)
+		return nil
+	}
+
+	seq.Options = state.MergeSequenceOptions(seq.Options, details.Options)
+	if details.OwnedBy != "" {
+		a.setSequenceOwner(seq, details.OwnedBy)
+	}
+
+	return nil
+}
+
+// setSequenceOwner applies an OWNED BY clause, given as [schema.]table.column or NONE
+func (a *Applier) setSequenceOwner(seq *state.Sequence, ownedBy string) {
+	if strings.EqualFold(ownedBy, "NONE") {
+		seq.SetOwner("", "")
+		return
+	}
+
+	parts := parser.SplitNameParts(ownedBy)
+	if len(parts) < 2 {
+		a.warn("could not parse OWNED BY %s on sequence %s", ownedBy, seq.Name)
+		return
+	}
+
+	column := parts[len(parts)-1]
+	var schema string
+	if len(parts) >= 3 {
+		schema = parts[len(parts)-3]
+	}
+	tableKey := a.qualify(schema, parts[len(parts)-2])
+	if _, exists := a.state.GetTable(tableKey); !exists {
+		a.warn("sequence %s is owned by %s, which no earlier migration creates", seq.Name, tableKey)
+	}
+
+	seq.SetOwner(tableKey, column)
+}
+
+func (a *Applier) applyDropSequence(stmt *parser.Statement) error {
+	details, ok := stmt.Details.(*parser.DropDetails)
+	if !ok {
+		return fmt.Errorf("invalid DROP SEQUENCE details")
+	}
+
+	keys := a.dropKeys(details)
+	for _, key := range keys {
+		a.dropDependents("sequence "+key, a.state.SequenceDependents(key, a.qualifyReference), details.Cascade, keys)
+		a.state.DropSequence(key)
+	}
+	return nil
+}
+
+func (a *Applier) applyCreateFunction(stmt *parser.Statement) error {
+	details, ok := stmt.Details.(*parser.CreateFunctionDetails)
+	if !ok {
+		return fmt.Errorf("invalid CREATE FUNCTION details")
+	}
+
+	fn := state.NewFunction(a.resolveSchema(details.Schema), details.FunctionName, details.Signature)
+	fn.CreatedIn = a.currentMigration
+	fn.Kind = details.Kind
+	fn.Privileges = a.state.NewPrivileges(fn.Kind, fn.Schema)
+	fn.Arguments = details.Arguments
+	fn.Returns = details.Returns
+	fn.Language = details.Language
+	fn.Volatility = details.Volatility
+	fn.Security = details.Security
+	fn.SetOptions = details.SetOptions
+	fn.Attributes = details.Attributes
+	fn.Body = details.Body
+
+	// SQL-language bodies are checked when the function is created, so the tables they read must exist
+	if fn.Language == "sql" {
+		fn.DependsOn = state.TableReferences(routineBodySQL(fn.Body), a.qualifyReference)
+	}
+
+	a.state.AddOrUpdateFunction(fn)
+
+	return nil
+}
+
+// routineBodySQL returns the SQL of a routine body, unquoting an AS '...' string
+func routineBodySQL(body string) string {
+	for _, tok := range parser.Tokenize(body) {
+		if tok.IsLiteral() {
+			return parser.UnquoteString(tok.Text)
+		}
+	}
+	return body
+}
+
+func (a *Applier) applyDropFunction(stmt *parser.Statement) error {
+	details, ok := stmt.Details.(*parser.DropFunctionDetails)
+	if !ok {
+		return fmt.Errorf("invalid DROP FUNCTION details")
+	}
+
+	var keys []string
+	for _, object := range details.Drop.Objects {
+		name := a.qualify(object.Schema, object.Name)
+		if object.HasSignature {
+			keys = append(keys, state.FunctionKey(name, object.Signature))
+			continue
+		}
+
+		// Without an argument list the name has to identify a single function
+		overloads := a.state.FunctionOverloads(name)
+		if len(overloads) > 1 {
+			a.warn("DROP %s %s without arguments matches %d overloads, dropping all of them", details.Kind, name, len(overloads))
+		}
+		keys = append(keys, overloads...)
+	}
+
+	for _, key := range keys {
+		a.dropDependents(strings.ToLower(details.Kind)+" "+key, a.state.FunctionDependents(key, a.qualifyReference), details.Drop.Cascade, keys)
+		a.state.DropFunction(key)
+	}
+	return nil
+}
+
+func (a *Applier) applyCreateTrigger(stmt *parser.Statement) error {
+	details, ok := stmt.Details.(*parser.CreateTriggerDetails)
+	if !ok {
+		return fmt.Errorf("invalid CREATE TRIGGER details")
+	}
+
+	tableKey := a.qualify(details.Schema, details.TableName)
+	table, exists := a.state.GetTable(tableKey)
+	if !exists {
+		a.warn("trigger %s is on %s, which is not a table created by an earlier migration", details.TriggerName, tableKey)
+		return nil
+	}
+
+	table.AddTrigger(&state.Trigger{
+		Name:          details.TriggerName,
+		Constraint:    details.Constraint,
+		Timing:        details.Timing,
+		Events:        details.Events,
+		UpdateColumns: details.UpdateColumns,
+		ForEach:       details.ForEach,
+		When:          details.When,
+		Options:       details.Options,
+		Function:      a.qualify(details.FunctionSchema, details.FunctionName),
+		Arguments:     details.Arguments,
+	})
+
+	return nil
+}
+
+func (a *Applier) applyAlterTrigger(stmt *parser.Statement) error {
+	details, ok := stmt.Details.(*parser.TriggerDetails)
+	if !ok {
+		return fmt.Errorf("invalid ALTER TRIGGER details")
+	}
+
+	if table, exists := a.state.GetTable(a.qualify(details.Schema, details.TableName)); exists {
+		if trigger, found := table.GetTrigger(details.TriggerName); found {
+			trigger.Name = details.NewName
+		}
+	}
+
+	return nil
+}
+
+func (a *Applier) applyDropTrigger(stmt *parser.Statement) error {
+	details, ok := stmt.Details.(*parser.TriggerDetails)
+	if !ok {
+		return fmt.Errorf("invalid DROP TRIGGER details")
+	}
+
+	if table, exists := a.state.GetTable(a.qualify(details.Schema, details.TableName)); exists {
+		table.DropTrigger(details.TriggerName)
+	}
+
+	return nil
+}
+
+func (a *Applier) applyCreatePolicy(stmt *parser.Statement) error {
+	details, ok := stmt.Details.(*parser.PolicyDetails)
+	if !ok {
+		return fmt.Errorf("invalid CREATE POLICY details")
+	}
+
+	tableKey := a.qualify(details.Schema, details.TableName)
+	table, exists := a.state.GetTable(tableKey)
+	if !exists {
+		a.warn("policy %s is on %s, which is not a table created by an earlier migration", details.PolicyName, tableKey)
+		return nil
+	}
+
+	// Policies apply to every command and to PUBLIC unless told otherwise
+	policy := &state.Policy{
+		Name:        details.PolicyName,
+		Restrictive: details.Restrictive,
+		Command:     details.Command,
+		Roles:       policyRoles(details.Roles),
+		Using:       details.Using,
+		WithCheck:   details.WithCheck,
+	}
+	if policy.Command == "" {
+		policy.Command = "ALL"
+	}
+	table.AddPolicy(policy)
+
+	return nil
+}
+
+func (a *Applier) applyAlterPolicy(stmt *parser.Statement) error {
+	details, ok := stmt.Details.(*parser.PolicyDetails)
+	if !ok {
+		return fmt.Errorf("invalid ALTER POLICY details")
+	}
+
+	tableKey := a.qualify(details.Schema, details.TableName)
+	table, exists := a.state.GetTable(tableKey)
+	if !exists {
+		a.warn("policy %s is on %s, which is not a table created by an earlier migration", details.PolicyName, tableKey)
+		return nil
+	}
+	policy, found := table.GetPolicy(details.PolicyName)
+	if !found {
+		a.warn("policy %s on %s not found", details.PolicyName, table.Name)
+		return nil
+	}
+
+	// Only the clauses given are changed
+	if details.NewName != "" {
+		policy.Name = details.NewName
+	}
+	if details.Roles != nil {
+		policy.Roles = policyRoles(details.Roles)
+	}
+	if details.Using != "" {
+		policy.Using = details.Using
+	}
+	if details.WithCheck != "" {
+		policy.WithCheck = details.WithCheck
+	}
+
+	return nil
+}
+
+// policyRoles returns the roles a policy applies to, with TO PUBLIC kept as the default of no roles
+func policyRoles(roles []string) []string {
+	if len(roles) == 1 && roles[0] == "PUBLIC" {
+		return nil
+	}
+	return roles
+}
+
+func (a *Applier) applyDropPolicy(stmt *parser.Statement) error {
+	details, ok := stmt.Details.(*parser.PolicyDetails)
+	if !ok {
+		return fmt.Errorf("invalid DROP POLICY details")
+	}
+
+	if table, exists := a.state.GetTable(a.qualify(details.Schema, details.TableName)); exists {
+		table.DropPolicy(details.PolicyName)
+	}
+
+	return nil
+}
+
+func (a *Applier) applyGrant(stmt *parser.Statement) error {
+	details, ok := stmt.Details.(*parser.GrantDetails)
+	if !ok {
+		return fmt.Errorf("invalid %s details", stmt.Type)
+	}
+
+	// ALL TABLES IN SCHEMA also covers views and materialized views, but not sequences
+	for _, schema := range details.AllInSchema {
+		for _, key := range a.state.SchemaObjects(schema) {
+			privileges, kind, found := a.state.ObjectPrivileges(details.ObjectType, key)
+			if found && (details.ObjectType != "TABLE" || kind != "SEQUENCE") {
+				applyPrivileges(&privileges.Grants, kind, details)
+			}
+		}
+	}
+
+	for _, object := range details.Objects {
+		for _, key := range a.objectKeys(details.ObjectType, object.Schema, object.Name, object.Signature, object.HasSignature) {
+			privileges, kind, found := a.state.ObjectPrivileges(details.ObjectType, key)
+			if !found {
+				a.warn("%s on %s, which no earlier migration creates", stmt.Type, key)
+				continue
+			}
+			applyPrivileges(&privileges.Grants, kind, details)
+		}
+	}
+
+	return nil
+}
+
+// objectKeys returns the state keys of the objects a statement names, for ObjectPrivileges
+// A function named without arguments stands for all of its overloads
+func (a *Applier) objectKeys(kind, schema, name, signature string, hasSignature bool) []string {
+	switch kind {
+	case "SCHEMA":
+		return []string{name}
+	case "FUNCTION", "PROCEDURE", "ROUTINE":
+		qualified := a.qualify(schema, name)
+		if hasSignature {
+			return []string{state.FunctionKey(qualified, signature)}
+		}
+		if overloads := a.state.FunctionOverloads(qualified); len(overloads) > 0 {
+			return overloads
+		}
+		return []string{qualified}
+	}
+
+	// The implicit sequence of a serial column only becomes a sequence of its own once named
+	key := a.qualify(schema, name)
+	if kind == "TABLE" || kind == "SEQUENCE" {
+		if _, _, found := a.state.ObjectPrivileges(kind, key); !found {
+			if seq := a.serialSequence(a.resolveSchema(schema), name); seq != nil {
+				key = seq.QualifiedName()
+			}
+		}
+	}
+	return []string{key}
+}
+
+// applyPrivileges applies the privileges of a GRANT or REVOKE to the grants on an object of the given kind
+func applyPrivileges(grants *state.Grants, kind string, details *parser.GrantDetails) {
+	for _, spec := range details.Privileges {
+		names := []string{spec.Name}
+		if spec.Name == "ALL" {
+			names = state.PrivilegeNames(kind)
+		}
+		columns := spec.Columns
+		if len(columns) == 0 {
+			columns = []string{""}
+		}
+
+		for _, grantee := range details.Grantees {
+			for _, name := range names {
+				for _, column := range columns {
+					if details.Revoke {
+						grants.Revoke(grantee, name, column, details.GrantOption)
+					} else {
+						grants.Grant(grantee, name, column, details.GrantOption)
+					}
+				}
+			}
+		}
+	}
+}
+
+func (a *Applier) applyAlterDefaultPrivileges(stmt *parser.Statement) error {
+	details, ok := stmt.Details.(*parser.DefaultPrivilegesDetails)
+	if !ok {
+		return fmt.Errorf("invalid ALTER DEFAULT PRIVILEGES details")
+	}
+
+	// Without FOR ROLE or IN SCHEMA the defaults are those of the current role, for every schema
+	roles := []string{""}
+	if len(details.Roles) > 0 {
+		roles = details.Roles
+	}
+	schemas := []string{""}
+	if len(details.Schemas) > 0 {
+		schemas = details.Schemas
+	}
+
+	kind := state.DefaultPrivilegesKind(details.Grant.ObjectType)
+	for _, role := range roles {
+		if role == "CURRENT_USER" || role == "CURRENT_ROLE" {
+			role = ""
+		}
+		for _, schema := range schemas {
+			dp := a.state.GetDefaultPrivileges(role, schema, details.Grant.ObjectType)
+			applyPrivileges(&dp.Grants, kind, &details.Grant)
+		}
+	}
+
+	return nil
+}
+
+func (a *Applier) applyAlterOwner(stmt *parser.Statement) error {
+	details, ok := stmt.Details.(*parser.OwnerDetails)
+	if !ok {
+		return fmt.Errorf("invalid ALTER ... OWNER TO details")
+	}
+
+	// The owner of a schema is its AUTHORIZATION
+	if details.ObjectType == "SCHEMA" {
+		schema, exists := a.state.GetSchema(details.Name)
+		if !exists {
+			a.warn("ALTER SCHEMA ... OWNER TO on %s, which no earlier migration creates", details.Name)
+			return nil
+		}
+		schema.Authorization = details.Owner
+		return nil
+	}
+
+	for _, key := range a.objectKeys(details.ObjectType, details.Schema, details.Name, details.Signature, details.HasSignature) {
+		privileges, _, found := a.state.ObjectPrivileges(details.ObjectType, key)
+		if !found {
+			a.warn("ALTER %s ... OWNER TO on %s, which no earlier migration creates", details.ObjectType, key)
+			continue
+		}
+		privileges.Owner = details.Owner
+	}
+
+	return nil
+}
+
+func (a *Applier) applyCreateExtension(stmt *parser.Statement) error {
+	details, ok := stmt.Details.(*parser.ExtensionDetails)
+	if !ok {
+		return fmt.Errorf("invalid CREATE EXTENSION details")
+	}
+
+	// CREATE EXTENSION IF NOT EXISTS leaves an installed extension as it is
+	if _, exists := a.state.GetExtension(details.ExtensionName); exists {
+		return nil
+	}
+
+	ext := state.NewExtension(details.ExtensionName)
+	ext.Schema = details.Schema
+	ext.Version = details.Version
+	ext.Cascade = details.Cascade
+	ext.CreatedIn = a.currentMigration
+	a.state.AddOrUpdateExtension(ext)
+
+	return nil
+}
+
+func (a *Applier) applyAlterExtension(stmt *parser.Statement) error {
+	details, ok := stmt.Details.(*parser.ExtensionDetails)
+	if !ok {
+		return fmt.Errorf("invalid ALTER EXTENSION details")
+	}
+
+	ext, exists := a.state.GetExtension(details.ExtensionName)
+	if !exists {
+		a.warn("ALTER EXTENSION on %s, which no earlier migration creates", details.ExtensionName)
+		return nil
+	}
+
+	if details.Update {
+		ext.Version = details.Version
+	} else {
+		ext.Schema = details.Schema
+	}
+
+	return nil
+}
+
+func (a *Applier) applyCreateSchema(stmt *parser.Statement) error {
+	details, ok := stmt.Details.(*parser.SchemaDetails)
+	if !ok {
+		return fmt.Errorf("invalid CREATE SCHEMA details")
+	}
+
+	// CREATE SCHEMA IF NOT EXISTS leaves an existing schema as it is, and public always exists
+	if a.state.HasSchema(details.SchemaName) {
+		return nil
+	}
+
+	schema := state.NewSchema(details.SchemaName)
+	schema.Authorization = details.Authorization
+	schema.CreatedIn = a.currentMigration
+	schema.Privileges = a.state.NewPrivileges("SCHEMA", "")
+	a.state.AddOrUpdateSchema(schema)
+
+	return nil
+}
+
+func (a *Applier) applyAlterSchema(stmt *parser.Statement) error {
+	details, ok := stmt.Details.(*parser.SchemaDetails)
+	if !ok {
+		return fmt.Errorf("invalid ALTER SCHEMA details")
+	}
+
+	if !a.state.RenameSchema(details.SchemaName, details.NewName, a.qualifyReference) {
+		a.warn("schema %s not found, RENAME skipped", details.SchemaName)
+	}
+
+	return nil
+}
+
+func (a *Applier) applyDropSchema(stmt *parser.Statement) error {
+	details, ok := stmt.Details.(*parser.DropDetails)
+	if !ok {
+		return fmt.Errorf("invalid DROP SCHEMA details")
+	}
+
+	for _, object := range details.Objects {
+		if !details.Cascade {
+			if objects := a.state.SchemaObjects(object.Name); len(objects) > 0 {
+				a.warn("schema %s is not empty, dropping it along with %s", object.Name, strings.Join(objects, ", "))
+			}
+		}
+		a.state.DropSchema(object.Name)
+	}
+
+	return nil
+}
+
+func (a *Applier) applyDropExtension(stmt *parser.Statement) error {
+	details, ok := stmt.Details.(*parser.DropDetails)
+	if !ok {
+		return fmt.Errorf("invalid DROP EXTENSION details")
+	}
+
+	for _, object := range details.Objects {
+		a.dropDependents("extension "+object.Name, a.state.ExtensionDependents(object.Name, a.qualifyReference), details.Cascade, nil)
+		a.state.DropExtension(object.Name)
+	}
+	return nil
+}
+
+func (a *Applier) applySetSearchPath(stmt *parser.Statement) error {
+	details, ok := stmt.Details.(*parser.SearchPathDetails)
+	if !ok {
+		return fmt.Errorf("invalid SET search_path details")
+	}
+
+	// An empty list is the default path
+	a.searchPath = details.Schemas
+	if len(details.Schemas) == 0 {
+		a.searchPath = nil
+	}
+
 	return nil
 }
 
@@ -488,29 +1982,58 @@ func (a *Applier) applyComment(stmt *parser.Statement) error {
 		return fmt.Errorf("invalid COMMENT details")
 	}
 
+	key := a.qualify(details.Schema, details.ObjectName)
+
 	switch details.ObjectType {
 	case "TABLE":
-		if table, exists := a.state.GetTable(details.ObjectName); exists {
+		if table, exists := a.state.GetTable(key); exists {
 			table.TableComment = details.Comment
 		}
 	case "COLUMN":
-		// Parse table.column format
-		parts := strings.Split(details.ObjectName, ".")
-		if len(parts) == 2 {
-			tableName := parts[0]
-			colName := parts[1]
-			if table, exists := a.state.GetTable(tableName); exists {
-				table.SetColumnComment(colName, details.Comment)
-			}
+		if table, exists := a.state.GetTable(key); exists && details.ColumnName != "" {
+			table.SetColumnComment(details.ColumnName, details.Comment)
 		}
 	case "TYPE":
-		if enum, exists := a.state.GetEnum(details.ObjectName); exists {
+		if enum, exists := a.state.GetEnum(key); exists {
 			enum.TypeComment = details.Comment
+		} else if ct, exists := a.state.GetCompositeType(key); exists {
+			ct.Comment = details.Comment
+		} else if rt, exists := a.state.GetRangeType(key); exists {
+			rt.Comment = details.Comment
+		}
+	case "DOMAIN":
+		if domain, exists := a.state.GetDomain(key); exists {
+			domain.Comment = details.Comment
 		}
 	case "VIEW":
-		if view, exists := a.state.GetView(details.ObjectName); exists {
+		if view, exists := a.state.GetView(key); exists {
 			view.Comment = details.Comment
 		}
+	case "MATERIALIZED VIEW":
+		if mview, exists := a.state.GetMaterializedView(key); exists {
+			mview.Comment = details.Comment
+		}
+	case "SEQUENCE":
+		if seq, exists := a.state.GetSequence(key); exists {
+			seq.Comment = details.Comment
+		}
+	case "FUNCTION", "PROCEDURE":
+		fnKey := state.FunctionKey(key, details.Signature)
+		// The argument list may be omitted when the name identifies a single function
+		if overloads := a.state.FunctionOverloads(key); len(overloads) == 1 && details.Signature == "" {
+			fnKey = overloads[0]
+		}
+		if fn, exists := a.state.GetFunction(fnKey); exists {
+			fn.Comment = details.Comment
+		}
+	case "EXTENSION":
+		if ext, exists := a.state.GetExtension(details.ObjectName); exists {
+			ext.Comment = details.Comment
+		}
+	case "SCHEMA":
+		if schema, exists := a.state.GetSchema(details.ObjectName); exists {
+			schema.Comment = details.Comment
+		}
 	}
 
 	return nil
@@ -524,42 +2047,8 @@ func (a *Applier) applyDoBlock(stmt *parser.Statement) error {
 
 	// If it contains ALTER TYPE ADD VALUE, apply it
 	if details.TypeName != "" && details.Value != "" {
-		enum, exists := a.state.GetEnum(details.TypeName)
-		if !exists {
-			enum = state.NewEnum(details.TypeName)
-			enum.CreatedIn = a.currentMigration
-			a.state.AddOrUpdateEnum(enum)
-		}
-		enum.AddValue(details.Value)
+		a.addEnumValue(details.Schema, details.TypeName, details.Value, details.Before, details.After)
 	}
 
 	return nil
 }
-
-// splitTopLevel splits a string by delimiter at top level (not within parentheses)
-func splitTopLevel(s string, delim rune) []string {
-	var parts []string
-	var current strings.Builder
-	depth := 0
-
-	for _, ch := range s {
-		if ch == '(' {
-			depth++
-			current.WriteRune(ch)
-		} else if ch == ')' {
-			depth--
-			current.WriteRune(ch)
-		} else if ch == delim && depth == 0 {
-			parts = append(parts, current.String())
-			current.Reset()
-		} else {
-			current.WriteRune(ch)
-		}
-	}
-
-	if current.Len() > 0 {
-		parts = append(parts, current.String())
-	}
-
-	return parts
-}
@@ -1,7 +1,9 @@
 package consolidator
 
 import (
+	"errors"
 	"fmt"
+	"sort"
 
 	"github.com/brianstarke/schemactor/internal/migration"
 	"github.com/brianstarke/schemactor/internal/parser"
@@ -10,9 +12,11 @@ import (
 
 // Consolidator orchestrates the migration consolidation process
 type Consolidator struct {
-	inputDir  string
-	outputDir string
-	verbose   bool
+	inputDir    string
+	outputDir   string
+	verbose     bool
+	strict      bool
+	createRoles bool
 }
 
 // NewConsolidator creates a new consolidator
@@ -24,6 +28,16 @@ func NewConsolidator(inputDir, outputDir string, verbose bool) *Consolidator {
 	}
 }
 
+// SetStrict makes consolidation fail when any statement was skipped or only partly understood
+func (c *Consolidator) SetStrict(strict bool) {
+	c.strict = strict
+}
+
+// SetCreateRoles makes the consolidated migrations create the roles that own objects or are granted privileges
+func (c *Consolidator) SetCreateRoles(createRoles bool) {
+	c.createRoles = createRoles
+}
+
 // Consolidate runs the consolidation process
 func (c *Consolidator) Consolidate(dryRun bool) error {
 	// Phase 1: Read migrations
@@ -49,6 +63,8 @@ func (c *Consolidator) Consolidate(dryRun bool) error {
 	dbState := state.NewDatabaseState()
 	sqlParser := parser.NewParser()
 	applier := NewApplier(dbState)
+	var diagnostics []parser.Diagnostic
+	var errs []error
 
 	for _, mig := range migrations {
 		if c.verbose {
@@ -57,17 +73,38 @@ func (c *Consolidator) Consolidate(dryRun bool) error {
 
 		// Set current migration number for tracking creation order
 		applier.SetCurrentMigration(mig.Number)
+		parserSeen, applierSeen := len(sqlParser.Diagnostics()), len(applier.Diagnostics())
 
+		// Keep going after a failure so one run reports every problem
 		statements, err := sqlParser.ParseFile(mig.UpPath)
 		if err != nil {
-			return fmt.Errorf("parsing migration %s: %w", mig.Name, err)
+			errs = append(errs, err)
 		}
 
 		for _, stmt := range statements {
 			if err := applier.Apply(stmt); err != nil {
-				return fmt.Errorf("applying statement in %s: %w", mig.Name, err)
+				errs = append(errs, err)
 			}
 		}
+
+		// Keep this migration's diagnostics in statement order
+		found := append([]parser.Diagnostic{}, sqlParser.Diagnostics()[parserSeen:]...)
+		found = append(found, applier.Diagnostics()[applierSeen:]...)
+		sort.SliceStable(found, func(i, j int) bool {
+			return found[i].Pos.Line < found[j].Pos.Line
+		})
+		diagnostics = append(diagnostics, found...)
+	}
+
+	// Report everything that was skipped or only partly understood
+	for _, d := range diagnostics {
+		fmt.Printf("Warning: %s\n", d)
+	}
+	if len(errs) > 0 {
+		return fmt.Errorf("building state: %w", errors.Join(errs...))
+	}
+	if c.strict && len(diagnostics) > 0 {
+		return fmt.Errorf("strict mode: %d statements were not fully understood", len(diagnostics))
 	}
 
 	if c.verbose {
@@ -118,6 +155,7 @@ func (c *Consolidator) Consolidate(dryRun bool) error {
 	}
 
 	generator := NewGenerator(dbState, depGraph)
+	generator.SetCreateRoles(c.createRoles)
 	consolidatedMigrations, err := generator.Generate(orderedObjects)
 	if err != nil {
 		return fmt.Errorf("generating migrations: %w", err)
@@ -136,7 +174,7 @@ func (c *Consolidator) Consolidate(dryRun bool) error {
 
 	if dryRun {
 		if c.verbose {
-			fmt.Println("\n*** DRY RUN MODE - No files will be written ***\n")
+			fmt.Print("\n*** DRY RUN MODE - No files will be written ***\n\n")
 		}
 		writer.PreviewMigrations(consolidatedMigrations)
 	} else {
@@ -1,10 +1,11 @@
 package consolidator
 
 import (
-	"fmt"
 	"regexp"
+	"sort"
 	"strings"
 
+	"github.com/brianstarke/schemactor/internal/parser"
 	"github.com/brianstarke/schemactor/internal/state"
 )
 
@@ -12,10 +13,16 @@ import (
 type ObjectType int
 
 const (
-	ObjectDomain ObjectType = iota
+	ObjectExtension ObjectType = iota
+	ObjectDomain
 	ObjectEnum
+	ObjectRange
+	ObjectComposite
+	ObjectSequence
+	ObjectFunction
 	ObjectTable
 	ObjectView
+	ObjectMaterializedView
 )
 
 // DependencyNode represents a node in the dependency graph
@@ -59,6 +66,11 @@ func (g *DependencyGraph) AddEdge(from, to string) {
 func BuildDependencyGraph(dbState *state.DatabaseState) *DependencyGraph {
 	graph := NewDependencyGraph()
 
+	// Add all extensions, keyed by their unqualified name
+	for name, ext := range dbState.Extensions {
+		graph.AddNode(ObjectExtension, name, ext.CreatedIn)
+	}
+
 	// Add all domains
 	for name, domain := range dbState.Domains {
 		graph.AddNode(ObjectDomain, name, domain.CreatedIn)
@@ -69,6 +81,26 @@ func BuildDependencyGraph(dbState *state.DatabaseState) *DependencyGraph {
 		graph.AddNode(ObjectEnum, name, enum.CreatedIn)
 	}
 
+	// Add all range types
+	for name, rt := range dbState.RangeTypes {
+		graph.AddNode(ObjectRange, name, rt.CreatedIn)
+	}
+
+	// Add all composite types
+	for name, ct := range dbState.CompositeTypes {
+		graph.AddNode(ObjectComposite, name, ct.CreatedIn)
+	}
+
+	// Add all sequences
+	for name, seq := range dbState.Sequences {
+		graph.AddNode(ObjectSequence, name, seq.CreatedIn)
+	}
+
+	// Add all functions, keyed by name and signature
+	for key, fn := range dbState.Functions {
+		graph.AddNode(ObjectFunction, key, fn.CreatedIn)
+	}
+
 	// Add all tables
 	for name, table := range dbState.Tables {
 		graph.AddNode(ObjectTable, name, table.CreatedIn)
@@ -79,6 +111,11 @@ func BuildDependencyGraph(dbState *state.DatabaseState) *DependencyGraph {
 		graph.AddNode(ObjectView, name, view.CreatedIn)
 	}
 
+	// Add all materialized views
+	for name, mview := range dbState.MaterializedViews {
+		graph.AddNode(ObjectMaterializedView, name, mview.CreatedIn)
+	}
+
 	// Build edges for tables
 	for tableName, table := range dbState.Tables {
 		// Table depends on foreign key references
@@ -88,6 +125,13 @@ func BuildDependencyGraph(dbState *state.DatabaseState) *DependencyGraph {
 			}
 		}
 
+		// Partition depends on its parent, and an inheriting table on the tables it inherits from
+		for _, parent := range append([]string{table.PartitionOf}, table.Inherits...) {
+			if _, exists := dbState.Tables[parent]; exists && parent != tableName {
+				graph.AddEdge(tableName, parent)
+			}
+		}
+
 		// Table depends on enums used in columns
 		enumDeps := findEnumDependencies(table, dbState)
 		for _, enumName := range enumDeps {
@@ -103,6 +147,114 @@ func BuildDependencyGraph(dbState *state.DatabaseState) *DependencyGraph {
 		for _, domainName := range domainDeps {
 			graph.AddEdge(tableName, domainName)
 		}
+
+		// Table depends on composite and range types used in columns
+		for _, colName := range table.ColumnOrder {
+			colType := typeReference(table.Columns[colName].Type)
+			if dbState.CompositeTypes[colType] != nil || dbState.RangeTypes[colType] != nil {
+				graph.AddEdge(tableName, colType)
+			}
+		}
+
+		// Table depends on sequences used by column defaults
+		for _, seqName := range findSequenceDependencies(table, dbState) {
+			graph.AddEdge(tableName, seqName)
+		}
+
+		// Table depends on the functions its triggers execute
+		for _, trigger := range table.Triggers {
+			if fnKey := state.FunctionKey(trigger.Function, ""); dbState.Functions[fnKey] != nil {
+				graph.AddEdge(tableName, fnKey)
+			}
+		}
+
+		// Table depends on functions called by defaults, generated columns, checks, indexes, trigger conditions and policies
+		for _, expr := range tableExpressions(table) {
+			for _, fnKey := range findFunctionCalls(expr, dbState) {
+				graph.AddEdge(tableName, fnKey)
+			}
+		}
+
+		// Table depends on the extensions providing its column types, called functions and index operator classes
+		var colTypes []string
+		for _, colName := range table.ColumnOrder {
+			colTypes = append(colTypes, table.Columns[colName].Type)
+		}
+		for _, ext := range findExtensionDependencies(colTypes, tableExpressions(table), dbState) {
+			graph.AddEdge(tableName, ext)
+		}
+	}
+
+	// Build edges for domains
+	for domainName, domain := range dbState.Domains {
+		exprs := []string{domain.Default}
+		for _, constraint := range domain.Constraints {
+			exprs = append(exprs, constraint.Expression)
+		}
+		for _, expr := range exprs {
+			for _, fnKey := range findFunctionCalls(expr, dbState) {
+				graph.AddEdge(domainName, fnKey)
+			}
+		}
+
+		for _, ext := range findExtensionDependencies([]string{domain.BaseType}, exprs, dbState) {
+			graph.AddEdge(domainName, ext)
+		}
+	}
+
+	// Build edges for composite types, which depend on the types of their attributes
+	for typeName, ct := range dbState.CompositeTypes {
+		types := compositeTypes(ct)
+		for _, dep := range findTypeDependencies(types, dbState) {
+			if dep != typeName {
+				graph.AddEdge(typeName, dep)
+			}
+		}
+		for _, ext := range findExtensionDependencies(types, nil, dbState) {
+			graph.AddEdge(typeName, ext)
+		}
+	}
+
+	// Build edges for range types, which depend on their subtype and support functions
+	for typeName, rt := range dbState.RangeTypes {
+		subtype := []string{rt.Option("SUBTYPE")}
+		for _, dep := range findTypeDependencies(subtype, dbState) {
+			graph.AddEdge(typeName, dep)
+		}
+		for _, ext := range findExtensionDependencies(subtype, nil, dbState) {
+			graph.AddEdge(typeName, ext)
+		}
+		if diff := rt.Option("SUBTYPE_DIFF"); diff != "" {
+			for _, fnKey := range dbState.FunctionOverloads(typeReference(diff)) {
+				graph.AddEdge(typeName, fnKey)
+			}
+		}
+	}
+
+	// Build edges for functions
+	for fnKey, fn := range dbState.Functions {
+		// Function depends on the types of its arguments and result
+		types := functionTypes(fn)
+		for _, dep := range findTypeDependencies(types, dbState) {
+			graph.AddEdge(fnKey, dep)
+		}
+		for _, ext := range findExtensionDependencies(types, nil, dbState) {
+			graph.AddEdge(fnKey, ext)
+		}
+
+		// SQL-language functions depend on the tables and views their body reads
+		for _, dep := range fn.DependsOn {
+			if _, exists := graph.Nodes[dep]; exists {
+				graph.AddEdge(fnKey, dep)
+			}
+		}
+	}
+
+	// The owning table sets OWNED BY once both exist, so it comes after the sequence
+	for seqName, seq := range dbState.Sequences {
+		if _, exists := dbState.Tables[seq.OwnedBy]; exists {
+			graph.AddEdge(seq.OwnedBy, seqName)
+		}
 	}
 
 	// Build edges for views
@@ -115,6 +267,37 @@ func BuildDependencyGraph(dbState *state.DatabaseState) *DependencyGraph {
 				}
 			}
 		}
+
+		// View depends on the functions it calls
+		for _, fnKey := range findFunctionCalls(view.Definition, dbState) {
+			graph.AddEdge(viewName, fnKey)
+		}
+		for _, ext := range findExtensionDependencies(nil, []string{view.Definition}, dbState) {
+			graph.AddEdge(viewName, ext)
+		}
+	}
+
+	// Build edges for materialized views
+	for mviewName, mview := range dbState.MaterializedViews {
+		for _, dep := range mview.DependsOn {
+			if _, exists := graph.Nodes[dep]; exists && dep != mviewName {
+				graph.AddEdge(mviewName, dep)
+			}
+		}
+
+		// Materialized view depends on the functions it and its indexes call
+		exprs := []string{mview.Definition}
+		for _, idx := range mview.Indexes {
+			exprs = append(exprs, idx.Expressions()...)
+		}
+		for _, expr := range exprs {
+			for _, fnKey := range findFunctionCalls(expr, dbState) {
+				graph.AddEdge(mviewName, fnKey)
+			}
+		}
+		for _, ext := range findExtensionDependencies(nil, exprs, dbState) {
+			graph.AddEdge(mviewName, ext)
+		}
 	}
 
 	return graph
@@ -128,10 +311,7 @@ func findEnumDependencies(table *state.Table, dbState *state.DatabaseState) []st
 	for _, colName := range table.ColumnOrder {
 		col := table.Columns[colName]
 		// Check if column type is an enum
-		colType := strings.TrimSpace(col.Type)
-
-		// Remove type modifiers like NOT NULL
-		colType = strings.Split(colType, " ")[0]
+		colType := typeReference(col.Type)
 
 		if _, exists := dbState.Enums[colType]; exists {
 			if !contains(deps, colType) {
@@ -151,10 +331,7 @@ func findDomainDependencies(table *state.Table, dbState *state.DatabaseState) []
 	for _, colName := range table.ColumnOrder {
 		col := table.Columns[colName]
 		// Check if column type is a domain
-		colType := strings.TrimSpace(col.Type)
-
-		// Remove type modifiers
-		colType = strings.Split(colType, " ")[0]
+		colType := typeReference(col.Type)
 
 		if _, exists := dbState.Domains[colType]; exists {
 			if !contains(deps, colType) {
@@ -166,6 +343,138 @@ func findDomainDependencies(table *state.Table, dbState *state.DatabaseState) []
 	return deps
 }
 
+// findSequenceDependencies finds sequences whose nextval() a table's column defaults call
+func findSequenceDependencies(table *state.Table, dbState *state.DatabaseState) []string {
+	var deps []string
+
+	// Iterate over columns in order to ensure deterministic ordering
+	for _, colName := range table.ColumnOrder {
+		col := table.Columns[colName]
+		for _, ref := range state.NextvalReferences(col.Default) {
+			seqName := typeReference(ref)
+			if _, exists := dbState.Sequences[seqName]; exists && !contains(deps, seqName) {
+				deps = append(deps, seqName)
+			}
+		}
+	}
+
+	return deps
+}
+
+// tableExpressions returns the expressions of a table that may call functions
+func tableExpressions(table *state.Table) []string {
+	var exprs []string
+	for _, colName := range table.ColumnOrder {
+		col := table.Columns[colName]
+		exprs = append(exprs, col.Default, col.Generated)
+	}
+	for _, check := range table.Checks {
+		exprs = append(exprs, check.Expression)
+	}
+	for _, idx := range table.Indexes {
+		exprs = append(exprs, idx.Expressions()...)
+	}
+	for _, trigger := range table.Triggers {
+		exprs = append(exprs, trigger.When)
+	}
+	for _, policy := range table.Policies {
+		exprs = append(exprs, policy.Using, policy.WithCheck)
+	}
+	return exprs
+}
+
+// findFunctionCalls finds the functions an expression calls
+// Calls cannot be matched to an overload without knowing argument types, so every overload is returned
+func findFunctionCalls(expr string, dbState *state.DatabaseState) []string {
+	var deps []string
+	for _, call := range state.FunctionCalls(parser.MaskLiterals(expr)) {
+		for _, fnKey := range dbState.FunctionOverloads(typeReference(call)) {
+			if !contains(deps, fnKey) {
+				deps = append(deps, fnKey)
+			}
+		}
+	}
+	return deps
+}
+
+// functionTypes returns the types a function takes or returns
+func functionTypes(fn *state.Function) []string {
+	types := parser.RoutineArgumentTypes(fn.Arguments)
+
+	returns := strings.TrimSpace(fn.Returns)
+	if setof := regexp.MustCompile(`(?i)^SETOF\s+`); setof.MatchString(returns) {
+		returns = setof.ReplaceAllString(returns, "")
+	}
+	if regexp.MustCompile(`(?i)^TABLE\s*\(`).MatchString(returns) {
+		types = append(types, parser.RoutineArgumentTypes(parser.ExtractParenthesesContent(returns))...)
+	} else if returns != "" {
+		types = append(types, returns)
+	}
+	return types
+}
+
+// compositeTypes returns the types of a composite type's attributes
+func compositeTypes(ct *state.CompositeType) []string {
+	var types []string
+	for _, attr := range ct.Attributes {
+		types = append(types, attr.Type)
+	}
+	return types
+}
+
+// findTypeDependencies finds the enums, domains, composite and range types and table row types among the given types
+func findTypeDependencies(types []string, dbState *state.DatabaseState) []string {
+	var deps []string
+	for _, t := range types {
+		ref := typeReference(t)
+		_, isEnum := dbState.Enums[ref]
+		_, isDomain := dbState.Domains[ref]
+		_, isComposite := dbState.CompositeTypes[ref]
+		_, isRange := dbState.RangeTypes[ref]
+		_, isTable := dbState.Tables[ref]
+		if (isEnum || isDomain || isComposite || isRange || isTable) && !contains(deps, ref) {
+			deps = append(deps, ref)
+		}
+	}
+	return deps
+}
+
+// identifierPattern matches the words of an expression that may name an operator class
+var identifierPattern = regexp.MustCompile(`[A-Za-z_][A-Za-z0-9_]*`)
+
+// findExtensionDependencies finds the installed extensions providing the given types, or the functions
+// and operator classes used in the given expressions
+// Only the objects listed in state.KnownExtensions are recognized
+func findExtensionDependencies(types, exprs []string, dbState *state.DatabaseState) []string {
+	var deps []string
+	add := func(kind, name string) {
+		ext, ok := state.ExtensionProviding(kind, name)
+		if !ok || contains(deps, ext) {
+			return
+		}
+		if _, exists := dbState.Extensions[ext]; exists {
+			deps = append(deps, ext)
+		}
+	}
+
+	for _, t := range types {
+		if _, name, _ := parser.ReadQualifiedName(t); name != "" {
+			add("type", name)
+		}
+	}
+	for _, expr := range exprs {
+		masked := parser.MaskLiterals(expr)
+		for _, call := range state.FunctionCalls(masked) {
+			add("function", call)
+		}
+		for _, word := range identifierPattern.FindAllString(masked, -1) {
+			add("opclass", word)
+		}
+	}
+
+	return deps
+}
+
 // TopologicalSort performs a topological sort on the dependency graph
 // Returns objects in order: dependencies first
 func (g *DependencyGraph) TopologicalSort() ([]string, error) {
@@ -211,7 +520,14 @@ func (g *DependencyGraph) TopologicalSort() ([]string, error) {
 	}
 
 	if len(result) != len(g.Nodes) {
-		return nil, fmt.Errorf("circular dependency detected")
+		var cycle []string
+		for node := range g.Nodes {
+			if inDegree[node] > 0 {
+				cycle = append(cycle, node)
+			}
+		}
+		sort.Strings(cycle)
+		return nil, &DependencyError{Objects: cycle}
 	}
 
 	return result, nil
@@ -247,16 +563,28 @@ func sortByPriority(nodes []string, graph *DependencyGraph) {
 // getPriority returns priority value for object type (lower = higher priority)
 func getPriority(node *DependencyNode) int {
 	switch node.Type {
-	case ObjectDomain:
+	case ObjectExtension:
 		return 0
-	case ObjectEnum:
+	case ObjectDomain:
 		return 1
-	case ObjectTable:
+	case ObjectEnum:
 		return 2
-	case ObjectView:
+	case ObjectRange:
 		return 3
-	default:
+	case ObjectComposite:
 		return 4
+	case ObjectSequence:
+		return 5
+	case ObjectFunction:
+		return 6
+	case ObjectTable:
+		return 7
+	case ObjectView:
+		return 8
+	case ObjectMaterializedView:
+		return 9
+	default:
+		return 10
 	}
 }
 
@@ -266,8 +594,7 @@ func AnalyzeEnumUsage(dbState *state.DatabaseState) {
 		// Iterate over columns in order to ensure deterministic enum ordering
 		for _, colName := range table.ColumnOrder {
 			col := table.Columns[colName]
-			colType := strings.TrimSpace(col.Type)
-			colType = strings.Split(colType, " ")[0]
+			colType := typeReference(col.Type)
 
 			if enum, exists := dbState.Enums[colType]; exists {
 				enum.AddUsedBy(tableName)
@@ -279,15 +606,21 @@ func AnalyzeEnumUsage(dbState *state.DatabaseState) {
 
 // ExtractTypeFromColumn extracts the base type from a column type definition
 func ExtractTypeFromColumn(colType string) string {
-	// Remove parentheses and everything after
-	re := regexp.MustCompile(`^([^\s(]+)`)
-	matches := re.FindStringSubmatch(colType)
+	// Remove parentheses, array brackets and everything after
+	re := regexp.MustCompile(`^([^\s(\[]+)`)
+	matches := re.FindStringSubmatch(strings.TrimSpace(colType))
 	if len(matches) >= 2 {
 		return matches[1]
 	}
 	return colType
 }
 
+// typeReference returns the schema-qualified state key for a column's type
+func typeReference(colType string) string {
+	schema, name, _ := parser.ReadQualifiedName(colType)
+	return state.QualifiedName(schema, name)
+}
+
 func contains(slice []string, item string) bool {
 	for _, s := range slice {
 		if s == item {
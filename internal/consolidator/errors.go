@@ -0,0 +1,32 @@
+package consolidator
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/brianstarke/schemactor/internal/parser"
+)
+
+// ApplyError reports a statement that parsed but could not be applied to the database state
+type ApplyError struct {
+	Pos       parser.Position
+	Statement string
+	Err       error
+}
+
+func (e *ApplyError) Error() string {
+	return fmt.Sprintf("%s: %v", e.Pos, e.Err)
+}
+
+func (e *ApplyError) Unwrap() error {
+	return e.Err
+}
+
+// DependencyError reports objects whose dependencies form a cycle, so no creation order exists
+type DependencyError struct {
+	Objects []string // Objects left unordered, sorted by name
+}
+
+func (e *DependencyError) Error() string {
+	return fmt.Sprintf("circular dependency between %s", strings.Join(e.Objects, ", "))
+}
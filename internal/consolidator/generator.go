@@ -2,6 +2,7 @@ package consolidator
 
 import (
 	"fmt"
+	"sort"
 	"strings"
 
 	"github.com/brianstarke/schemactor/internal/migration"
@@ -10,9 +11,10 @@ import (
 
 // Generator generates SQL from database state
 type Generator struct {
-	state     *state.DatabaseState
-	graph     *DependencyGraph
-	enumsUsed map[string]bool
+	state       *state.DatabaseState
+	graph       *DependencyGraph
+	enumsUsed   map[string]bool
+	createRoles bool
 }
 
 // NewGenerator creates a new SQL generator
@@ -24,11 +26,57 @@ func NewGenerator(dbState *state.DatabaseState, graph *DependencyGraph) *Generat
 	}
 }
 
+// SetCreateRoles makes the migrations create the roles they reference, which otherwise must already exist
+func (g *Generator) SetCreateRoles(createRoles bool) {
+	g.createRoles = createRoles
+}
+
 // Generate generates consolidated migrations
 func (g *Generator) Generate(orderedObjects []string) ([]*migration.ConsolidatedMigration, error) {
 	var migrations []*migration.ConsolidatedMigration
 	migrationNum := 1
 
+	// Roles come before everything that can be owned by or granted to them
+	if roles := g.state.Roles(); g.createRoles && len(roles) > 0 {
+		migrations = append(migrations, &migration.ConsolidatedMigration{
+			Number:  migrationNum,
+			Name:    "create-roles",
+			UpSQL:   g.GenerateRolesSQL(roles),
+			DownSQL: g.GenerateRolesDownSQL(roles),
+		})
+		migrationNum++
+	}
+
+	// Schemas come first, together in one migration, so every other object has a schema to land in
+	if schemas := g.orderedSchemas(); len(schemas) > 0 {
+		migrations = append(migrations, &migration.ConsolidatedMigration{
+			Number:  migrationNum,
+			Name:    "create-schemas",
+			UpSQL:   g.GenerateSchemasSQL(schemas),
+			DownSQL: g.GenerateSchemasDownSQL(schemas),
+		})
+		migrationNum++
+	}
+
+	// Extensions come next, together in one migration
+	var extensions []*state.Extension
+	for _, objName := range orderedObjects {
+		if node, exists := g.graph.Nodes[objName]; exists && node.Type == ObjectExtension {
+			if ext, exists := g.state.Extensions[objName]; exists {
+				extensions = append(extensions, ext)
+			}
+		}
+	}
+	if len(extensions) > 0 {
+		migrations = append(migrations, &migration.ConsolidatedMigration{
+			Number:  migrationNum,
+			Name:    "create-extensions",
+			UpSQL:   g.GenerateExtensionsSQL(extensions),
+			DownSQL: g.GenerateExtensionsDownSQL(extensions),
+		})
+		migrationNum++
+	}
+
 	for _, objName := range orderedObjects {
 		node, exists := g.graph.Nodes[objName]
 		if !exists {
@@ -43,16 +91,72 @@ func (g *Generator) Generate(orderedObjects []string) ([]*migration.Consolidated
 			}
 			migrations = append(migrations, &migration.ConsolidatedMigration{
 				Number:  migrationNum,
-				Name:    fmt.Sprintf("create-%s-domain", domain.Name),
+				Name:    fmt.Sprintf("create-%s-domain", migrationObjectName(domain.Schema, domain.Name)),
 				UpSQL:   g.GenerateDomainSQL(domain),
 				DownSQL: g.GenerateDomainDownSQL(domain),
 			})
 			migrationNum++
 
+		case ObjectExtension:
+			// Extensions were all included in the first migration
+			continue
+
 		case ObjectEnum:
 			// Enums are included in their first table, not as separate migrations
 			continue
 
+		case ObjectRange:
+			rt, exists := g.state.RangeTypes[objName]
+			if !exists {
+				continue
+			}
+			migrations = append(migrations, &migration.ConsolidatedMigration{
+				Number:  migrationNum,
+				Name:    fmt.Sprintf("create-%s-type", migrationObjectName(rt.Schema, rt.Name)),
+				UpSQL:   g.GenerateRangeTypeSQL(rt),
+				DownSQL: g.GenerateTypeDownSQL(rt.Schema, rt.Name),
+			})
+			migrationNum++
+
+		case ObjectComposite:
+			ct, exists := g.state.CompositeTypes[objName]
+			if !exists {
+				continue
+			}
+			migrations = append(migrations, &migration.ConsolidatedMigration{
+				Number:  migrationNum,
+				Name:    fmt.Sprintf("create-%s-type", migrationObjectName(ct.Schema, ct.Name)),
+				UpSQL:   g.GenerateCompositeTypeSQL(ct),
+				DownSQL: g.GenerateTypeDownSQL(ct.Schema, ct.Name),
+			})
+			migrationNum++
+
+		case ObjectSequence:
+			seq, exists := g.state.Sequences[objName]
+			if !exists {
+				continue
+			}
+			migrations = append(migrations, &migration.ConsolidatedMigration{
+				Number:  migrationNum,
+				Name:    fmt.Sprintf("create-%s-sequence", migrationObjectName(seq.Schema, seq.Name)),
+				UpSQL:   g.GenerateSequenceSQL(seq),
+				DownSQL: g.GenerateSequenceDownSQL(seq),
+			})
+			migrationNum++
+
+		case ObjectFunction:
+			fn, exists := g.state.Functions[objName]
+			if !exists {
+				continue
+			}
+			migrations = append(migrations, &migration.ConsolidatedMigration{
+				Number:  migrationNum,
+				Name:    fmt.Sprintf("create-%s-%s", migrationObjectName(fn.Schema, fn.Name), strings.ToLower(fn.Kind)),
+				UpSQL:   g.GenerateFunctionSQL(fn),
+				DownSQL: g.GenerateFunctionDownSQL(fn),
+			})
+			migrationNum++
+
 		case ObjectTable:
 			table, exists := g.state.Tables[objName]
 			if !exists {
@@ -63,7 +167,7 @@ func (g *Generator) Generate(orderedObjects []string) ([]*migration.Consolidated
 
 			migrations = append(migrations, &migration.ConsolidatedMigration{
 				Number:  migrationNum,
-				Name:    fmt.Sprintf("create-%s", table.Name),
+				Name:    fmt.Sprintf("create-%s", migrationObjectName(table.Schema, table.Name)),
 				UpSQL:   upSQL,
 				DownSQL: downSQL,
 			})
@@ -76,11 +180,37 @@ func (g *Generator) Generate(orderedObjects []string) ([]*migration.Consolidated
 			}
 			migrations = append(migrations, &migration.ConsolidatedMigration{
 				Number:  migrationNum,
-				Name:    fmt.Sprintf("create-%s-view", view.Name),
+				Name:    fmt.Sprintf("create-%s-view", migrationObjectName(view.Schema, view.Name)),
 				UpSQL:   g.GenerateViewSQL(view),
 				DownSQL: g.GenerateViewDownSQL(view),
 			})
 			migrationNum++
+
+		case ObjectMaterializedView:
+			mview, exists := g.state.MaterializedViews[objName]
+			if !exists {
+				continue
+			}
+			migrations = append(migrations, &migration.ConsolidatedMigration{
+				Number:  migrationNum,
+				Name:    fmt.Sprintf("create-%s-materialized-view", migrationObjectName(mview.Schema, mview.Name)),
+				UpSQL:   g.GenerateMaterializedViewSQL(mview),
+				DownSQL: g.GenerateMaterializedViewDownSQL(mview),
+			})
+			migrationNum++
+		}
+	}
+
+	// Default privileges come last, so they only apply to objects created after the consolidated ones
+	if len(g.state.DefaultPrivileges) > 0 {
+		upSQL, downSQL := g.GenerateDefaultPrivilegesSQL(g.state.DefaultPrivileges)
+		if upSQL != "" {
+			migrations = append(migrations, &migration.ConsolidatedMigration{
+				Number:  migrationNum,
+				Name:    "alter-default-privileges",
+				UpSQL:   upSQL,
+				DownSQL: downSQL,
+			})
 		}
 	}
 
@@ -101,6 +231,7 @@ func (g *Generator) GenerateTableMigration(table *state.Table) (string, string)
 
 	// Generate table SQL
 	upSQL.WriteString(g.GenerateTableSQL(table))
+	upSQL.WriteString(g.GenerateSequenceOwnershipSQL(table))
 
 	// Generate down SQL
 	downSQL.WriteString(g.GenerateTableDownSQL(table))
@@ -110,9 +241,14 @@ func (g *Generator) GenerateTableMigration(table *state.Table) (string, string)
 
 // GenerateRequiredEnums generates CREATE TYPE statements for enums used by table
 func (g *Generator) GenerateRequiredEnums(table *state.Table) string {
+	return g.generateEnums(table.RequiredEnums)
+}
+
+// generateEnums generates CREATE TYPE statements for the given enums not yet generated
+func (g *Generator) generateEnums(enumNames []string) string {
 	var sql strings.Builder
 
-	for _, enumName := range table.RequiredEnums {
+	for _, enumName := range enumNames {
 		if g.enumsUsed[enumName] {
 			continue
 		}
@@ -131,15 +267,99 @@ func (g *Generator) GenerateRequiredEnums(table *state.Table) string {
 	return strings.TrimSpace(sql.String())
 }
 
+// generateTypeEnums generates CREATE TYPE statements for the enums among the given types not yet generated
+// The result is empty or ends with a blank line, ready to be followed by the statement using the types
+func (g *Generator) generateTypeEnums(types []string) string {
+	var enumNames []string
+	for _, dep := range findTypeDependencies(types, g.state) {
+		if _, isEnum := g.state.Enums[dep]; isEnum {
+			enumNames = append(enumNames, dep)
+		}
+	}
+
+	enumSQL := g.generateEnums(enumNames)
+	if enumSQL == "" {
+		return ""
+	}
+	return enumSQL + "\n\n"
+}
+
+// GenerateCompositeTypeSQL generates CREATE TYPE SQL for a composite type
+// Enums among its attribute types that no earlier migration created are included first
+func (g *Generator) GenerateCompositeTypeSQL(ct *state.CompositeType) string {
+	var sql strings.Builder
+
+	sql.WriteString(g.generateTypeEnums(compositeTypes(ct)))
+
+	typeName := qualifiedIdent(ct.Schema, ct.Name)
+
+	sql.WriteString(fmt.Sprintf("CREATE TYPE %s AS (\n", typeName))
+	for i, attr := range ct.Attributes {
+		sql.WriteString(fmt.Sprintf("    %s %s", state.QuoteIdentifier(attr.Name), attr.Type))
+		if attr.Collation != "" {
+			sql.WriteString(fmt.Sprintf(" COLLATE %s", attr.Collation))
+		}
+		if i < len(ct.Attributes)-1 {
+			sql.WriteString(",")
+		}
+		sql.WriteString("\n")
+	}
+	sql.WriteString(");\n")
+
+	if ct.Comment != "" {
+		sql.WriteString(fmt.Sprintf("\nCOMMENT ON TYPE %s IS '%s';\n",
+			typeName, escapeComment(ct.Comment)))
+	}
+
+	sql.WriteString(g.GeneratePrivilegesSQL("TYPE", typeName, ct.Privileges))
+
+	return sql.String()
+}
+
+// GenerateRangeTypeSQL generates CREATE TYPE SQL for a range type
+func (g *Generator) GenerateRangeTypeSQL(rt *state.RangeType) string {
+	var sql strings.Builder
+
+	sql.WriteString(g.generateTypeEnums([]string{rt.Option("SUBTYPE")}))
+
+	typeName := qualifiedIdent(rt.Schema, rt.Name)
+
+	sql.WriteString(fmt.Sprintf("CREATE TYPE %s AS RANGE (\n", typeName))
+	for i, option := range rt.Options {
+		sql.WriteString("    " + option)
+		if i < len(rt.Options)-1 {
+			sql.WriteString(",")
+		}
+		sql.WriteString("\n")
+	}
+	sql.WriteString(");\n")
+
+	if rt.Comment != "" {
+		sql.WriteString(fmt.Sprintf("\nCOMMENT ON TYPE %s IS '%s';\n",
+			typeName, escapeComment(rt.Comment)))
+	}
+
+	sql.WriteString(g.GeneratePrivilegesSQL("TYPE", typeName, rt.Privileges))
+
+	return sql.String()
+}
+
+// GenerateTypeDownSQL generates DROP TYPE SQL for a composite or range type
+func (g *Generator) GenerateTypeDownSQL(schema, name string) string {
+	return fmt.Sprintf("DROP TYPE IF EXISTS %s;\n", qualifiedIdent(schema, name))
+}
+
 // GenerateEnumSQL generates CREATE TYPE SQL for an enum
 func (g *Generator) GenerateEnumSQL(enum *state.Enum) string {
 	var sql strings.Builder
 
-	sql.WriteString(fmt.Sprintf("DROP TYPE IF EXISTS %s;\n", enum.Name))
-	sql.WriteString(fmt.Sprintf("CREATE TYPE %s AS ENUM (\n", enum.Name))
+	enumName := qualifiedIdent(enum.Schema, enum.Name)
+
+	sql.WriteString(fmt.Sprintf("DROP TYPE IF EXISTS %s;\n", enumName))
+	sql.WriteString(fmt.Sprintf("CREATE TYPE %s AS ENUM (\n", enumName))
 
 	for i, value := range enum.Values {
-		sql.WriteString(fmt.Sprintf("    '%s'", value))
+		sql.WriteString("    " + quoteLiteral(value))
 		if i < len(enum.Values)-1 {
 			sql.WriteString(",")
 		}
@@ -151,9 +371,11 @@ func (g *Generator) GenerateEnumSQL(enum *state.Enum) string {
 	// Add comment if exists
 	if enum.TypeComment != "" {
 		sql.WriteString(fmt.Sprintf("\nCOMMENT ON TYPE %s IS '%s';\n",
-			enum.Name, escapeComment(enum.TypeComment)))
+			enumName, escapeComment(enum.TypeComment)))
 	}
 
+	sql.WriteString(g.GeneratePrivilegesSQL("TYPE", enumName, enum.Privileges))
+
 	return sql.String()
 }
 
@@ -161,7 +383,25 @@ func (g *Generator) GenerateEnumSQL(enum *state.Enum) string {
 func (g *Generator) GenerateTableSQL(table *state.Table) string {
 	var sql strings.Builder
 
-	sql.WriteString(fmt.Sprintf("CREATE TABLE %s (\n", table.Name))
+	tableName := qualifiedIdent(table.Schema, table.Name)
+
+	// A partition without columns of its own takes them from its parent, and only lists its own constraints
+	partitionOf := table.IsPartition() && len(table.ColumnOrder) == 0
+	hasDefinition := !partitionOf || table.PrimaryKey != nil ||
+		len(table.Uniques) > 0 || len(table.Checks) > 0 || len(table.ForeignKeys) > 0
+
+	create := "CREATE TABLE"
+	if table.Unlogged {
+		create = "CREATE UNLOGGED TABLE"
+	}
+	if partitionOf {
+		sql.WriteString(fmt.Sprintf("%s %s PARTITION OF %s", create, tableName, qualifiedReference(table.PartitionOf)))
+		if hasDefinition {
+			sql.WriteString(" (\n")
+		}
+	} else {
+		sql.WriteString(fmt.Sprintf("%s %s (\n", create, tableName))
+	}
 
 	// Generate column definitions
 	for i, colName := range table.ColumnOrder {
@@ -183,8 +423,9 @@ func (g *Generator) GenerateTableSQL(table *state.Table) string {
 
 	// Add primary key
 	if table.PrimaryKey != nil {
-		sql.WriteString(fmt.Sprintf("    PRIMARY KEY (%s)",
-			strings.Join(table.PrimaryKey.Columns, ", ")))
+		sql.WriteString(fmt.Sprintf("    %sPRIMARY KEY (%s)",
+			constraintName(table.PrimaryKey.Name, table.DefaultPrimaryKeyName()),
+			strings.Join(state.QuoteIdentifiers(table.PrimaryKey.Columns), ", ")))
 
 		needsComma := len(table.Checks) > 0 ||
 			len(table.Uniques) > 0 ||
@@ -198,8 +439,9 @@ func (g *Generator) GenerateTableSQL(table *state.Table) string {
 
 	// Add unique constraints
 	for i, unique := range table.Uniques {
-		sql.WriteString(fmt.Sprintf("    UNIQUE (%s)",
-			strings.Join(unique.Columns, ", ")))
+		sql.WriteString(fmt.Sprintf("    %sUNIQUE (%s)",
+			constraintName(unique.Name, table.DefaultUniqueName(unique.Columns)),
+			strings.Join(state.QuoteIdentifiers(unique.Columns), ", ")))
 
 		needsComma := i < len(table.Uniques)-1 ||
 			len(table.Checks) > 0 ||
@@ -213,7 +455,9 @@ func (g *Generator) GenerateTableSQL(table *state.Table) string {
 
 	// Add check constraints
 	for i, check := range table.Checks {
-		sql.WriteString(fmt.Sprintf("    CHECK (%s)", check.Expression))
+		sql.WriteString(fmt.Sprintf("    %sCHECK (%s)",
+			constraintName(check.Name, table.DefaultCheckName(check.Expression)),
+			check.Expression))
 
 		needsComma := i < len(table.Checks)-1 || len(table.ForeignKeys) > 0
 
@@ -225,10 +469,13 @@ func (g *Generator) GenerateTableSQL(table *state.Table) string {
 
 	// Add foreign keys
 	for i, fk := range table.ForeignKeys {
-		sql.WriteString(fmt.Sprintf("    FOREIGN KEY (%s) REFERENCES %s (%s)",
-			strings.Join(fk.Columns, ", "),
-			fk.ReferencedTable,
-			strings.Join(fk.ReferencedColumns, ", ")))
+		sql.WriteString(fmt.Sprintf("    %sFOREIGN KEY (%s) REFERENCES %s",
+			constraintName(fk.Name, table.DefaultForeignKeyName(fk.Columns)),
+			strings.Join(state.QuoteIdentifiers(fk.Columns), ", "),
+			qualifiedReference(fk.ReferencedTable)))
+		if len(fk.ReferencedColumns) > 0 {
+			sql.WriteString(fmt.Sprintf(" (%s)", strings.Join(state.QuoteIdentifiers(fk.ReferencedColumns), ", ")))
+		}
 
 		if fk.OnDelete != "" {
 			sql.WriteString(fmt.Sprintf(" ON DELETE %s", fk.OnDelete))
@@ -236,6 +483,9 @@ func (g *Generator) GenerateTableSQL(table *state.Table) string {
 		if fk.OnUpdate != "" {
 			sql.WriteString(fmt.Sprintf(" ON UPDATE %s", fk.OnUpdate))
 		}
+		if fk.Deferrable != "" {
+			sql.WriteString(" " + fk.Deferrable)
+		}
 
 		if i < len(table.ForeignKeys)-1 {
 			sql.WriteString(",")
@@ -243,24 +493,80 @@ func (g *Generator) GenerateTableSQL(table *state.Table) string {
 		sql.WriteString("\n")
 	}
 
-	sql.WriteString(");\n")
+	if hasDefinition {
+		sql.WriteString(")")
+	}
+	if len(table.Inherits) > 0 {
+		parents := make([]string, len(table.Inherits))
+		for i, parent := range table.Inherits {
+			parents[i] = qualifiedReference(parent)
+		}
+		sql.WriteString(fmt.Sprintf(" INHERITS (%s)", strings.Join(parents, ", ")))
+	}
+	if partitionOf {
+		sql.WriteString(" " + table.PartitionBound)
+	}
+	if table.IsPartitioned() {
+		sql.WriteString(fmt.Sprintf(" PARTITION BY %s (%s)", table.PartitionStrategy, table.PartitionKey))
+	}
+	if table.AccessMethod != "" {
+		sql.WriteString(" USING " + state.QuoteIdentifier(table.AccessMethod))
+	}
+	if len(table.StorageParams) > 0 {
+		sql.WriteString(fmt.Sprintf(" WITH (%s)", strings.Join(table.StorageParams, ", ")))
+	}
+	if table.Tablespace != "" {
+		sql.WriteString(" TABLESPACE " + state.QuoteIdentifier(table.Tablespace))
+	}
+	sql.WriteString(";\n")
+
+	// Add column settings that have no CREATE TABLE syntax
+	sql.WriteString(g.GenerateColumnSettingsSQL(table))
+
+	// A table created on its own and attached later is attached once it has its columns
+	if table.IsPartition() && !partitionOf {
+		sql.WriteString(fmt.Sprintf("\nALTER TABLE %s ATTACH PARTITION %s %s;\n",
+			qualifiedReference(table.PartitionOf), tableName, table.PartitionBound))
+	}
 
 	// Add indexes
 	for _, idx := range table.Indexes {
 		sql.WriteString("\n")
-		sql.WriteString(g.GenerateIndexSQL(idx, table.Name))
+		sql.WriteString(g.GenerateIndexSQL(idx, table))
+	}
+
+	// Add the replica identity, which may use one of the indexes
+	switch table.ReplicaIdentity {
+	case "":
+	case "INDEX":
+		sql.WriteString(fmt.Sprintf("\nALTER TABLE %s REPLICA IDENTITY USING INDEX %s;\n",
+			tableName, state.QuoteIdentifier(table.ReplicaIndex)))
+	default:
+		sql.WriteString(fmt.Sprintf("\nALTER TABLE %s REPLICA IDENTITY %s;\n", tableName, table.ReplicaIdentity))
 	}
 
+	// Add triggers
+	for _, trigger := range table.Triggers {
+		sql.WriteString("\n")
+		sql.WriteString(g.GenerateTriggerSQL(trigger, table))
+	}
+
+	// Add row-level security and policies
+	sql.WriteString(g.GenerateRowSecuritySQL(table))
+
+	// Add owner and privileges
+	sql.WriteString(g.GeneratePrivilegesSQL("TABLE", tableName, table.Privileges))
+
 	// Add table comment
 	if table.TableComment != "" {
 		sql.WriteString(fmt.Sprintf("\nCOMMENT ON TABLE %s IS '%s';\n",
-			table.Name, escapeComment(table.TableComment)))
+			tableName, escapeComment(table.TableComment)))
 	}
 
 	// Add column comments
 	for colName, comment := range table.ColumnComments {
 		sql.WriteString(fmt.Sprintf("COMMENT ON COLUMN %s.%s IS '%s';\n",
-			table.Name, colName, escapeComment(comment)))
+			tableName, state.QuoteIdentifier(colName), escapeComment(comment)))
 	}
 
 	return sql.String()
@@ -270,11 +576,24 @@ func (g *Generator) GenerateTableSQL(table *state.Table) string {
 func (g *Generator) GenerateColumnDef(col *state.Column) string {
 	var def strings.Builder
 
-	def.WriteString(col.Name)
+	def.WriteString(state.QuoteIdentifier(col.Name))
 	def.WriteString(" ")
 	def.WriteString(col.Type)
 
-	if col.Default != "" {
+	if col.Collation != "" {
+		def.WriteString(" COLLATE ")
+		def.WriteString(col.Collation)
+	}
+
+	switch {
+	case col.Generated != "":
+		def.WriteString(fmt.Sprintf(" GENERATED ALWAYS AS (%s) STORED", col.Generated))
+	case col.Identity != "":
+		def.WriteString(fmt.Sprintf(" GENERATED %s AS IDENTITY", col.Identity))
+		if len(col.IdentityOptions) > 0 {
+			def.WriteString(fmt.Sprintf(" (%s)", strings.Join(col.IdentityOptions, " ")))
+		}
+	case col.Default != "":
 		def.WriteString(" DEFAULT ")
 		def.WriteString(col.Default)
 	}
@@ -286,8 +605,43 @@ func (g *Generator) GenerateColumnDef(col *state.Column) string {
 	return def.String()
 }
 
+// GenerateColumnSettingsSQL generates ALTER COLUMN statements for per-column
+// statistics, storage, compression and attribute options
+func (g *Generator) GenerateColumnSettingsSQL(table *state.Table) string {
+	var sql strings.Builder
+	tableName := qualifiedIdent(table.Schema, table.Name)
+
+	for _, colName := range table.ColumnOrder {
+		col := table.Columns[colName]
+		prefix := fmt.Sprintf("ALTER TABLE %s ALTER COLUMN %s", tableName, state.QuoteIdentifier(col.Name))
+
+		if col.Statistics != "" {
+			sql.WriteString(fmt.Sprintf("%s SET STATISTICS %s;\n", prefix, col.Statistics))
+		}
+		if col.Storage != "" {
+			sql.WriteString(fmt.Sprintf("%s SET STORAGE %s;\n", prefix, col.Storage))
+		}
+		if col.Compression != "" {
+			sql.WriteString(fmt.Sprintf("%s SET COMPRESSION %s;\n", prefix, state.QuoteIdentifier(col.Compression)))
+		}
+		if len(col.Options) > 0 {
+			sql.WriteString(fmt.Sprintf("%s SET (%s);\n", prefix, strings.Join(col.Options, ", ")))
+		}
+	}
+
+	if sql.Len() == 0 {
+		return ""
+	}
+	return "\n" + sql.String()
+}
+
 // GenerateIndexSQL generates CREATE INDEX SQL
-func (g *Generator) GenerateIndexSQL(idx *state.Index, tableName string) string {
+func (g *Generator) GenerateIndexSQL(idx *state.Index, table *state.Table) string {
+	return generateIndexSQL(idx, qualifiedIdent(table.Schema, table.Name))
+}
+
+// generateIndexSQL generates CREATE INDEX SQL on the given table or materialized view
+func generateIndexSQL(idx *state.Index, target string) string {
 	var sql strings.Builder
 
 	if idx.Unique {
@@ -296,9 +650,29 @@ func (g *Generator) GenerateIndexSQL(idx *state.Index, tableName string) string
 		sql.WriteString("CREATE INDEX ")
 	}
 
-	sql.WriteString(fmt.Sprintf("%s ON %s (%s)",
-		idx.Name, tableName, strings.Join(idx.Columns, ", ")))
+	sql.WriteString(fmt.Sprintf("%s ON %s", state.QuoteIdentifier(idx.Name), target))
+	if idx.Method != "" {
+		sql.WriteString(" USING " + state.QuoteIdentifier(idx.Method))
+	}
 
+	elements := make([]string, len(idx.Elements))
+	for i, element := range idx.Elements {
+		elements[i] = generateIndexElement(element)
+	}
+	sql.WriteString(fmt.Sprintf(" (%s)", strings.Join(elements, ", ")))
+
+	if len(idx.Include) > 0 {
+		sql.WriteString(fmt.Sprintf(" INCLUDE (%s)", strings.Join(state.QuoteIdentifiers(idx.Include), ", ")))
+	}
+	if idx.NullsNotDistinct {
+		sql.WriteString(" NULLS NOT DISTINCT")
+	}
+	if len(idx.Params) > 0 {
+		sql.WriteString(fmt.Sprintf(" WITH (%s)", strings.Join(idx.Params, ", ")))
+	}
+	if idx.Tablespace != "" {
+		sql.WriteString(" TABLESPACE " + state.QuoteIdentifier(idx.Tablespace))
+	}
 	if idx.Where != "" {
 		sql.WriteString(fmt.Sprintf(" WHERE %s", idx.Where))
 	}
@@ -308,46 +682,368 @@ func (g *Generator) GenerateIndexSQL(idx *state.Index, tableName string) string
 	return sql.String()
 }
 
-// GenerateTableDownSQL generates DROP TABLE SQL
+// generateIndexElement generates a key column or expression of an index with its options
+func generateIndexElement(element state.IndexElement) string {
+	parts := []string{element.Expression}
+	if element.Column != "" {
+		parts[0] = state.QuoteIdentifier(element.Column)
+	}
+	if element.Collation != "" {
+		parts = append(parts, "COLLATE", element.Collation)
+	}
+	if element.Opclass != "" {
+		parts = append(parts, element.Opclass)
+	}
+	if element.Order != "" {
+		parts = append(parts, element.Order)
+	}
+	if element.Nulls != "" {
+		parts = append(parts, "NULLS", element.Nulls)
+	}
+	return strings.Join(parts, " ")
+}
+
+// GenerateTriggerSQL generates CREATE TRIGGER SQL, followed by ALTER TABLE when the trigger is not enabled
+func (g *Generator) GenerateTriggerSQL(trigger *state.Trigger, table *state.Table) string {
+	var sql strings.Builder
+
+	tableName := qualifiedIdent(table.Schema, table.Name)
+
+	events := make([]string, len(trigger.Events))
+	for i, event := range trigger.Events {
+		if event == "UPDATE" && len(trigger.UpdateColumns) > 0 {
+			event += " OF " + strings.Join(state.QuoteIdentifiers(trigger.UpdateColumns), ", ")
+		}
+		events[i] = event
+	}
+
+	if trigger.Constraint {
+		sql.WriteString("CREATE CONSTRAINT TRIGGER ")
+	} else {
+		sql.WriteString("CREATE TRIGGER ")
+	}
+	sql.WriteString(fmt.Sprintf("%s\n    %s %s ON %s\n",
+		state.QuoteIdentifier(trigger.Name), trigger.Timing, strings.Join(events, " OR "), tableName))
+	if trigger.Options != "" {
+		sql.WriteString(fmt.Sprintf("    %s\n", trigger.Options))
+	}
+	sql.WriteString(fmt.Sprintf("    FOR EACH %s\n", trigger.ForEach))
+	if trigger.When != "" {
+		sql.WriteString(fmt.Sprintf("    WHEN (%s)\n", trigger.When))
+	}
+	sql.WriteString(fmt.Sprintf("    EXECUTE FUNCTION %s(%s);\n", qualifiedReference(trigger.Function), trigger.Arguments))
+
+	if trigger.Enabled != "" {
+		sql.WriteString(fmt.Sprintf("ALTER TABLE %s %s TRIGGER %s;\n",
+			tableName, trigger.Enabled, state.QuoteIdentifier(trigger.Name)))
+	}
+
+	return sql.String()
+}
+
+// GenerateRowSecuritySQL generates the row-level security settings and policies of a table
+func (g *Generator) GenerateRowSecuritySQL(table *state.Table) string {
+	var sql strings.Builder
+
+	tableName := qualifiedIdent(table.Schema, table.Name)
+
+	if table.RowSecurity || table.ForceSecurity {
+		sql.WriteString("\n")
+	}
+	if table.RowSecurity {
+		sql.WriteString(fmt.Sprintf("ALTER TABLE %s ENABLE ROW LEVEL SECURITY;\n", tableName))
+	}
+	if table.ForceSecurity {
+		sql.WriteString(fmt.Sprintf("ALTER TABLE %s FORCE ROW LEVEL SECURITY;\n", tableName))
+	}
+
+	// Clauses left at their defaults (permissive, for all commands, to PUBLIC) are omitted
+	for _, policy := range table.Policies {
+		clauses := []string{fmt.Sprintf("CREATE POLICY %s ON %s", state.QuoteIdentifier(policy.Name), tableName)}
+		if policy.Restrictive {
+			clauses = append(clauses, "AS RESTRICTIVE")
+		}
+		if policy.Command != "ALL" {
+			clauses = append(clauses, "FOR "+policy.Command)
+		}
+		if len(policy.Roles) > 0 {
+			roles := make([]string, len(policy.Roles))
+			for i, role := range policy.Roles {
+				roles[i] = state.QuoteRole(role)
+			}
+			clauses = append(clauses, "TO "+strings.Join(roles, ", "))
+		}
+		if policy.Using != "" {
+			clauses = append(clauses, fmt.Sprintf("USING (%s)", policy.Using))
+		}
+		if policy.WithCheck != "" {
+			clauses = append(clauses, fmt.Sprintf("WITH CHECK (%s)", policy.WithCheck))
+		}
+		sql.WriteString("\n" + strings.Join(clauses, "\n    ") + ";\n")
+	}
+
+	return sql.String()
+}
+
+// GenerateTableDownSQL generates DROP TABLE SQL, dropping the table's triggers first
 func (g *Generator) GenerateTableDownSQL(table *state.Table) string {
-	return fmt.Sprintf("DROP TABLE IF EXISTS %s CASCADE;\n", table.Name)
+	var sql strings.Builder
+
+	tableName := qualifiedIdent(table.Schema, table.Name)
+
+	for _, trigger := range table.Triggers {
+		sql.WriteString(fmt.Sprintf("DROP TRIGGER IF EXISTS %s ON %s;\n", state.QuoteIdentifier(trigger.Name), tableName))
+	}
+	for _, policy := range table.Policies {
+		sql.WriteString(fmt.Sprintf("DROP POLICY IF EXISTS %s ON %s;\n", state.QuoteIdentifier(policy.Name), tableName))
+	}
+	sql.WriteString(fmt.Sprintf("DROP TABLE IF EXISTS %s CASCADE;\n", tableName))
+
+	return sql.String()
 }
 
 // GenerateDomainSQL generates CREATE DOMAIN SQL
 func (g *Generator) GenerateDomainSQL(domain *state.Domain) string {
 	var sql strings.Builder
 
-	sql.WriteString(fmt.Sprintf("DROP DOMAIN IF EXISTS %s;\n", domain.Name))
+	domainName := qualifiedIdent(domain.Schema, domain.Name)
+
+	sql.WriteString(fmt.Sprintf("DROP DOMAIN IF EXISTS %s;\n", domainName))
 	sql.WriteString(fmt.Sprintf("CREATE DOMAIN %s AS %s",
-		domain.Name, domain.BaseType))
+		domainName, domain.BaseType))
+
+	if domain.Collation != "" {
+		sql.WriteString(fmt.Sprintf(" COLLATE %s", domain.Collation))
+	}
 
 	if domain.Default != "" {
 		sql.WriteString(fmt.Sprintf(" DEFAULT %s", domain.Default))
 	}
 
-	if domain.Constraint != "" {
-		sql.WriteString(fmt.Sprintf(" CHECK (%s)", domain.Constraint))
+	if domain.NotNull {
+		sql.WriteString(" NOT NULL")
+	}
+
+	for _, constraint := range domain.Constraints {
+		if !constraint.NotValid {
+			sql.WriteString(fmt.Sprintf(" %sCHECK (%s)",
+				constraintName(constraint.Name, domain.DefaultCheckName()), constraint.Expression))
+		}
 	}
 
 	sql.WriteString(";\n")
 
+	// NOT VALID constraints cannot be part of CREATE DOMAIN, they are added unchecked afterwards
+	for _, constraint := range domain.Constraints {
+		if constraint.NotValid {
+			sql.WriteString(fmt.Sprintf("ALTER DOMAIN %s ADD CONSTRAINT %s CHECK (%s) NOT VALID;\n",
+				domainName, state.QuoteIdentifier(constraint.Name), constraint.Expression))
+		}
+	}
+
 	if domain.Comment != "" {
 		sql.WriteString(fmt.Sprintf("\nCOMMENT ON DOMAIN %s IS '%s';\n",
-			domain.Name, escapeComment(domain.Comment)))
+			domainName, escapeComment(domain.Comment)))
 	}
 
+	sql.WriteString(g.GeneratePrivilegesSQL("DOMAIN", domainName, domain.Privileges))
+
 	return sql.String()
 }
 
 // GenerateDomainDownSQL generates DROP DOMAIN SQL
 func (g *Generator) GenerateDomainDownSQL(domain *state.Domain) string {
-	return fmt.Sprintf("DROP DOMAIN IF EXISTS %s;\n", domain.Name)
+	return fmt.Sprintf("DROP DOMAIN IF EXISTS %s;\n", qualifiedIdent(domain.Schema, domain.Name))
+}
+
+// GenerateSequenceSQL generates CREATE SEQUENCE SQL
+// Ownership is set by the owning table's migration, since the table is created later
+func (g *Generator) GenerateSequenceSQL(seq *state.Sequence) string {
+	var sql strings.Builder
+
+	seqName := qualifiedIdent(seq.Schema, seq.Name)
+
+	sql.WriteString(fmt.Sprintf("CREATE SEQUENCE %s", seqName))
+	for _, option := range seq.Options {
+		sql.WriteString("\n    ")
+		sql.WriteString(option)
+	}
+	sql.WriteString(";\n")
+
+	if seq.Comment != "" {
+		sql.WriteString(fmt.Sprintf("\nCOMMENT ON SEQUENCE %s IS '%s';\n",
+			seqName, escapeComment(seq.Comment)))
+	}
+
+	sql.WriteString(g.GeneratePrivilegesSQL("SEQUENCE", seqName, seq.Privileges))
+
+	return sql.String()
+}
+
+// GenerateSequenceDownSQL generates DROP SEQUENCE SQL
+func (g *Generator) GenerateSequenceDownSQL(seq *state.Sequence) string {
+	return fmt.Sprintf("DROP SEQUENCE IF EXISTS %s;\n", qualifiedIdent(seq.Schema, seq.Name))
+}
+
+// orderedSchemas returns the schemas in the order they were created
+func (g *Generator) orderedSchemas() []*state.Schema {
+	var schemas []*state.Schema
+	for _, schema := range g.state.Schemas {
+		schemas = append(schemas, schema)
+	}
+	sort.Slice(schemas, func(i, j int) bool {
+		if schemas[i].CreatedIn != schemas[j].CreatedIn {
+			return schemas[i].CreatedIn < schemas[j].CreatedIn
+		}
+		return schemas[i].Name < schemas[j].Name
+	})
+	return schemas
+}
+
+// GenerateSchemasSQL generates CREATE SCHEMA statements, in the given order
+func (g *Generator) GenerateSchemasSQL(schemas []*state.Schema) string {
+	var sql strings.Builder
+
+	for _, schema := range schemas {
+		sql.WriteString(fmt.Sprintf("CREATE SCHEMA IF NOT EXISTS %s", state.QuoteIdentifier(schema.Name)))
+		if schema.Authorization != "" {
+			sql.WriteString(fmt.Sprintf(" AUTHORIZATION %s", state.QuoteRole(schema.Authorization)))
+		}
+		sql.WriteString(";\n")
+	}
+
+	for _, schema := range schemas {
+		if schema.Comment != "" {
+			sql.WriteString(fmt.Sprintf("\nCOMMENT ON SCHEMA %s IS '%s';\n",
+				state.QuoteIdentifier(schema.Name), escapeComment(schema.Comment)))
+		}
+		sql.WriteString(g.GeneratePrivilegesSQL("SCHEMA", state.QuoteIdentifier(schema.Name), schema.Privileges))
+	}
+
+	return sql.String()
+}
+
+// GenerateSchemasDownSQL generates DROP SCHEMA statements, in reverse order
+// Every object in them has been dropped by the later migrations' down files, so no CASCADE is needed
+func (g *Generator) GenerateSchemasDownSQL(schemas []*state.Schema) string {
+	var sql strings.Builder
+	for i := len(schemas) - 1; i >= 0; i-- {
+		sql.WriteString(fmt.Sprintf("DROP SCHEMA IF EXISTS %s;\n", state.QuoteIdentifier(schemas[i].Name)))
+	}
+	return sql.String()
+}
+
+// GenerateExtensionsSQL generates CREATE EXTENSION statements, in the given order
+func (g *Generator) GenerateExtensionsSQL(extensions []*state.Extension) string {
+	var sql strings.Builder
+
+	for _, ext := range extensions {
+		sql.WriteString(fmt.Sprintf("CREATE EXTENSION IF NOT EXISTS %s", state.QuoteIdentifier(ext.Name)))
+		if ext.Schema != "" {
+			sql.WriteString(fmt.Sprintf(" SCHEMA %s", state.QuoteIdentifier(ext.Schema)))
+		}
+		if ext.Version != "" {
+			sql.WriteString(fmt.Sprintf(" VERSION '%s'", escapeComment(ext.Version)))
+		}
+		if ext.Cascade {
+			sql.WriteString(" CASCADE")
+		}
+		sql.WriteString(";\n")
+	}
+
+	for _, ext := range extensions {
+		if ext.Comment != "" {
+			sql.WriteString(fmt.Sprintf("\nCOMMENT ON EXTENSION %s IS '%s';\n",
+				state.QuoteIdentifier(ext.Name), escapeComment(ext.Comment)))
+		}
+	}
+
+	return sql.String()
+}
+
+// GenerateExtensionsDownSQL generates DROP EXTENSION statements, in reverse order
+func (g *Generator) GenerateExtensionsDownSQL(extensions []*state.Extension) string {
+	var sql strings.Builder
+	for i := len(extensions) - 1; i >= 0; i-- {
+		sql.WriteString(fmt.Sprintf("DROP EXTENSION IF EXISTS %s;\n", state.QuoteIdentifier(extensions[i].Name)))
+	}
+	return sql.String()
+}
+
+// GenerateSequenceOwnershipSQL generates ALTER SEQUENCE ... OWNED BY for the sequences a table owns
+func (g *Generator) GenerateSequenceOwnershipSQL(table *state.Table) string {
+	tableKey := table.QualifiedName()
+
+	var owned []*state.Sequence
+	for _, seq := range g.state.Sequences {
+		if seq.OwnedBy == tableKey {
+			owned = append(owned, seq)
+		}
+	}
+	sort.Slice(owned, func(i, j int) bool {
+		return owned[i].QualifiedName() < owned[j].QualifiedName()
+	})
+
+	var sql strings.Builder
+	for _, seq := range owned {
+		sql.WriteString(fmt.Sprintf("\nALTER SEQUENCE %s OWNED BY %s.%s;\n",
+			qualifiedIdent(seq.Schema, seq.Name), qualifiedIdent(table.Schema, table.Name), state.QuoteIdentifier(seq.OwnerCol)))
+	}
+
+	return sql.String()
+}
+
+// GenerateFunctionSQL generates CREATE OR REPLACE FUNCTION or PROCEDURE SQL
+// Enums in the signature that no earlier migration created are included first
+func (g *Generator) GenerateFunctionSQL(fn *state.Function) string {
+	var sql strings.Builder
+
+	sql.WriteString(g.generateTypeEnums(functionTypes(fn)))
+
+	fnName := qualifiedIdent(fn.Schema, fn.Name)
+
+	sql.WriteString(fmt.Sprintf("CREATE OR REPLACE %s %s(%s)\n", fn.Kind, fnName, strings.TrimSpace(fn.Arguments)))
+	if fn.Returns != "" {
+		sql.WriteString(fmt.Sprintf("    RETURNS %s\n", fn.Returns))
+	}
+	if fn.Language != "" {
+		sql.WriteString(fmt.Sprintf("    LANGUAGE %s\n", fn.Language))
+	}
+	if fn.Volatility != "" {
+		sql.WriteString(fmt.Sprintf("    %s\n", fn.Volatility))
+	}
+	if fn.Security != "" {
+		sql.WriteString(fmt.Sprintf("    SECURITY %s\n", fn.Security))
+	}
+	for _, attribute := range fn.Attributes {
+		sql.WriteString(fmt.Sprintf("    %s\n", attribute))
+	}
+	for _, option := range fn.SetOptions {
+		sql.WriteString(fmt.Sprintf("    %s\n", option))
+	}
+	sql.WriteString(fn.Body)
+	sql.WriteString(";\n")
+
+	if fn.Comment != "" {
+		sql.WriteString(fmt.Sprintf("\nCOMMENT ON %s %s(%s) IS '%s';\n",
+			fn.Kind, fnName, fn.Signature, escapeComment(fn.Comment)))
+	}
+
+	sql.WriteString(g.GeneratePrivilegesSQL(fn.Kind, fmt.Sprintf("%s(%s)", fnName, fn.Signature), fn.Privileges))
+
+	return sql.String()
+}
+
+// GenerateFunctionDownSQL generates DROP FUNCTION or PROCEDURE SQL
+func (g *Generator) GenerateFunctionDownSQL(fn *state.Function) string {
+	return fmt.Sprintf("DROP %s IF EXISTS %s(%s);\n", fn.Kind, qualifiedIdent(fn.Schema, fn.Name), fn.Signature)
 }
 
 // GenerateViewSQL generates CREATE VIEW SQL
 func (g *Generator) GenerateViewSQL(view *state.View) string {
 	var sql strings.Builder
 
+	sql.WriteString(setSearchPathSQL(view.SearchPath))
 	sql.WriteString(view.Definition)
 
 	// Add semicolon if not present (it's stripped during parsing)
@@ -355,22 +1051,284 @@ func (g *Generator) GenerateViewSQL(view *state.View) string {
 		sql.WriteString(";")
 	}
 	sql.WriteString("\n")
+	sql.WriteString(resetSearchPathSQL(view.SearchPath))
 
 	if view.Comment != "" {
 		// Extract view name from definition
 		sql.WriteString(fmt.Sprintf("\nCOMMENT ON VIEW %s IS '%s';\n",
-			view.Name, escapeComment(view.Comment)))
+			qualifiedIdent(view.Schema, view.Name), escapeComment(view.Comment)))
 	}
 
+	sql.WriteString(g.GeneratePrivilegesSQL("VIEW", qualifiedIdent(view.Schema, view.Name), view.Privileges))
+
 	return sql.String()
 }
 
 // GenerateViewDownSQL generates DROP VIEW SQL
 func (g *Generator) GenerateViewDownSQL(view *state.View) string {
-	return fmt.Sprintf("DROP VIEW IF EXISTS %s CASCADE;\n", view.Name)
+	return fmt.Sprintf("DROP VIEW IF EXISTS %s CASCADE;\n", qualifiedIdent(view.Schema, view.Name))
+}
+
+// GenerateMaterializedViewSQL generates CREATE MATERIALIZED VIEW SQL, followed by its indexes
+func (g *Generator) GenerateMaterializedViewSQL(mview *state.MaterializedView) string {
+	var sql strings.Builder
+
+	sql.WriteString(setSearchPathSQL(mview.SearchPath))
+	sql.WriteString(strings.TrimSuffix(strings.TrimSpace(mview.Definition), ";"))
+	if !mview.WithData {
+		sql.WriteString("\nWITH NO DATA")
+	}
+	sql.WriteString(";\n")
+	sql.WriteString(resetSearchPathSQL(mview.SearchPath))
+
+	mviewName := qualifiedIdent(mview.Schema, mview.Name)
+
+	if mview.Comment != "" {
+		sql.WriteString(fmt.Sprintf("\nCOMMENT ON MATERIALIZED VIEW %s IS '%s';\n",
+			mviewName, escapeComment(mview.Comment)))
+	}
+
+	sql.WriteString(g.GeneratePrivilegesSQL("MATERIALIZED VIEW", mviewName, mview.Privileges))
+
+	for _, idx := range mview.Indexes {
+		sql.WriteString("\n")
+		sql.WriteString(generateIndexSQL(idx, mviewName))
+	}
+
+	return sql.String()
+}
+
+// GenerateMaterializedViewDownSQL generates DROP MATERIALIZED VIEW SQL
+func (g *Generator) GenerateMaterializedViewDownSQL(mview *state.MaterializedView) string {
+	return fmt.Sprintf("DROP MATERIALIZED VIEW IF EXISTS %s CASCADE;\n", qualifiedIdent(mview.Schema, mview.Name))
+}
+
+// GeneratePrivilegesSQL generates the owner and grants of an object
+// kind is the object's kind as written in ALTER ... OWNER TO, and name its output name, with the arguments of a function
+func (g *Generator) GeneratePrivilegesSQL(kind, name string, privileges state.Privileges) string {
+	var statements []string
+	if privileges.Owner != "" {
+		statements = append(statements, fmt.Sprintf("ALTER %s %s OWNER TO %s;", kind, name, state.QuoteRole(privileges.Owner)))
+	}
+
+	// Views and materialized views take their privileges as tables
+	on := kind
+	if state.PrivilegeClass(kind) == "TABLE" {
+		on = "TABLE"
+	}
+	grants, _ := grantStatements(kind, on+" "+name, privileges.Grants, state.PublicPrivileges(kind))
+	statements = append(statements, grants...)
+
+	if len(statements) == 0 {
+		return ""
+	}
+	return "\n" + strings.Join(statements, "\n") + "\n"
+}
+
+// GenerateDefaultPrivilegesSQL generates ALTER DEFAULT PRIVILEGES statements and the statements undoing them
+func (g *Generator) GenerateDefaultPrivilegesSQL(defaults []*state.DefaultPrivileges) (string, string) {
+	var up, down []string
+	for _, dp := range defaults {
+		prefix := "ALTER DEFAULT PRIVILEGES "
+		if dp.Role != "" {
+			prefix += "FOR ROLE " + state.QuoteRole(dp.Role) + " "
+		}
+
+		// Entries for a schema only add to the defaults, PUBLIC keeps what it is granted anyway
+		public := state.PublicPrivileges(state.DefaultPrivilegesKind(dp.ObjectType))
+		if dp.Schema != "" {
+			prefix += "IN SCHEMA " + state.QuoteIdentifier(dp.Schema) + " "
+			public = nil
+		}
+
+		grants, revokes := grantStatements(state.DefaultPrivilegesKind(dp.ObjectType), dp.ObjectType, dp.Grants, public)
+		for _, statement := range grants {
+			up = append(up, prefix+statement)
+		}
+		for _, statement := range revokes {
+			down = append(down, prefix+statement)
+		}
+	}
+
+	// Undo in reverse order
+	for i, j := 0, len(down)-1; i < j; i, j = i+1, j-1 {
+		down[i], down[j] = down[j], down[i]
+	}
+
+	if len(up) == 0 {
+		return "", ""
+	}
+	return strings.Join(up, "\n") + "\n", strings.Join(down, "\n") + "\n"
+}
+
+// grantStatements returns the REVOKE and GRANT statements that take an object of the given kind from what
+// PostgreSQL grants PUBLIC on creation to the given grants, and the statements that take it back again
+// on is what follows ON, e.g. TABLE orders; privileges are grouped per role, in the order roles were first granted
+func grantStatements(kind, on string, grants state.Grants, public []string) ([]string, []string) {
+	var up, down []string
+	for _, privilege := range public {
+		if grants.Find("PUBLIC", privilege, "") == nil {
+			up = append(up, fmt.Sprintf("REVOKE %s ON %s FROM PUBLIC;", privilege, on))
+			down = append(down, fmt.Sprintf("GRANT %s ON %s TO PUBLIC;", privilege, on))
+		}
+	}
+
+	type roleGrants struct {
+		grantee     string
+		grantOption bool
+		privileges  []string            // In the order they were granted
+		whole       map[string]bool     // Privileges on the whole object
+		columns     map[string][]string // Columns per privilege limited to some columns
+	}
+	var groups []*roleGrants
+	for _, grant := range grants {
+		if grant.Grantee == "PUBLIC" && grant.Column == "" && !grant.GrantOption && contains(public, grant.Privilege) {
+			continue
+		}
+
+		var group *roleGrants
+		for _, existing := range groups {
+			if existing.grantee == grant.Grantee && existing.grantOption == grant.GrantOption {
+				group = existing
+			}
+		}
+		if group == nil {
+			group = &roleGrants{grantee: grant.Grantee, grantOption: grant.GrantOption,
+				whole: make(map[string]bool), columns: make(map[string][]string)}
+			groups = append(groups, group)
+		}
+		if !contains(group.privileges, grant.Privilege) {
+			group.privileges = append(group.privileges, grant.Privilege)
+		}
+		if grant.Column == "" {
+			group.whole[grant.Privilege] = true
+		} else {
+			group.columns[grant.Privilege] = append(group.columns[grant.Privilege], grant.Column)
+		}
+	}
+
+	for _, group := range groups {
+		// Known privileges are written in their usual order, ALL when every one of several is granted
+		ordered := []string{}
+		all := len(state.PrivilegeNames(kind)) > 1
+		for _, privilege := range state.PrivilegeNames(kind) {
+			all = all && group.whole[privilege]
+			if contains(group.privileges, privilege) {
+				ordered = append(ordered, privilege)
+			}
+		}
+		for _, privilege := range group.privileges {
+			if !contains(ordered, privilege) {
+				ordered = append(ordered, privilege)
+			}
+		}
+
+		var items []string
+		if all {
+			items = append(items, "ALL")
+		}
+		for _, privilege := range ordered {
+			switch {
+			case group.whole[privilege]:
+				if !all || !contains(state.PrivilegeNames(kind), privilege) {
+					items = append(items, privilege)
+				}
+			case len(group.columns[privilege]) > 0:
+				items = append(items, fmt.Sprintf("%s (%s)", privilege,
+					strings.Join(state.QuoteIdentifiers(group.columns[privilege]), ", ")))
+			}
+		}
+
+		role := state.QuoteRole(group.grantee)
+		statement := fmt.Sprintf("GRANT %s ON %s TO %s", strings.Join(items, ", "), on, role)
+		if group.grantOption {
+			statement += " WITH GRANT OPTION"
+		}
+		up = append(up, statement+";")
+		down = append(down, fmt.Sprintf("REVOKE %s ON %s FROM %s;", strings.Join(items, ", "), on, role))
+	}
+
+	return up, down
+}
+
+// GenerateRolesSQL generates CREATE ROLE statements for roles that do not exist yet
+// Roles are shared by every database of the cluster, so existing ones are left as they are
+func (g *Generator) GenerateRolesSQL(roles []string) string {
+	var sql strings.Builder
+
+	sql.WriteString("DO $$\nBEGIN\n")
+	for _, role := range roles {
+		sql.WriteString(fmt.Sprintf("    IF NOT EXISTS (SELECT FROM pg_roles WHERE rolname = %s) THEN\n", quoteLiteral(role)))
+		sql.WriteString(fmt.Sprintf("        CREATE ROLE %s;\n", state.QuoteIdentifier(role)))
+		sql.WriteString("    END IF;\n")
+	}
+	sql.WriteString("END\n$$;\n")
+
+	return sql.String()
+}
+
+// GenerateRolesDownSQL generates the down migration of GenerateRolesSQL
+// Roles may have existed before and may own objects in other databases, so they are not dropped
+func (g *Generator) GenerateRolesDownSQL(roles []string) string {
+	return fmt.Sprintf("-- Roles are shared by every database of the cluster and are left in place: %s\n",
+		strings.Join(state.QuoteIdentifiers(roles), ", "))
+}
+
+// setSearchPathSQL restores the search path a view definition was written against, so its
+// unqualified names resolve as they did; the default path needs nothing
+func setSearchPathSQL(path []string) string {
+	if path == nil {
+		return ""
+	}
+	return fmt.Sprintf("SET search_path TO %s;\n", strings.Join(state.QuoteIdentifiers(path), ", "))
+}
+
+// resetSearchPathSQL undoes setSearchPathSQL
+func resetSearchPathSQL(path []string) string {
+	if path == nil {
+		return ""
+	}
+	return "RESET search_path;\n"
+}
+
+// qualifiedIdent returns an object name for output, qualified with its schema
+// Objects in the default schema are emitted unqualified, and names are quoted when needed
+func qualifiedIdent(schema, name string) string {
+	if schema == "" || schema == state.DefaultSchema {
+		return state.QuoteIdentifier(name)
+	}
+	return state.QuoteIdentifier(schema) + "." + state.QuoteIdentifier(name)
+}
+
+// qualifiedReference returns the output name for a schema-qualified state key
+func qualifiedReference(key string) string {
+	return qualifiedIdent(state.SplitQualifiedName(key))
+}
+
+// migrationObjectName returns the object name used in migration file names
+// Dots are not allowed in migration names, so non-default schemas are joined with a dash
+func migrationObjectName(schema, name string) string {
+	if schema == "" || schema == state.DefaultSchema {
+		return name
+	}
+	return schema + "-" + name
 }
 
 // escapeComment escapes single quotes in comments
 func escapeComment(comment string) string {
 	return strings.ReplaceAll(comment, "'", "''")
 }
+
+// quoteLiteral returns value as a SQL string constant
+func quoteLiteral(value string) string {
+	return "'" + strings.ReplaceAll(value, "'", "''") + "'"
+}
+
+// constraintName returns the CONSTRAINT clause for a constraint name
+// Names matching what PostgreSQL would assign anyway are omitted
+func constraintName(name, defaultName string) string {
+	if name == "" || name == defaultName {
+		return ""
+	}
+	return "CONSTRAINT " + state.QuoteIdentifier(name) + " "
+}
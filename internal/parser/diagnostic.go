@@ -0,0 +1,34 @@
+package parser
+
+import (
+	"fmt"
+)
+
+// maxDiagnosticStatement is how much of the statement text a diagnostic shows
+const maxDiagnosticStatement = 120
+
+// Diagnostic describes a statement that was skipped or only partly understood
+type Diagnostic struct {
+	Pos       Position
+	Message   string
+	Statement string
+}
+
+// NewDiagnostic creates a diagnostic located at the given statement
+func NewDiagnostic(stmt *Statement, format string, args ...interface{}) Diagnostic {
+	return Diagnostic{
+		Pos:       stmt.Pos,
+		Message:   fmt.Sprintf(format, args...),
+		Statement: stmt.Original,
+	}
+}
+
+// String formats the diagnostic as file:line:col: message, followed by the statement text
+func (d Diagnostic) String() string {
+	text := []rune(NormalizeWhitespace(d.Statement))
+	if len(text) > maxDiagnosticStatement {
+		text = append(text[:maxDiagnosticStatement], []rune("...")...)
+	}
+
+	return fmt.Sprintf("%s: %s\n    %s", d.Pos, d.Message, string(text))
+}
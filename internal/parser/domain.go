@@ -0,0 +1,123 @@
+package parser
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// domainClauseKeywords are the keywords that start a clause after the base type of CREATE DOMAIN
+var domainClauseKeywords = map[string]bool{
+	"COLLATE":    true,
+	"DEFAULT":    true,
+	"CONSTRAINT": true,
+	"NOT":        true,
+	"NULL":       true,
+	"CHECK":      true,
+}
+
+// splitDomainClauses splits the text after a domain's base type into its clauses
+// A CONSTRAINT name stays with the NOT NULL, NULL or CHECK it names, and DEFAULT NULL stays one clause
+func splitDomainClauses(text string) []string {
+	var clauses []string
+	depth := 0
+	start := -1
+	current := ""  // Keyword the current clause continues from
+	empty := false // The current DEFAULT or CONSTRAINT has nothing after its keyword yet
+
+	for _, tok := range Tokenize(text) {
+		keyword := strings.ToUpper(tok.Text)
+		isKeyword := tok.Type == TokenIdentifier && depth == 0 && domainClauseKeywords[keyword]
+
+		switch {
+		case tok.IsOperator("("):
+			depth++
+		case tok.IsOperator(")"):
+			depth--
+		}
+
+		switch {
+		case !isKeyword || empty:
+			// Content of the current clause; the first word after CONSTRAINT is its name
+			empty = false
+		case current == "CONSTRAINT" || (current == "NOT" && keyword == "NULL"):
+			// The body of a named constraint, or the NULL of NOT NULL
+			current = keyword
+		default:
+			if start != -1 {
+				clauses = append(clauses, strings.TrimSpace(text[start:tok.Offset]))
+			}
+			start = tok.Offset
+			current = keyword
+			empty = keyword == "DEFAULT" || keyword == "CONSTRAINT"
+		}
+	}
+	if start != -1 {
+		clauses = append(clauses, strings.TrimSpace(text[start:]))
+	}
+
+	return clauses
+}
+
+// constraintNameRe matches a CONSTRAINT name prefix
+var constraintNameRe = regexp.MustCompile(`(?i)^CONSTRAINT\s+(` + IdentPattern + `)\s+`)
+
+// notValidRe matches a trailing NOT VALID
+var notValidRe = regexp.MustCompile(`(?i)\s+NOT\s+VALID\s*$`)
+
+// parseDomainConstraint parses a domain constraint, e.g. "CONSTRAINT positive CHECK (VALUE > 0) NOT VALID"
+// Returns the constraint and the kind of constraint, CHECK, NOT NULL or NULL
+func parseDomainConstraint(clause string) (DomainConstraint, string, error) {
+	var constraint DomainConstraint
+	clause = strings.TrimSpace(clause)
+
+	if matches := constraintNameRe.FindStringSubmatch(clause); matches != nil {
+		constraint.Name = NormalizeIdentifier(matches[1])
+		clause = clause[len(matches[0]):]
+	}
+	if loc := notValidRe.FindStringIndex(MaskLiterals(clause)); loc != nil {
+		constraint.NotValid = true
+		clause = clause[:loc[0]]
+	}
+
+	upper := strings.ToUpper(NormalizeWhitespace(clause))
+	switch {
+	case upper == "NOT NULL":
+		return constraint, "NOT NULL", nil
+	case upper == "NULL":
+		return constraint, "NULL", nil
+	case strings.HasPrefix(upper, "CHECK"):
+		constraint.Expression = strings.TrimSpace(ExtractParenthesesContent(clause))
+		if constraint.Expression != "" {
+			return constraint, "CHECK", nil
+		}
+	}
+	return constraint, "", fmt.Errorf("unsupported domain constraint: %s", clause)
+}
+
+// parseDomainClauses reads the clauses of CREATE DOMAIN after the base type into the details
+func parseDomainClauses(text string, details *CreateDomainDetails) error {
+	for _, clause := range splitDomainClauses(text) {
+		keyword, rest := ReadIdentifier(clause)
+		switch strings.ToUpper(keyword) {
+		case "COLLATE":
+			details.Collation = strings.TrimSpace(rest)
+		case "DEFAULT":
+			details.Default = strings.TrimSpace(rest)
+		default:
+			constraint, kind, err := parseDomainConstraint(clause)
+			if err != nil {
+				return err
+			}
+			switch kind {
+			case "NOT NULL":
+				details.NotNull = true
+			case "NULL":
+				details.NotNull = false
+			default:
+				details.Constraints = append(details.Constraints, constraint)
+			}
+		}
+	}
+	return nil
+}
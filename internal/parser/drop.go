@@ -0,0 +1,74 @@
+package parser
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+var (
+	// Options of a DROP statement, matched against the text before the first name
+	dropIfExistsRe     = regexp.MustCompile(`(?i)\bIF\s+EXISTS\s*$`)
+	dropConcurrentlyRe = regexp.MustCompile(`(?i)\bCONCURRENTLY\b`)
+
+	// dropBehaviorRe matches the CASCADE or RESTRICT after the names
+	dropBehaviorRe = regexp.MustCompile(`(?i)\s+(?:CASCADE|RESTRICT)\s*$`)
+	dropCascadeRe  = regexp.MustCompile(`(?i)\bCASCADE\s*$`)
+)
+
+// isCascade reports whether a DROP statement or action ends in CASCADE
+func isCascade(sql string) bool {
+	return dropCascadeRe.MatchString(MaskLiterals(sql))
+}
+
+// parseDrop parses a DROP statement that names one or more objects
+// DROP kind [CONCURRENTLY] [IF EXISTS] name [, ...] [CASCADE | RESTRICT]
+// The pattern captures the first name, so everything before it holds the options
+func (p *Parser) parseDrop(sql string, stmtType StatementType, pattern string) (*Statement, error) {
+	loc := p.patterns[pattern].FindStringSubmatchIndex(sql)
+	if loc == nil {
+		return nil, fmt.Errorf("invalid %s: %s", stmtType, sql)
+	}
+
+	details := parseDropOptions(sql, loc[2], false)
+	if len(details.Objects) == 0 {
+		return nil, fmt.Errorf("invalid %s: %s", stmtType, sql)
+	}
+
+	return &Statement{
+		Type:       stmtType,
+		Original:   sql,
+		Schema:     details.Objects[0].Schema,
+		ObjectName: details.Objects[0].Name,
+		Details:    details,
+	}, nil
+}
+
+// parseDropOptions parses the options and the names of a DROP statement whose first name starts at start
+// With signatures set the names are routines, each with an optional argument list
+func parseDropOptions(sql string, start int, signatures bool) *DropDetails {
+	details := &DropDetails{
+		IfExists:     dropIfExistsRe.MatchString(sql[:start]),
+		Concurrently: dropConcurrentlyRe.MatchString(sql[:start]),
+		Cascade:      isCascade(sql),
+	}
+
+	names := sql[start:]
+	if loc := dropBehaviorRe.FindStringIndex(MaskLiterals(names)); loc != nil {
+		names = names[:loc[0]]
+	}
+
+	for _, part := range SplitTopLevel(names, ",") {
+		var object DropObject
+		var rest string
+		object.Schema, object.Name, rest = ReadQualifiedName(part)
+		if signatures && strings.HasPrefix(strings.TrimSpace(rest), "(") {
+			object.HasSignature = true
+			object.Signature = RoutineSignature(ExtractParenthesesContent(rest))
+		}
+		if object.Name != "" {
+			details.Objects = append(details.Objects, object)
+		}
+	}
+	return details
+}
@@ -0,0 +1,20 @@
+package parser
+
+import (
+	"fmt"
+)
+
+// ParseError reports a statement that could not be parsed
+type ParseError struct {
+	Pos       Position
+	Statement string
+	Err       error
+}
+
+func (e *ParseError) Error() string {
+	return fmt.Sprintf("%s: %v", e.Pos, e.Err)
+}
+
+func (e *ParseError) Unwrap() error {
+	return e.Err
+}
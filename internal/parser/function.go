@@ -0,0 +1,166 @@
+package parser
+
+import (
+	"strings"
+)
+
+// routineClauseKeywords are the keywords that start a clause after the argument list of CREATE FUNCTION
+var routineClauseKeywords = map[string]bool{
+	"RETURNS":   true,
+	"LANGUAGE":  true,
+	"TRANSFORM": true,
+	"WINDOW":    true,
+	"IMMUTABLE": true,
+	"STABLE":    true,
+	"VOLATILE":  true,
+	"NOT":       true,
+	"LEAKPROOF": true,
+	"CALLED":    true,
+	"STRICT":    true,
+	"EXTERNAL":  true,
+	"SECURITY":  true,
+	"PARALLEL":  true,
+	"COST":      true,
+	"ROWS":      true,
+	"SUPPORT":   true,
+	"SET":       true,
+	"AS":        true,
+	"BEGIN":     true,
+}
+
+// typeAliases maps alternative type spellings to the name PostgreSQL uses in signatures
+var typeAliases = map[string]string{
+	"int":         "integer",
+	"int4":        "integer",
+	"int2":        "smallint",
+	"int8":        "bigint",
+	"float4":      "real",
+	"float8":      "double precision",
+	"float":       "double precision",
+	"bool":        "boolean",
+	"varchar":     "character varying",
+	"char":        "character",
+	"bpchar":      "character",
+	"decimal":     "numeric",
+	"timestamptz": "timestamp with time zone",
+	"timestamp":   "timestamp without time zone",
+	"timetz":      "time with time zone",
+	"time":        "time without time zone",
+	"varbit":      "bit varying",
+}
+
+// splitRoutineClauses splits the text after a routine's argument list into its clauses
+// Each clause starts at a top-level keyword; keywords inside parentheses and literals do not split
+func splitRoutineClauses(text string) []string {
+	var clauses []string
+	depth := 0
+	start := -1
+	inBody := false
+
+	for _, tok := range Tokenize(text) {
+		switch {
+		case tok.IsOperator("("):
+			depth++
+		case tok.IsOperator(")"):
+			depth--
+		case tok.Type == TokenIdentifier && depth == 0 && !inBody && routineClauseKeywords[strings.ToUpper(tok.Text)]:
+			if start != -1 {
+				clauses = append(clauses, strings.TrimSpace(text[start:tok.Offset]))
+			}
+			start = tok.Offset
+			// A BEGIN ATOMIC body runs to the end of the statement
+			inBody = tok.IsKeyword("BEGIN")
+		}
+	}
+	if start != -1 {
+		clauses = append(clauses, strings.TrimSpace(text[start:]))
+	}
+
+	return clauses
+}
+
+// RoutineSignature returns the argument types that identify a function among its overloads
+// Argument names, defaults, type modifiers and OUT arguments are left out, as PostgreSQL does
+func RoutineSignature(arguments string) string {
+	var types []string
+	for _, arg := range SplitTopLevel(arguments, ",") {
+		mode, _, argType := splitRoutineArgument(arg)
+		if argType == "" || mode == "OUT" {
+			continue
+		}
+		types = append(types, CanonicalTypeName(argType))
+	}
+	return strings.Join(types, ", ")
+}
+
+// RoutineArgumentTypes returns the type of every argument as written, OUT arguments included
+// Also reads the column list of RETURNS TABLE (...), which has the same form
+func RoutineArgumentTypes(arguments string) []string {
+	var types []string
+	for _, arg := range SplitTopLevel(arguments, ",") {
+		if _, _, argType := splitRoutineArgument(arg); argType != "" {
+			types = append(types, argType)
+		}
+	}
+	return types
+}
+
+// splitRoutineArgument splits an argument declaration into its mode, name and type
+// e.g. "INOUT total numeric(10,2) DEFAULT 0" becomes INOUT, total, numeric(10,2)
+func splitRoutineArgument(arg string) (mode, name, argType string) {
+	arg = strings.TrimSpace(arg)
+
+	// Drop the default value
+	masked := MaskLiterals(arg)
+	for _, marker := range []string{" DEFAULT ", "="} {
+		if i := strings.Index(strings.ToUpper(masked), marker); i != -1 {
+			arg, masked = strings.TrimSpace(arg[:i]), masked[:i]
+		}
+	}
+
+	if word, rest := ReadIdentifier(arg); word != "" && !strings.HasPrefix(arg, `"`) {
+		switch strings.ToUpper(word) {
+		case "IN", "OUT", "INOUT", "VARIADIC":
+			mode, arg = strings.ToUpper(word), strings.TrimSpace(rest)
+		}
+	}
+
+	// A lone type has nothing after it, otherwise the first word is the argument name
+	if dataType, rest := ReadDataType(arg); dataType != "" && strings.TrimSpace(rest) == "" {
+		return mode, "", dataType
+	}
+	name, rest := ReadIdentifier(arg)
+	return mode, name, strings.TrimSpace(rest)
+}
+
+// CanonicalTypeName normalizes a type for comparing signatures
+// Aliases are resolved to PostgreSQL's names and type modifiers are dropped
+func CanonicalTypeName(dataType string) string {
+	array := ""
+	if i := strings.Index(dataType, "["); i != -1 {
+		dataType, array = dataType[:i], "[]"
+	}
+
+	// Type modifiers such as varchar(255) do not take part in signatures
+	if i := strings.Index(dataType, "("); i != -1 {
+		end := strings.Index(dataType, ")")
+		if end > i {
+			dataType = dataType[:i] + dataType[end+1:]
+		}
+	}
+
+	schema, name, rest := ReadQualifiedName(dataType)
+	if name == "" {
+		return strings.ToLower(NormalizeWhitespace(dataType)) + array
+	}
+	if rest = strings.TrimSpace(rest); rest != "" {
+		name = strings.ToLower(name + " " + NormalizeWhitespace(rest))
+	}
+	if alias, ok := typeAliases[name]; ok && schema == "" {
+		name = alias
+	}
+	if schema != "" {
+		name = schema + "." + name
+	}
+	return name + array
+}
@@ -0,0 +1,182 @@
+package parser
+
+import (
+	"strings"
+)
+
+// IdentPattern matches a single SQL identifier, either quoted or unquoted
+const IdentPattern = `(?:"(?:[^"]|"")+"|[A-Za-z_][\w$]*)`
+
+// NamePattern matches an optionally schema-qualified object name
+const NamePattern = IdentPattern + `(?:\s*\.\s*` + IdentPattern + `)?`
+
+// NormalizeIdentifier folds an identifier the way PostgreSQL does
+// Quoted identifiers keep their exact spelling, unquoted ones are folded to lower case
+func NormalizeIdentifier(ident string) string {
+	ident = strings.TrimSpace(ident)
+	if len(ident) >= 2 && strings.HasPrefix(ident, `"`) && strings.HasSuffix(ident, `"`) {
+		return strings.ReplaceAll(ident[1:len(ident)-1], `""`, `"`)
+	}
+	return strings.ToLower(ident)
+}
+
+// SplitNameParts splits a dotted name into its normalized parts
+// Dots inside quoted identifiers are not treated as separators
+func SplitNameParts(name string) []string {
+	var parts []string
+	var current strings.Builder
+	inQuote := false
+
+	for _, ch := range name {
+		if ch == '"' {
+			inQuote = !inQuote
+		}
+		if ch == '.' && !inQuote {
+			parts = append(parts, NormalizeIdentifier(current.String()))
+			current.Reset()
+			continue
+		}
+		current.WriteRune(ch)
+	}
+	parts = append(parts, NormalizeIdentifier(current.String()))
+
+	return parts
+}
+
+// SplitQualifiedName splits a schema-qualified name into its normalized schema and object parts
+// The schema is empty when the name is unqualified
+func SplitQualifiedName(name string) (string, string) {
+	parts := SplitNameParts(name)
+	if len(parts) == 1 {
+		return "", parts[0]
+	}
+	return parts[len(parts)-2], parts[len(parts)-1]
+}
+
+// ReadIdentifier reads the identifier at the start of s
+// Returns the normalized identifier and the remaining text
+func ReadIdentifier(s string) (string, string) {
+	s = strings.TrimLeft(s, " \t\r\n")
+	if s == "" {
+		return "", ""
+	}
+
+	if s[0] == '"' {
+		for i := 1; i < len(s); i++ {
+			if s[i] != '"' {
+				continue
+			}
+			// Doubled quotes are an escaped quote inside the identifier
+			if i+1 < len(s) && s[i+1] == '"' {
+				i++
+				continue
+			}
+			return NormalizeIdentifier(s[:i+1]), s[i+1:]
+		}
+		return NormalizeIdentifier(s), ""
+	}
+
+	// Unquoted identifiers cannot start with a digit
+	if s[0] >= '0' && s[0] <= '9' {
+		return "", s
+	}
+
+	end := 0
+	for end < len(s) && isIdentChar(s[end]) {
+		end++
+	}
+	return NormalizeIdentifier(s[:end]), s[end:]
+}
+
+// ReadQualifiedName reads an optionally schema-qualified name at the start of s
+// Returns the normalized schema (empty when unqualified), name and the remaining text
+func ReadQualifiedName(s string) (string, string, string) {
+	first, rest := ReadIdentifier(s)
+	trimmed := strings.TrimLeft(rest, " \t\r\n")
+	if first == "" || !strings.HasPrefix(trimmed, ".") {
+		return "", first, rest
+	}
+
+	second, rest := ReadIdentifier(trimmed[1:])
+	return first, second, rest
+}
+
+// SplitIdentifierList splits a comma-separated identifier list into normalized names
+func SplitIdentifierList(list string) []string {
+	var names []string
+	for _, part := range splitOutsideQuotes(list, ',') {
+		part = strings.TrimSpace(part)
+		if part != "" {
+			names = append(names, NormalizeIdentifier(part))
+		}
+	}
+	return names
+}
+
+// roleKeywords are the role specifications that name no role of their own
+var roleKeywords = map[string]bool{
+	"PUBLIC":       true,
+	"CURRENT_USER": true,
+	"CURRENT_ROLE": true,
+	"SESSION_USER": true,
+}
+
+// NormalizeRole normalizes a role specification
+// PUBLIC, CURRENT_USER, CURRENT_ROLE and SESSION_USER are kept as upper-case keywords, anything else is a role name
+func NormalizeRole(role string) string {
+	role = strings.TrimSpace(role)
+	if upper := strings.ToUpper(role); roleKeywords[upper] {
+		return upper
+	}
+	return NormalizeIdentifier(role)
+}
+
+// SplitRoleList splits a comma-separated list of role specifications into normalized roles
+func SplitRoleList(list string) []string {
+	var roles []string
+	for _, part := range splitOutsideQuotes(list, ',') {
+		if role := NormalizeRole(part); role != "" {
+			roles = append(roles, role)
+		}
+	}
+	return roles
+}
+
+// IsIdentifier reports whether s consists of exactly one identifier
+func IsIdentifier(s string) bool {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return false
+	}
+	ident, rest := ReadIdentifier(s)
+	return ident != "" && strings.TrimSpace(rest) == ""
+}
+
+// splitOutsideQuotes splits s by delim, ignoring delimiters inside double quotes
+func splitOutsideQuotes(s string, delim rune) []string {
+	var parts []string
+	var current strings.Builder
+	inQuote := false
+
+	for _, ch := range s {
+		if ch == '"' {
+			inQuote = !inQuote
+		}
+		if ch == delim && !inQuote {
+			parts = append(parts, current.String())
+			current.Reset()
+			continue
+		}
+		current.WriteRune(ch)
+	}
+	parts = append(parts, current.String())
+
+	return parts
+}
+
+func isIdentChar(ch byte) bool {
+	return ch == '_' || ch == '$' ||
+		(ch >= 'a' && ch <= 'z') ||
+		(ch >= 'A' && ch <= 'Z') ||
+		(ch >= '0' && ch <= '9')
+}
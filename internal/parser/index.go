@@ -0,0 +1,104 @@
+package parser
+
+import (
+	"regexp"
+	"strings"
+)
+
+var (
+	// Index clauses are matched against text with literals masked and parenthesized lists blanked
+	indexUsingRe      = regexp.MustCompile(`(?i)^\s*USING\s+(` + IdentPattern + `)`)
+	indexIncludeRe    = regexp.MustCompile(`(?i)\bINCLUDE\s*\(`)
+	indexNullsRe      = regexp.MustCompile(`(?i)\bNULLS\s+NOT\s+DISTINCT\b`)
+	indexParamsRe     = regexp.MustCompile(`(?i)\bWITH\s*\(`)
+	indexTablespaceRe = regexp.MustCompile(`(?i)\bTABLESPACE\s+(` + IdentPattern + `)`)
+	indexWhereRe      = regexp.MustCompile(`(?i)\bWHERE\b`)
+
+	// Options of an index element, after its column or expression
+	elementNullsRe   = regexp.MustCompile(`(?i)(?:^|\s)NULLS\s+(FIRST|LAST)\s*$`)
+	elementOrderRe   = regexp.MustCompile(`(?i)(?:^|\s)(ASC|DESC)\s*$`)
+	elementCollateRe = regexp.MustCompile(`(?i)^\s*COLLATE\s+(` + NamePattern + `)`)
+)
+
+// parseIndexClauses parses what follows the table name of CREATE INDEX into details
+// [USING method] ( element, ... ) [INCLUDE ( columns )] [NULLS [NOT] DISTINCT] [WITH ( parameters )]
+// [TABLESPACE name] [WHERE predicate]
+func parseIndexClauses(text string, details *CreateIndexDetails) {
+	masked := blankParentheses(MaskLiterals(text))
+
+	if loc := indexUsingRe.FindStringSubmatchIndex(masked); loc != nil {
+		details.Method = NormalizeIdentifier(text[loc[2]:loc[3]])
+		text, masked = text[loc[1]:], masked[loc[1]:]
+	}
+
+	if open := strings.Index(masked, "("); open != -1 {
+		end := closingParenthesis(masked, open)
+		for _, element := range SplitTopLevel(ExtractParenthesesContent(text[open:end]), ",") {
+			if element = strings.TrimSpace(element); element != "" {
+				details.Elements = append(details.Elements, parseIndexElement(element))
+			}
+		}
+		text, masked = text[end:], masked[end:]
+	}
+
+	// The predicate runs to the end, so the other clauses are only looked for before it
+	if loc := indexWhereRe.FindStringIndex(masked); loc != nil {
+		details.Where = strings.TrimSpace(text[loc[1]:])
+		text, masked = text[:loc[0]], masked[:loc[0]]
+	}
+
+	if loc := indexIncludeRe.FindStringIndex(masked); loc != nil {
+		details.Include = SplitIdentifierList(ExtractParenthesesContent(text[loc[1]-1:]))
+	}
+	details.NullsNotDistinct = indexNullsRe.MatchString(masked)
+	if loc := indexParamsRe.FindStringIndex(masked); loc != nil {
+		details.Params = SplitStorageParameters(ExtractParenthesesContent(text[loc[1]-1:]))
+	}
+	if loc := indexTablespaceRe.FindStringSubmatchIndex(masked); loc != nil {
+		details.Tablespace = NormalizeIdentifier(text[loc[2]:loc[3]])
+	}
+}
+
+// parseIndexElement parses a key column or expression of an index with its options
+// column | ( expression ) | function ( arguments ) [COLLATE collation] [opclass [( parameters )]] [ASC | DESC] [NULLS FIRST | LAST]
+func parseIndexElement(text string) IndexElement {
+	var element IndexElement
+	masked := blankParentheses(MaskLiterals(text))
+
+	// Expressions are kept as written, function calls without parentheses of their own
+	var rest, restMasked string
+	trimmed := strings.TrimLeft(masked, " \t\r\n")
+	start := len(masked) - len(trimmed)
+	_, _, afterName := ReadQualifiedName(masked)
+	switch {
+	case strings.HasPrefix(trimmed, "("):
+		end := closingParenthesis(masked, start)
+		element.Expression = text[start:end]
+		rest, restMasked = text[end:], masked[end:]
+	case strings.HasPrefix(strings.TrimLeft(afterName, " \t\r\n"), "("):
+		open := len(masked) - len(afterName) + strings.Index(afterName, "(")
+		end := closingParenthesis(masked, open)
+		element.Expression = text[start:end]
+		rest, restMasked = text[end:], masked[end:]
+	default:
+		var afterColumn string
+		element.Column, afterColumn = ReadIdentifier(text)
+		rest, restMasked = afterColumn, masked[len(masked)-len(afterColumn):]
+	}
+
+	if loc := elementNullsRe.FindStringSubmatchIndex(restMasked); loc != nil {
+		element.Nulls = strings.ToUpper(restMasked[loc[2]:loc[3]])
+		rest, restMasked = rest[:loc[0]], restMasked[:loc[0]]
+	}
+	if loc := elementOrderRe.FindStringSubmatchIndex(restMasked); loc != nil {
+		element.Order = strings.ToUpper(restMasked[loc[2]:loc[3]])
+		rest, restMasked = rest[:loc[0]], restMasked[:loc[0]]
+	}
+	if loc := elementCollateRe.FindStringSubmatchIndex(restMasked); loc != nil {
+		element.Collation = rest[loc[2]:loc[3]]
+		rest = rest[loc[1]:]
+	}
+	element.Opclass = strings.TrimSpace(rest)
+
+	return element
+}
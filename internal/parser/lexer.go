@@ -3,98 +3,512 @@ package parser
 import (
 	"strings"
 	"unicode"
+	"unicode/utf8"
 )
 
+// TokenType identifies the kind of a lexical token
+type TokenType int
+
+const (
+	TokenWhitespace       TokenType = iota
+	TokenComment                    // -- line comment or /* block comment */
+	TokenIdentifier                 // Unquoted identifier or keyword
+	TokenQuotedIdentifier           // "Quoted" or U&"quoted" identifier
+	TokenString                     // 'string', E'escape', B'bits', X'hex', N'national' or U&'unicode'
+	TokenDollarString               // $$string$$ or $tag$string$tag$
+	TokenNumber                     // Integer or decimal constant
+	TokenParameter                  // Positional parameter such as $1
+	TokenOperator                   // Operators and punctuation, including ( ) , ; . and ::
+)
+
+func (tt TokenType) String() string {
+	switch tt {
+	case TokenWhitespace:
+		return "whitespace"
+	case TokenComment:
+		return "comment"
+	case TokenIdentifier:
+		return "identifier"
+	case TokenQuotedIdentifier:
+		return "quoted identifier"
+	case TokenString:
+		return "string"
+	case TokenDollarString:
+		return "dollar string"
+	case TokenNumber:
+		return "number"
+	case TokenParameter:
+		return "parameter"
+	case TokenOperator:
+		return "operator"
+	default:
+		return "unknown"
+	}
+}
+
+// Token is a lexical token with its position in the input
+type Token struct {
+	Type   TokenType
+	Text   string // Exact source text, including quotes and delimiters
+	Offset int    // Byte offset of the first character
+	Line   int    // 1-based line number
+	Column int    // 1-based column, counted in characters
+}
+
+// IsKeyword reports whether the token is the given unquoted keyword
+func (t Token) IsKeyword(keyword string) bool {
+	return t.Type == TokenIdentifier && strings.EqualFold(t.Text, keyword)
+}
+
+// IsOperator reports whether the token is the given operator or punctuation
+func (t Token) IsOperator(op string) bool {
+	return t.Type == TokenOperator && t.Text == op
+}
+
+// IsLiteral reports whether the token is a string constant of any kind
+func (t Token) IsLiteral() bool {
+	return t.Type == TokenString || t.Type == TokenDollarString
+}
+
+// operatorChars are the characters PostgreSQL operators are built from
+const operatorChars = "+-*/<>=~!@#%^&|`?"
+
+// lexer turns SQL text into tokens, tracking line and column as it goes
+type lexer struct {
+	input  string
+	pos    int
+	line   int
+	column int
+	tokens []Token
+}
+
+// Tokenize splits SQL text into tokens
+// Every byte of the input belongs to exactly one token, so joining the token texts gives back the input
+func Tokenize(sql string) []Token {
+	l := &lexer{input: sql, line: 1, column: 1}
+	for l.pos < len(l.input) {
+		l.next()
+	}
+	return l.tokens
+}
+
+// next scans the token starting at the current position
+func (l *lexer) next() {
+	start := l.pos
+	ch, _ := utf8.DecodeRuneInString(l.input[l.pos:])
+
+	var tokenType TokenType
+	var end int
+	switch {
+	case unicode.IsSpace(ch):
+		tokenType, end = TokenWhitespace, l.scanWhile(start, unicode.IsSpace)
+	case strings.HasPrefix(l.input[start:], "--"):
+		tokenType, end = TokenComment, l.scanLineComment(start)
+	case strings.HasPrefix(l.input[start:], "/*"):
+		tokenType, end = TokenComment, l.scanBlockComment(start)
+	case ch == '\'':
+		tokenType, end = TokenString, l.scanString(start, start, false)
+	case ch == '"':
+		tokenType, end = TokenQuotedIdentifier, l.scanQuoted(start+1, '"')
+	case (ch == 'U' || ch == 'u') && strings.HasPrefix(l.input[start+1:], "&'"):
+		tokenType, end = TokenString, l.scanString(start, start+2, false)
+	case (ch == 'U' || ch == 'u') && strings.HasPrefix(l.input[start+1:], `&"`):
+		tokenType, end = TokenQuotedIdentifier, l.scanQuoted(start+3, '"')
+	case strings.ContainsRune("EeBbXxNn", ch) && strings.HasPrefix(l.input[start+1:], "'"):
+		tokenType, end = TokenString, l.scanString(start, start+1, ch == 'E' || ch == 'e')
+	case ch == '$':
+		tokenType, end = l.scanDollar(start)
+	case isIdentStart(ch):
+		tokenType, end = TokenIdentifier, l.scanWhile(start, isIdentRune)
+	case unicode.IsDigit(ch) || (ch == '.' && start+1 < len(l.input) && isDigitByte(l.input[start+1])):
+		tokenType, end = TokenNumber, l.scanNumber(start)
+	default:
+		tokenType, end = TokenOperator, l.scanOperator(start)
+	}
+
+	l.emit(tokenType, start, end)
+}
+
+// emit records the token spanning input[start:end] and advances the position
+func (l *lexer) emit(tokenType TokenType, start, end int) {
+	text := l.input[start:end]
+	l.tokens = append(l.tokens, Token{
+		Type:   tokenType,
+		Text:   text,
+		Offset: start,
+		Line:   l.line,
+		Column: l.column,
+	})
+
+	for _, ch := range text {
+		if ch == '\n' {
+			l.line++
+			l.column = 1
+		} else {
+			l.column++
+		}
+	}
+	l.pos = end
+}
+
+// scanWhile returns the end of the run of runes starting at start that satisfy accept
+func (l *lexer) scanWhile(start int, accept func(rune) bool) int {
+	i := start
+	for i < len(l.input) {
+		ch, size := utf8.DecodeRuneInString(l.input[i:])
+		if !accept(ch) {
+			break
+		}
+		i += size
+	}
+	return i
+}
+
+// scanLineComment returns the end of a -- comment, which stops before the newline
+func (l *lexer) scanLineComment(start int) int {
+	if i := strings.IndexByte(l.input[start:], '\n'); i != -1 {
+		return start + i
+	}
+	return len(l.input)
+}
+
+// scanBlockComment returns the end of a /* comment */, which may be nested
+func (l *lexer) scanBlockComment(start int) int {
+	depth := 0
+	for i := start; i < len(l.input)-1; i++ {
+		switch l.input[i : i+2] {
+		case "/*":
+			depth++
+			i++
+		case "*/":
+			depth--
+			i++
+			if depth == 0 {
+				return i + 1
+			}
+		}
+	}
+	return len(l.input)
+}
+
+// scanString returns the end of a string constant whose opening quote is at quote
+// Quotes are escaped by doubling them, and also by backslash in E'...' strings
+func (l *lexer) scanString(start, quote int, backslashEscapes bool) int {
+	for i := quote + 1; i < len(l.input); i++ {
+		switch l.input[i] {
+		case '\\':
+			if backslashEscapes {
+				i++
+			}
+		case '\'':
+			if i+1 < len(l.input) && l.input[i+1] == '\'' {
+				i++
+				continue
+			}
+			return i + 1
+		}
+	}
+	return len(l.input)
+}
+
+// scanQuoted returns the end of a quoted identifier whose body starts at bodyStart
+func (l *lexer) scanQuoted(bodyStart int, quote byte) int {
+	for i := bodyStart; i < len(l.input); i++ {
+		if l.input[i] == quote {
+			if i+1 < len(l.input) && l.input[i+1] == quote {
+				i++
+				continue
+			}
+			return i + 1
+		}
+	}
+	return len(l.input)
+}
+
+// scanDollar scans a dollar-quoted string or a positional parameter starting at a $
+func (l *lexer) scanDollar(start int) (TokenType, int) {
+	rest := l.input[start+1:]
+
+	// $1, $2, ... are parameters
+	if len(rest) > 0 && isDigitByte(rest[0]) {
+		return TokenParameter, l.scanWhile(start+1, unicode.IsDigit)
+	}
+
+	// $tag$ opens a dollar-quoted string that runs to the same tag
+	// The tag follows identifier rules but cannot contain a dollar sign
+	tagEnd := l.scanWhile(start+1, func(ch rune) bool {
+		return ch != '$' && isIdentRune(ch)
+	})
+	if tagEnd >= len(l.input) || l.input[tagEnd] != '$' {
+		return TokenOperator, start + 1
+	}
+
+	tag := l.input[start : tagEnd+1]
+	if i := strings.Index(l.input[tagEnd+1:], tag); i != -1 {
+		return TokenDollarString, tagEnd + 1 + i + len(tag)
+	}
+	return TokenDollarString, len(l.input)
+}
+
+// scanNumber returns the end of a numeric constant
+func (l *lexer) scanNumber(start int) int {
+	i := start
+	seenDot := false
+	for i < len(l.input) {
+		ch := l.input[i]
+		switch {
+		case isDigitByte(ch) || ch == '_':
+			i++
+		case ch == '.' && !seenDot && !strings.HasPrefix(l.input[i:], ".."):
+			seenDot = true
+			i++
+		case (ch == 'e' || ch == 'E') && i+1 < len(l.input):
+			next := i + 1
+			if l.input[next] == '+' || l.input[next] == '-' {
+				next++
+			}
+			if next >= len(l.input) || !isDigitByte(l.input[next]) {
+				return i
+			}
+			i = next
+			for i < len(l.input) && isDigitByte(l.input[i]) {
+				i++
+			}
+			return i
+		default:
+			return i
+		}
+	}
+	return i
+}
+
+// scanOperator returns the end of an operator or punctuation token
+// Operator runs stop where a comment starts, as in PostgreSQL
+func (l *lexer) scanOperator(start int) int {
+	if strings.HasPrefix(l.input[start:], "::") {
+		return start + 2
+	}
+	if !strings.ContainsRune(operatorChars, rune(l.input[start])) {
+		_, size := utf8.DecodeRuneInString(l.input[start:])
+		return start + size
+	}
+
+	i := start + 1
+	for i < len(l.input) && strings.ContainsRune(operatorChars, rune(l.input[i])) {
+		if strings.HasPrefix(l.input[i:], "--") || strings.HasPrefix(l.input[i:], "/*") {
+			break
+		}
+		i++
+	}
+	return i
+}
+
+func isIdentStart(ch rune) bool {
+	return ch == '_' || unicode.IsLetter(ch)
+}
+
+func isIdentRune(ch rune) bool {
+	return ch == '_' || ch == '$' || unicode.IsLetter(ch) || unicode.IsDigit(ch)
+}
+
+func isDigitByte(ch byte) bool {
+	return ch >= '0' && ch <= '9'
+}
+
+// rawStatement is the text of a single statement and where it starts
+type rawStatement struct {
+	Text string
+	Pos  Position // File is left empty
+}
+
 // SplitStatements splits SQL text into individual statements
-// Handles semicolons within quotes and dollar-quoted strings
+// Statements end at semicolons outside literals, quoted identifiers and comments.
+// Comments are dropped; all other text, literals included, is kept exactly as written.
 func SplitStatements(sql string) []string {
 	var statements []string
+	for _, stmt := range splitStatements(sql) {
+		statements = append(statements, stmt.Text)
+	}
+	return statements
+}
+
+// splitStatements splits SQL text into statements, recording where each one starts
+// Semicolons inside the BEGIN ATOMIC ... END body of a function or procedure do not end the statement
+func splitStatements(sql string) []rawStatement {
+	var statements []rawStatement
 	var current strings.Builder
-	var inSingleQuote bool
-	var inDoubleQuote bool
-	var inDollarQuote bool
-	var dollarTag string
-
-	runes := []rune(sql)
-
-	for i := 0; i < len(runes); i++ {
-		ch := runes[i]
-
-		// Handle dollar quotes ($$, $tag$, etc.)
-		if ch == '$' && !inSingleQuote && !inDoubleQuote {
-			// Look ahead to find matching $
-			tagEnd := i + 1
-			for tagEnd < len(runes) && runes[tagEnd] != '$' {
-				tagEnd++
-			}
-			if tagEnd < len(runes) {
-				tag := string(runes[i:tagEnd+1])
-
-				if inDollarQuote {
-					current.WriteRune(ch)
-					if tag == dollarTag {
-						// End of dollar quote
-						for j := i + 1; j <= tagEnd; j++ {
-							current.WriteRune(runes[j])
-						}
-						i = tagEnd
-						inDollarQuote = false
-						dollarTag = ""
-						continue
-					}
-				} else {
-					// Start of dollar quote
-					inDollarQuote = true
-					dollarTag = tag
-					for j := i; j <= tagEnd; j++ {
-						current.WriteRune(runes[j])
-					}
-					i = tagEnd
-					continue
+	var pos Position
+	var leading []string // First keywords of the statement, up to the object kind
+	depth := 0           // BEGIN ... END nesting within a routine body
+
+	flush := func() {
+		if text := strings.TrimSpace(current.String()); text != "" {
+			statements = append(statements, rawStatement{Text: text, Pos: pos})
+		}
+		current.Reset()
+		pos = Position{}
+		leading = nil
+		depth = 0
+	}
+
+	for _, tok := range Tokenize(sql) {
+		switch {
+		case tok.Type == TokenComment:
+			// Keep the tokens on either side apart
+			current.WriteString(" ")
+		case tok.IsOperator(";") && depth == 0:
+			flush()
+		default:
+			if pos.Line == 0 && tok.Type != TokenWhitespace {
+				pos = Position{Offset: tok.Offset, Line: tok.Line, Column: tok.Column}
+			}
+			if tok.Type == TokenIdentifier {
+				if len(leading) < 4 {
+					leading = append(leading, strings.ToUpper(tok.Text))
 				}
+				depth = routineBodyDepth(leading, tok, depth)
 			}
+			current.WriteString(tok.Text)
 		}
+	}
+	flush()
 
-		// Handle single quotes
-		if ch == '\'' && !inDoubleQuote && !inDollarQuote {
-			current.WriteRune(ch)
-			// Check for escaped quote
-			if i+1 < len(runes) && runes[i+1] == '\'' {
-				current.WriteRune(runes[i+1])
-				i++
-			} else {
-				inSingleQuote = !inSingleQuote
-			}
-			continue
+	return statements
+}
+
+// routineBodyDepth tracks BEGIN ... END nesting in the body of a CREATE FUNCTION or PROCEDURE statement
+// CASE also closes with END, so it opens a level once inside a body
+func routineBodyDepth(leading []string, tok Token, depth int) int {
+	if !isRoutineDefinition(leading) {
+		return depth
+	}
+	switch {
+	case tok.IsKeyword("BEGIN"):
+		return depth + 1
+	case tok.IsKeyword("CASE") && depth > 0:
+		return depth + 1
+	case tok.IsKeyword("END") && depth > 0:
+		return depth - 1
+	}
+	return depth
+}
+
+// isRoutineDefinition reports whether a statement starting with the given keywords creates a function or procedure
+func isRoutineDefinition(leading []string) bool {
+	if len(leading) < 2 || leading[0] != "CREATE" {
+		return false
+	}
+	kind := leading[1]
+	if kind == "OR" && len(leading) >= 4 {
+		kind = leading[3]
+	}
+	return kind == "FUNCTION" || kind == "PROCEDURE"
+}
+
+// SplitTopLevel splits SQL text at each delimiter outside parentheses, literals and quoted identifiers
+func SplitTopLevel(s string, delim string) []string {
+	var parts []string
+	depth := 0
+	start := 0
+
+	for _, tok := range Tokenize(s) {
+		switch {
+		case tok.IsOperator("(") || tok.IsOperator("["):
+			depth++
+		case tok.IsOperator(")") || tok.IsOperator("]"):
+			depth--
+		case tok.IsOperator(delim) && depth == 0:
+			parts = append(parts, s[start:tok.Offset])
+			start = tok.Offset + len(tok.Text)
 		}
+	}
+	if start < len(s) {
+		parts = append(parts, s[start:])
+	}
+
+	return parts
+}
 
-		// Handle double quotes
-		if ch == '"' && !inSingleQuote && !inDollarQuote {
-			current.WriteRune(ch)
-			inDoubleQuote = !inDoubleQuote
-			continue
+// MaskLiterals blanks out the contents of string constants and comments
+// String constants become a plain '...' constant of the same length, prefix and dollar quotes included,
+// so masked text still tokenizes with each constant as one value.
+// The result has the same length as the input, so match positions can be mapped back
+func MaskLiterals(sql string) string {
+	masked := []byte(sql)
+	for _, tok := range Tokenize(sql) {
+		start, end := tok.Offset, tok.Offset+len(tok.Text)
+		switch {
+		case tok.IsLiteral() && len(tok.Text) >= 2:
+			for i := start; i < end; i++ {
+				masked[i] = ' '
+			}
+			// E'...', B'...', U&'...' and friends keep their opening quote where it was
+			quote := start
+			if tok.Type == TokenString {
+				quote += strings.IndexByte(tok.Text, '\'')
+			}
+			masked[quote], masked[end-1] = '\'', '\''
+		case tok.Type == TokenComment:
+			for i := start; i < end; i++ {
+				masked[i] = ' '
+			}
 		}
+	}
+	return string(masked)
+}
 
-		// Handle statement terminator
-		if ch == ';' && !inSingleQuote && !inDoubleQuote && !inDollarQuote {
-			stmt := strings.TrimSpace(current.String())
-			if stmt != "" {
-				statements = append(statements, stmt)
+// UnquoteString returns the value of a string constant token
+// Handles doubled quotes, backslash escapes in E'...' strings and dollar quoting
+func UnquoteString(text string) string {
+	if strings.HasPrefix(text, "$") {
+		if i := strings.Index(text[1:], "$"); i != -1 {
+			tagLen := i + 2
+			if len(text) >= 2*tagLen {
+				return text[tagLen : len(text)-tagLen]
 			}
-			current.Reset()
-			continue
 		}
+		return text
+	}
 
-		current.WriteRune(ch)
+	quote := strings.IndexByte(text, '\'')
+	if quote == -1 || len(text) < quote+2 {
+		return text
 	}
+	prefix := strings.ToUpper(text[:quote])
+	body := text[quote+1 : len(text)-1]
 
-	// Add final statement if any
-	stmt := strings.TrimSpace(current.String())
-	if stmt != "" {
-		statements = append(statements, stmt)
+	if prefix != "E" {
+		return strings.ReplaceAll(body, "''", "'")
 	}
 
-	return statements
+	var value strings.Builder
+	for i := 0; i < len(body); i++ {
+		ch := body[i]
+		switch {
+		case ch == '\'' && i+1 < len(body) && body[i+1] == '\'':
+			value.WriteByte('\'')
+			i++
+		case ch == '\\' && i+1 < len(body):
+			i++
+			switch body[i] {
+			case 'n':
+				value.WriteByte('\n')
+			case 't':
+				value.WriteByte('\t')
+			case 'r':
+				value.WriteByte('\r')
+			case 'b':
+				value.WriteByte('\b')
+			case 'f':
+				value.WriteByte('\f')
+			default:
+				value.WriteByte(body[i])
+			}
+		default:
+			value.WriteByte(ch)
+		}
+	}
+	return value.String()
 }
 
 // NormalizeWhitespace replaces multiple whitespace characters with single space
@@ -117,46 +531,26 @@ func NormalizeWhitespace(s string) string {
 	return strings.TrimSpace(result.String())
 }
 
-// ExtractParenthesesContent extracts content within parentheses
-// Handles nested parentheses
+// ExtractParenthesesContent extracts content within the first pair of parentheses
+// Handles nested parentheses and ignores parentheses inside literals and quoted identifiers
 func ExtractParenthesesContent(s string) string {
-	start := strings.Index(s, "(")
-	if start == -1 {
-		return ""
-	}
-
 	depth := 0
-	for i := start; i < len(s); i++ {
-		if s[i] == '(' {
+	start := -1
+
+	for _, tok := range Tokenize(s) {
+		switch {
+		case tok.IsOperator("("):
+			if depth == 0 {
+				start = tok.Offset
+			}
 			depth++
-		} else if s[i] == ')' {
+		case tok.IsOperator(")") && depth > 0:
 			depth--
 			if depth == 0 {
-				return s[start+1 : i]
+				return s[start+1 : tok.Offset]
 			}
 		}
 	}
 
 	return ""
 }
-
-// StripComments removes SQL comments from the input
-func StripComments(sql string) string {
-	var result strings.Builder
-	lines := strings.Split(sql, "\n")
-
-	for _, line := range lines {
-		// Remove single-line comments (--)
-		if idx := strings.Index(line, "--"); idx != -1 {
-			line = line[:idx]
-		}
-
-		trimmed := strings.TrimSpace(line)
-		if trimmed != "" {
-			result.WriteString(line)
-			result.WriteRune('\n')
-		}
-	}
-
-	return result.String()
-}
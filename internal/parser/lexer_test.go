@@ -0,0 +1,127 @@
+package parser
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestTokenize(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+		want  []string // Type and text of each token other than whitespace
+	}{
+		{
+			name:  "identifiers and operators",
+			input: `SELECT a.b, "C d" FROM t;`,
+			want:  []string{"identifier SELECT", "identifier a", "operator .", "identifier b", "operator ,", `quoted identifier "C d"`, "identifier FROM", "identifier t", "operator ;"},
+		},
+		{
+			name:  "string with doubled quote",
+			input: `'it''s'`,
+			want:  []string{"string 'it''s'"},
+		},
+		{
+			name:  "escape string with backslash quote",
+			input: `E'it\'s -- ok'`,
+			want:  []string{`string E'it\'s -- ok'`},
+		},
+		{
+			name:  "prefixed strings",
+			input: `B'101' X'ff' N'n' U&'d\0061'`,
+			want:  []string{"string B'101'", "string X'ff'", "string N'n'", `string U&'d\0061'`},
+		},
+		{
+			name:  "dollar strings",
+			input: `$$a'b$$ $fn$ $$ $fn$`,
+			want:  []string{"dollar string $$a'b$$", "dollar string $fn$ $$ $fn$"},
+		},
+		{
+			name:  "comments",
+			input: "a -- x 'y\n/* b /* c */ 'd' */ e",
+			want:  []string{"identifier a", "comment -- x 'y", "comment /* b /* c */ 'd' */", "identifier e"},
+		},
+		{
+			name:  "numbers, parameters and casts",
+			input: `$1::numeric + 1.5e3`,
+			want:  []string{"parameter $1", "operator ::", "identifier numeric", "operator +", "number 1.5e3"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got []string
+			for _, tok := range Tokenize(tt.input) {
+				if tok.Type != TokenWhitespace {
+					got = append(got, tok.Type.String()+" "+tok.Text)
+				}
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Tokenize(%q)\n got %q\nwant %q", tt.input, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMaskLiterals(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+		want  string
+	}{
+		{
+			name:  "plain string",
+			input: `a = 'x(y'`,
+			want:  `a = '   '`,
+		},
+		{
+			name:  "escape string",
+			input: `(e DEFAULT E'it\'s -- ok')`,
+			want:  `(e DEFAULT  '           ')`,
+		},
+		{
+			name:  "prefixed strings",
+			input: `B'1' X'f' N'n' U&'d'`,
+			want:  ` ' '  ' '  ' '   ' '`,
+		},
+		{
+			name:  "dollar string",
+			input: `AS $fn$ ( $fn$;`,
+			want:  `AS '         ';`,
+		},
+		{
+			name:  "comment",
+			input: "a -- (\nb",
+			want:  "a     \nb",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := MaskLiterals(tt.input)
+			if got != tt.want {
+				t.Errorf("MaskLiterals(%q) = %q, want %q", tt.input, got, tt.want)
+			}
+			if len(got) != len(tt.input) {
+				t.Errorf("MaskLiterals(%q) changed the length from %d to %d", tt.input, len(tt.input), len(got))
+			}
+
+			// Masked text still reads as one value per constant
+			var literals int
+			for _, tok := range Tokenize(got) {
+				if tok.IsLiteral() {
+					literals++
+				}
+			}
+			var want int
+			for _, tok := range Tokenize(tt.input) {
+				if tok.IsLiteral() {
+					want++
+				}
+			}
+			if literals != want {
+				t.Errorf("MaskLiterals(%q) = %q tokenizes to %d constants, want %d", tt.input, got, literals, want)
+			}
+		})
+	}
+}
@@ -1,6 +1,7 @@
 package parser
 
 import (
+	"errors"
 	"fmt"
 	"os"
 	"regexp"
@@ -9,7 +10,8 @@ import (
 
 // Parser handles SQL DDL parsing
 type Parser struct {
-	patterns map[string]*regexp.Regexp
+	patterns    map[string]*regexp.Regexp
+	diagnostics []Diagnostic
 }
 
 // NewParser creates a new SQL parser
@@ -19,31 +21,108 @@ func NewParser() *Parser {
 	}
 }
 
+// typePattern matches a column data type
+// Handles: word, word(params), word precision, word with time zone, word[]
+const typePattern = `(?:double\s+precision|(?:character|bit)\s+varying|(?:timestamp|time)(?:\s*\(\s*\d+\s*\))?\s+with(?:out)?\s+time\s+zone|` + NamePattern + `)(?:\s*\([^)]*\))?(?:\s*\[\d*\])*`
+
+// dataTypeRe matches a data type at the start of a column definition
+var dataTypeRe = regexp.MustCompile(`(?i)^\s*(` + typePattern + `)`)
+
+// ReadDataType reads a data type from the start of s
+// Returns the type as written and the remaining text
+func ReadDataType(s string) (dataType, rest string) {
+	loc := dataTypeRe.FindStringSubmatchIndex(s)
+	if loc == nil {
+		return "", s
+	}
+	return s[loc[2]:loc[3]], s[loc[1]:]
+}
+
 // compilePatterns compiles all regex patterns for statement matching
 func compilePatterns() map[string]*regexp.Regexp {
 	return map[string]*regexp.Regexp{
-		"CREATE_TABLE":    regexp.MustCompile(`(?i)^\s*CREATE\s+TABLE\s+(?:IF\s+NOT\s+EXISTS\s+)?(\w+)`),
-		"ALTER_TABLE":     regexp.MustCompile(`(?i)^\s*ALTER\s+TABLE\s+(?:IF\s+EXISTS\s+)?(\w+)`),
-		"DROP_TABLE":      regexp.MustCompile(`(?i)^\s*DROP\s+TABLE\s+(?:IF\s+EXISTS\s+)?(\w+)`),
-		"CREATE_TYPE":     regexp.MustCompile(`(?i)^\s*CREATE\s+TYPE\s+(\w+)\s+AS\s+ENUM`),
-		"ALTER_TYPE":      regexp.MustCompile(`(?i)^\s*ALTER\s+TYPE\s+(\w+)\s+ADD\s+VALUE`),
-		"DROP_TYPE":       regexp.MustCompile(`(?i)^\s*DROP\s+TYPE\s+(?:IF\s+EXISTS\s+)?(\w+)`),
-		"CREATE_DOMAIN":   regexp.MustCompile(`(?i)^\s*CREATE\s+DOMAIN\s+(\w+)\s+AS`),
-		"DROP_DOMAIN":     regexp.MustCompile(`(?i)^\s*DROP\s+DOMAIN\s+(?:IF\s+EXISTS\s+)?(\w+)`),
-		"CREATE_VIEW":     regexp.MustCompile(`(?i)^\s*CREATE\s+(?:OR\s+REPLACE\s+)?VIEW\s+(\w+)`),
-		"DROP_VIEW":       regexp.MustCompile(`(?i)^\s*DROP\s+VIEW\s+(?:IF\s+EXISTS\s+)?(\w+)`),
-		"CREATE_INDEX":    regexp.MustCompile(`(?i)^\s*CREATE\s+(?:UNIQUE\s+)?INDEX\s+(\w+)\s+ON\s+(\w+)`),
-		"DROP_INDEX":      regexp.MustCompile(`(?i)^\s*DROP\s+INDEX\s+(?:IF\s+EXISTS\s+)?(\w+)`),
-		"COMMENT_ON":      regexp.MustCompile(`(?i)^\s*COMMENT\s+ON\s+(TABLE|COLUMN|TYPE|VIEW)\s+(\S+)`),
-		"DO_BLOCK":        regexp.MustCompile(`(?i)^\s*DO\s+\$\$`),
-
-		// ALTER TABLE operations
-		// Type pattern handles: word, word(params), word precision, word with time zone, word[]
-		"ADD_COLUMN":      regexp.MustCompile(`(?i)ADD\s+COLUMN\s+(\w+)\s+((?:(?:double|character|timestamp|time)\s+(?:precision|varying|with(?:\s+time)?\s+zone|without(?:\s+time)?\s+zone)|\w+)(?:\([^)]+\))?(?:\[\])?)`),
-		"DROP_COLUMN":     regexp.MustCompile(`(?i)DROP\s+COLUMN\s+(?:IF\s+EXISTS\s+)?(\w+)`),
-		"ALTER_COLUMN":    regexp.MustCompile(`(?i)ALTER\s+COLUMN\s+(\w+)`),
-		"ALTER_COL_TYPE":  regexp.MustCompile(`(?i)ALTER\s+COLUMN\s+(\w+)\s+TYPE\s+((?:(?:double|character|timestamp|time)\s+(?:precision|varying|with(?:\s+time)?\s+zone|without(?:\s+time)?\s+zone)|\w+)(?:\([^)]+\))?(?:\[\])?)(?:\s+USING\s+(.+))?`),
-		"ALTER_COL_NULL":  regexp.MustCompile(`(?i)ALTER\s+COLUMN\s+(\w+)\s+(SET|DROP)\s+NOT\s+NULL`),
+		"CREATE_TABLE":  regexp.MustCompile(`(?i)^\s*CREATE\s+(UNLOGGED\s+)?TABLE\s+(?:IF\s+NOT\s+EXISTS\s+)?(` + NamePattern + `)`),
+		"ALTER_TABLE":   regexp.MustCompile(`(?i)^\s*ALTER\s+TABLE\s+(?:IF\s+EXISTS\s+)?(?:ONLY\s+)?(` + NamePattern + `)`),
+		"DROP_TABLE":    regexp.MustCompile(`(?i)^\s*DROP\s+TABLE\s+(?:IF\s+EXISTS\s+)?(` + NamePattern + `)`),
+		"CREATE_TYPE":   regexp.MustCompile(`(?i)^\s*CREATE\s+TYPE\s+(` + NamePattern + `)\s+AS\s*(ENUM\b|RANGE\b|\()`),
+		"ALTER_TYPE":    regexp.MustCompile(`(?i)^\s*ALTER\s+TYPE\s+(` + NamePattern + `)\s+(ADD\s+VALUE|RENAME\s+VALUE|RENAME\s+TO|(?:ADD|DROP|ALTER|RENAME)\s+ATTRIBUTE)\b`),
+		"DROP_TYPE":     regexp.MustCompile(`(?i)^\s*DROP\s+TYPE\s+(?:IF\s+EXISTS\s+)?(` + NamePattern + `)`),
+		"CREATE_DOMAIN": regexp.MustCompile(`(?i)^\s*CREATE\s+DOMAIN\s+(` + NamePattern + `)(?:\s+AS\b)?`),
+		"ALTER_DOMAIN":  regexp.MustCompile(`(?i)^\s*ALTER\s+DOMAIN\s+(` + NamePattern + `)\s+(SET\s+DEFAULT|DROP\s+DEFAULT|SET\s+NOT\s+NULL|DROP\s+NOT\s+NULL|ADD|DROP\s+CONSTRAINT|RENAME\s+CONSTRAINT|VALIDATE\s+CONSTRAINT|RENAME\s+TO)\b`),
+		"DROP_DOMAIN":   regexp.MustCompile(`(?i)^\s*DROP\s+DOMAIN\s+(?:IF\s+EXISTS\s+)?(` + NamePattern + `)`),
+		"CREATE_VIEW":   regexp.MustCompile(`(?i)^\s*CREATE\s+(?:OR\s+REPLACE\s+)?VIEW\s+(` + NamePattern + `)`),
+		"DROP_VIEW":     regexp.MustCompile(`(?i)^\s*DROP\s+VIEW\s+(?:IF\s+EXISTS\s+)?(` + NamePattern + `)`),
+		"CREATE_MVIEW":  regexp.MustCompile(`(?i)^\s*CREATE\s+MATERIALIZED\s+VIEW\s+(?:IF\s+NOT\s+EXISTS\s+)?(` + NamePattern + `)`),
+		"DROP_MVIEW":    regexp.MustCompile(`(?i)^\s*DROP\s+MATERIALIZED\s+VIEW\s+(?:IF\s+EXISTS\s+)?(` + NamePattern + `)`),
+		"REFRESH_MVIEW": regexp.MustCompile(`(?i)^\s*REFRESH\s+MATERIALIZED\s+VIEW\s+(?:CONCURRENTLY\s+)?(` + NamePattern + `)`),
+		"CREATE_INDEX":  regexp.MustCompile(`(?i)^\s*CREATE\s+(UNIQUE\s+)?INDEX\s+(?:CONCURRENTLY\s+)?(?:IF\s+NOT\s+EXISTS\s+)?(?:(` + IdentPattern + `)\s+)??ON\s+(?:ONLY\s+)?(` + NamePattern + `)`),
+		"ALTER_INDEX":   regexp.MustCompile(`(?i)^\s*ALTER\s+INDEX\s+(?:IF\s+EXISTS\s+)?(` + NamePattern + `)\s+RENAME\s+TO\s+(` + IdentPattern + `)`),
+		"DROP_INDEX":    regexp.MustCompile(`(?i)^\s*DROP\s+INDEX\s+(?:CONCURRENTLY\s+)?(?:IF\s+EXISTS\s+)?(` + NamePattern + `)`),
+		"CREATE_SEQ":    regexp.MustCompile(`(?i)^\s*CREATE\s+(?:(?:TEMP|TEMPORARY|UNLOGGED)\s+)?SEQUENCE\s+(?:IF\s+NOT\s+EXISTS\s+)?(` + NamePattern + `)`),
+		"ALTER_SEQ":     regexp.MustCompile(`(?i)^\s*ALTER\s+SEQUENCE\s+(?:IF\s+EXISTS\s+)?(` + NamePattern + `)`),
+		"DROP_SEQ":      regexp.MustCompile(`(?i)^\s*DROP\s+SEQUENCE\s+(?:IF\s+EXISTS\s+)?(` + NamePattern + `)`),
+		"CREATE_FUNC":   regexp.MustCompile(`(?i)^\s*CREATE\s+(?:OR\s+REPLACE\s+)?(FUNCTION|PROCEDURE)\s+(` + NamePattern + `)\s*\(`),
+		"DROP_FUNC":     regexp.MustCompile(`(?i)^\s*DROP\s+(FUNCTION|PROCEDURE|ROUTINE)\s+(?:IF\s+EXISTS\s+)?(` + NamePattern + `)\s*(\()?`),
+		"CREATE_TRIG":   regexp.MustCompile(`(?is)^\s*CREATE\s+(?:OR\s+REPLACE\s+)?(CONSTRAINT\s+)?TRIGGER\s+(` + IdentPattern + `)\s+(BEFORE|AFTER|INSTEAD\s+OF)\s+(.+?)\s+ON\s+(` + NamePattern + `)`),
+		"ALTER_TRIG":    regexp.MustCompile(`(?i)^\s*ALTER\s+TRIGGER\s+(` + IdentPattern + `)\s+ON\s+(` + NamePattern + `)\s+RENAME\s+TO\s+(` + IdentPattern + `)`),
+		"CREATE_POLICY": regexp.MustCompile(`(?i)^\s*CREATE\s+POLICY\s+(` + IdentPattern + `)\s+ON\s+(` + NamePattern + `)`),
+		"ALTER_POLICY":  regexp.MustCompile(`(?i)^\s*ALTER\s+POLICY\s+(` + IdentPattern + `)\s+ON\s+(` + NamePattern + `)`),
+		"DROP_POLICY":   regexp.MustCompile(`(?i)^\s*DROP\s+POLICY\s+(?:IF\s+EXISTS\s+)?(` + IdentPattern + `)\s+ON\s+(` + NamePattern + `)`),
+		"DROP_TRIG":     regexp.MustCompile(`(?i)^\s*DROP\s+TRIGGER\s+(?:IF\s+EXISTS\s+)?(` + IdentPattern + `)\s+ON\s+(` + NamePattern + `)`),
+		"CREATE_EXT":    regexp.MustCompile(`(?i)^\s*CREATE\s+EXTENSION\s+(?:IF\s+NOT\s+EXISTS\s+)?(` + IdentPattern + `)`),
+		"ALTER_EXT":     regexp.MustCompile(`(?i)^\s*ALTER\s+EXTENSION\s+(` + IdentPattern + `)\s+(UPDATE|SET\s+SCHEMA)\b`),
+		"DROP_EXT":      regexp.MustCompile(`(?i)^\s*DROP\s+EXTENSION\s+(?:IF\s+EXISTS\s+)?(` + IdentPattern + `)`),
+		"CREATE_SCHEMA": regexp.MustCompile(`(?i)^\s*CREATE\s+SCHEMA\s+(?:IF\s+NOT\s+EXISTS\s+)?(?:AUTHORIZATION\s+(` + IdentPattern + `)|(` + IdentPattern + `)(?:\s+AUTHORIZATION\s+(` + IdentPattern + `))?)`),
+		"ALTER_SCHEMA":  regexp.MustCompile(`(?i)^\s*ALTER\s+SCHEMA\s+(` + IdentPattern + `)\s+RENAME\s+TO\s+(` + IdentPattern + `)`),
+		"DROP_SCHEMA":   regexp.MustCompile(`(?i)^\s*DROP\s+SCHEMA\s+(?:IF\s+EXISTS\s+)?(` + IdentPattern + `)`),
+		"GRANT":         regexp.MustCompile(`(?i)^\s*(?:GRANT|REVOKE)\b`),
+		"DEFAULT_PRIVS": regexp.MustCompile(`(?i)^\s*ALTER\s+DEFAULT\s+PRIVILEGES\b`),
+		"ALTER_OWNER":   regexp.MustCompile(`(?is)^\s*ALTER\s+(VIEW|MATERIALIZED\s+VIEW|SEQUENCE|TYPE|DOMAIN|FUNCTION|PROCEDURE|ROUTINE|SCHEMA)\s+(?:IF\s+EXISTS\s+)?(` + NamePattern + `)(?:\s*(\(.*\)))?\s+OWNER\s+TO\s+(` + IdentPattern + `)\s*$`),
+		"SEARCH_PATH":   regexp.MustCompile(`(?i)^\s*(?:SET\s+(?:SESSION\s+|LOCAL\s+)?search_path\s*(?:TO|=)\s*(.*?)|SELECT\s+(?:pg_catalog\s*\.\s*)?set_config\s*\(\s*'search_path'\s*,\s*('(?:[^']|'')*')\s*,.*)\s*$`),
+		"COMMENT_ON":    regexp.MustCompile(`(?i)^\s*COMMENT\s+ON\s+(TABLE|COLUMN|TYPE|DOMAIN|VIEW|MATERIALIZED\s+VIEW|SEQUENCE|FUNCTION|PROCEDURE|EXTENSION|SCHEMA)\s+(` + IdentPattern + `(?:\s*\.\s*` + IdentPattern + `){0,2})`),
+		"DO_BLOCK":      regexp.MustCompile(`(?i)^\s*DO\s+\$\$`),
+
+		// ALTER TABLE actions, matched against one action at a time
+		// The COLUMN keyword is optional, so constraint patterns must be tried before column ones
+		"ADD_CONSTRAINT":  regexp.MustCompile(`(?i)^ADD\s+(?:CONSTRAINT\s+(` + IdentPattern + `)\s+)?(?:PRIMARY\s+KEY|UNIQUE|FOREIGN\s+KEY|CHECK|EXCLUDE)\b`),
+		"DROP_CONSTRAINT": regexp.MustCompile(`(?i)^DROP\s+CONSTRAINT\s+(?:IF\s+EXISTS\s+)?(` + IdentPattern + `)`),
+		"ADD_COLUMN":      regexp.MustCompile(`(?i)^ADD\s+(?:COLUMN\s+)?(?:IF\s+NOT\s+EXISTS\s+)?(` + IdentPattern + `)\s+(` + typePattern + `)`),
+		"DROP_COLUMN":     regexp.MustCompile(`(?i)^DROP\s+(?:COLUMN\s+)?(?:IF\s+EXISTS\s+)?(` + IdentPattern + `)`),
+		"ALTER_COLUMN":    regexp.MustCompile(`(?i)^ALTER\s+(?:COLUMN\s+)?(` + IdentPattern + `)`),
+
+		// ALTER COLUMN sub-commands, matched against the text after the column name
+		"COL_TYPE":           regexp.MustCompile(`(?i)^(?:SET\s+DATA\s+)?TYPE\s+(` + typePattern + `)(?:\s+COLLATE\s+(` + NamePattern + `))?(?:\s+USING\s+(.+))?`),
+		"COL_SET_DEFAULT":    regexp.MustCompile(`(?i)^SET\s+DEFAULT\s+(.+)`),
+		"COL_DROP_DEFAULT":   regexp.MustCompile(`(?i)^DROP\s+DEFAULT\b`),
+		"COL_NOT_NULL":       regexp.MustCompile(`(?i)^(SET|DROP)\s+NOT\s+NULL\b`),
+		"COL_SET_EXPRESSION": regexp.MustCompile(`(?i)^SET\s+EXPRESSION\s+AS\s*\(`),
+		"COL_DROP_EXPR":      regexp.MustCompile(`(?i)^DROP\s+EXPRESSION\b`),
+		"COL_ADD_IDENTITY":   regexp.MustCompile(`(?i)^ADD\s+GENERATED\s+(ALWAYS|BY\s+DEFAULT)\s+AS\s+IDENTITY\b`),
+		"COL_DROP_IDENTITY":  regexp.MustCompile(`(?i)^DROP\s+IDENTITY\b`),
+		"COL_STATISTICS":     regexp.MustCompile(`(?i)^SET\s+STATISTICS\s+(-?\d+|DEFAULT)`),
+		"COL_STORAGE":        regexp.MustCompile(`(?i)^SET\s+STORAGE\s+(\w+)`),
+		"COL_COMPRESSION":    regexp.MustCompile(`(?i)^SET\s+COMPRESSION\s+(` + IdentPattern + `)`),
+		"COL_OPTIONS":        regexp.MustCompile(`(?i)^(SET|RESET)\s*\(`),
+		"COL_IDENTITY":       regexp.MustCompile(`(?i)^(?:SET|RESTART)\b`),
+		"COL_SET_GENERATED":  regexp.MustCompile(`(?i)^SET\s+GENERATED\s+(ALWAYS|BY\s+DEFAULT)$`),
+
+		// ALTER TABLE ... ENABLE/DISABLE TRIGGER, ROW LEVEL SECURITY, OWNER TO, ATTACH/DETACH PARTITION
+		// and storage, persistence and replica identity settings
+		"OWNER_TO":      regexp.MustCompile(`(?i)^OWNER\s+TO\s+(` + IdentPattern + `)$`),
+		"TABLE_OPTIONS": regexp.MustCompile(`(?i)^(SET|RESET)\s*\(`),
+		"PERSISTENCE":   regexp.MustCompile(`(?i)^SET\s+(LOGGED|UNLOGGED)$`),
+		"TABLESPACE":    regexp.MustCompile(`(?i)^SET\s+TABLESPACE\s+(` + IdentPattern + `)$`),
+		"ACCESS_METHOD": regexp.MustCompile(`(?i)^SET\s+ACCESS\s+METHOD\s+(` + IdentPattern + `)$`),
+		"REPLICA_IDENT": regexp.MustCompile(`(?i)^REPLICA\s+IDENTITY\s+(DEFAULT|FULL|NOTHING|USING\s+INDEX\s+(` + IdentPattern + `))$`),
+		"ATTACH_PART":   regexp.MustCompile(`(?is)^ATTACH\s+PARTITION\s+(` + NamePattern + `)\s+(FOR\s+VALUES\s+.+|DEFAULT)$`),
+		"DETACH_PART":   regexp.MustCompile(`(?i)^DETACH\s+PARTITION\s+(` + NamePattern + `)(?:\s+(?:CONCURRENTLY|FINALIZE))?$`),
+		"ROW_SECURITY":  regexp.MustCompile(`(?i)^(ENABLE|DISABLE|FORCE|NO\s+FORCE)\s+ROW\s+LEVEL\s+SECURITY$`),
+		"TRIGGER_STATE": regexp.MustCompile(`(?i)^(ENABLE|DISABLE)\s+(?:(REPLICA|ALWAYS)\s+)?TRIGGER\s+(` + IdentPattern + `)$`),
+
+		// ALTER TABLE renames, which cannot be combined with other actions
+		"RENAME_TABLE":      regexp.MustCompile(`(?i)^RENAME\s+TO\s+(` + IdentPattern + `)`),
+		"RENAME_CONSTRAINT": regexp.MustCompile(`(?i)^RENAME\s+CONSTRAINT\s+(` + IdentPattern + `)\s+TO\s+(` + IdentPattern + `)`),
+		"RENAME_COLUMN":     regexp.MustCompile(`(?i)^RENAME\s+(?:COLUMN\s+)?(` + IdentPattern + `)\s+TO\s+(` + IdentPattern + `)`),
 	}
 }
 
@@ -54,29 +133,84 @@ func (p *Parser) ParseFile(filepath string) ([]*Statement, error) {
 		return nil, fmt.Errorf("reading file: %w", err)
 	}
 
-	return p.Parse(string(content))
+	return p.parse(string(content), filepath)
 }
 
 // Parse parses SQL content and returns statements
+// Statements that fail to parse are skipped and reported together as *ParseError values
+// joined into the returned error, alongside the statements that did parse.
 func (p *Parser) Parse(sql string) ([]*Statement, error) {
-	// Strip comments
-	sql = StripComments(sql)
+	return p.parse(sql, "")
+}
 
-	// Split into statements
-	rawStatements := SplitStatements(sql)
+// Diagnostics returns the statements skipped or only partly understood so far
+func (p *Parser) Diagnostics() []Diagnostic {
+	return p.diagnostics
+}
+
+// parse parses SQL content read from file, which may be empty
+func (p *Parser) parse(sql, file string) ([]*Statement, error) {
+	// Split into statements, dropping comments
+	rawStatements := splitStatements(sql)
 
 	var statements []*Statement
+	var errs []error
 	for _, rawStmt := range rawStatements {
-		stmt, err := p.parseStatement(rawStmt)
+		pos := rawStmt.Pos
+		pos.File = file
+
+		stmt, err := p.parseStatement(rawStmt.Text)
 		if err != nil {
-			return nil, err
+			errs = append(errs, &ParseError{Pos: pos, Statement: rawStmt.Text, Err: err})
+			continue
 		}
-		if stmt != nil {
+
+		if stmt == nil {
+			stmt = &Statement{Type: Unknown, Original: strings.TrimSpace(rawStmt.Text)}
+		}
+		stmt.Pos = pos
+
+		switch {
+		case stmt.Type == Unknown && !isTransactionControl(stmt.Original):
+			p.diagnostics = append(p.diagnostics, NewDiagnostic(stmt, "unrecognized statement skipped"))
+		case stmt.Type == CreateTable && emptyTableBody(stmt.Details.(*CreateTableDetails)):
+			p.diagnostics = append(p.diagnostics, NewDiagnostic(stmt, "CREATE TABLE %s defines no columns", stmt.ObjectName))
+		case stmt.Type == CreateIndex && len(stmt.Details.(*CreateIndexDetails).Elements) == 0:
+			p.diagnostics = append(p.diagnostics, NewDiagnostic(stmt, "CREATE INDEX %s has no key columns or expressions", stmt.ObjectName))
+		case stmt.Type == AlterTable:
+			for _, action := range stmt.Details.(*AlterTableDetails).Skipped {
+				p.diagnostics = append(p.diagnostics, NewDiagnostic(stmt, "unrecognized ALTER TABLE action skipped: %s", action))
+			}
+		case stmt.Type == CreateSequence:
+			for _, option := range stmt.Details.(*CreateSequenceDetails).Skipped {
+				p.diagnostics = append(p.diagnostics, NewDiagnostic(stmt, "unrecognized CREATE SEQUENCE option skipped: %s", option))
+			}
+		case stmt.Type == AlterSequence:
+			for _, action := range stmt.Details.(*AlterSequenceDetails).Skipped {
+				p.diagnostics = append(p.diagnostics, NewDiagnostic(stmt, "unrecognized ALTER SEQUENCE action skipped: %s", action))
+			}
+		case stmt.Type == DoBlock && stmt.Details.(*DoBlockDetails).TypeName == "":
+			p.diagnostics = append(p.diagnostics, NewDiagnostic(stmt, "DO block skipped, only ALTER TYPE ... ADD VALUE is understood inside DO blocks"))
+		}
+
+		if stmt.Type != Unknown {
 			statements = append(statements, stmt)
 		}
 	}
 
-	return statements, nil
+	return statements, errors.Join(errs...)
+}
+
+// emptyTableBody reports whether a table that takes no columns from a parent was created without any definition
+func emptyTableBody(details *CreateTableDetails) bool {
+	return strings.TrimSpace(details.Definition) == "" && details.PartitionOf == "" && len(details.Inherits) == 0
+}
+
+// isTransactionControl reports whether sql is a statement like BEGIN or COMMIT,
+// which has no effect on the consolidated schema
+func isTransactionControl(sql string) bool {
+	re := regexp.MustCompile(`(?i)^\s*(?:BEGIN|COMMIT|END|START\s+TRANSACTION|ROLLBACK)\b`)
+	return re.MatchString(sql)
 }
 
 // parseStatement parses a single SQL statement
@@ -86,10 +220,6 @@ func (p *Parser) parseStatement(sql string) (*Statement, error) {
 		return nil, nil
 	}
 
-	stmt := &Statement{
-		Original: sql,
-	}
-
 	// Match statement type
 	switch {
 	case p.patterns["CREATE_TABLE"].MatchString(sql):
@@ -97,152 +227,480 @@ func (p *Parser) parseStatement(sql string) (*Statement, error) {
 	case p.patterns["ALTER_TABLE"].MatchString(sql):
 		return p.parseAlterTable(sql)
 	case p.patterns["DROP_TABLE"].MatchString(sql):
-		return p.parseDropTable(sql)
+		return p.parseDrop(sql, DropTable, "DROP_TABLE")
 	case p.patterns["CREATE_TYPE"].MatchString(sql):
 		return p.parseCreateType(sql)
 	case p.patterns["ALTER_TYPE"].MatchString(sql):
 		return p.parseAlterType(sql)
 	case p.patterns["DROP_TYPE"].MatchString(sql):
-		return p.parseDropType(sql)
+		return p.parseDrop(sql, DropType, "DROP_TYPE")
 	case p.patterns["CREATE_DOMAIN"].MatchString(sql):
 		return p.parseCreateDomain(sql)
+	case p.patterns["ALTER_DOMAIN"].MatchString(sql):
+		return p.parseAlterDomain(sql)
 	case p.patterns["DROP_DOMAIN"].MatchString(sql):
-		return p.parseDropDomain(sql)
+		return p.parseDrop(sql, DropDomain, "DROP_DOMAIN")
 	case p.patterns["CREATE_VIEW"].MatchString(sql):
 		return p.parseCreateView(sql)
 	case p.patterns["DROP_VIEW"].MatchString(sql):
-		return p.parseDropView(sql)
+		return p.parseDrop(sql, DropView, "DROP_VIEW")
+	case p.patterns["CREATE_MVIEW"].MatchString(sql):
+		return p.parseCreateMaterializedView(sql)
+	case p.patterns["DROP_MVIEW"].MatchString(sql):
+		return p.parseDrop(sql, DropMaterializedView, "DROP_MVIEW")
+	case p.patterns["REFRESH_MVIEW"].MatchString(sql):
+		return p.parseRefreshMaterializedView(sql)
 	case p.patterns["CREATE_INDEX"].MatchString(sql):
 		return p.parseCreateIndex(sql)
+	case p.patterns["ALTER_INDEX"].MatchString(sql):
+		return p.parseAlterIndex(sql)
 	case p.patterns["DROP_INDEX"].MatchString(sql):
-		return p.parseDropIndex(sql)
+		return p.parseDrop(sql, DropIndex, "DROP_INDEX")
+	case p.patterns["ALTER_OWNER"].MatchString(sql):
+		return p.parseAlterOwner(sql)
+	case p.patterns["CREATE_SEQ"].MatchString(sql):
+		return p.parseCreateSequence(sql)
+	case p.patterns["ALTER_SEQ"].MatchString(sql):
+		return p.parseAlterSequence(sql)
+	case p.patterns["DROP_SEQ"].MatchString(sql):
+		return p.parseDrop(sql, DropSequence, "DROP_SEQ")
+	case p.patterns["CREATE_FUNC"].MatchString(sql):
+		return p.parseCreateFunction(sql)
+	case p.patterns["DROP_FUNC"].MatchString(sql):
+		return p.parseDropFunction(sql)
+	case p.patterns["CREATE_TRIG"].MatchString(sql):
+		return p.parseCreateTrigger(sql)
+	case p.patterns["ALTER_TRIG"].MatchString(sql):
+		return p.parseAlterTrigger(sql)
+	case p.patterns["DROP_TRIG"].MatchString(sql):
+		return p.parseDropTrigger(sql)
+	case p.patterns["CREATE_POLICY"].MatchString(sql):
+		return p.parsePolicy(sql, CreatePolicy, "CREATE_POLICY")
+	case p.patterns["ALTER_POLICY"].MatchString(sql):
+		return p.parsePolicy(sql, AlterPolicy, "ALTER_POLICY")
+	case p.patterns["DROP_POLICY"].MatchString(sql):
+		return p.parsePolicy(sql, DropPolicy, "DROP_POLICY")
+	case p.patterns["GRANT"].MatchString(sql):
+		return p.parseGrant(sql)
+	case p.patterns["DEFAULT_PRIVS"].MatchString(sql):
+		return p.parseAlterDefaultPrivileges(sql)
+	case p.patterns["CREATE_EXT"].MatchString(sql):
+		return p.parseCreateExtension(sql)
+	case p.patterns["ALTER_EXT"].MatchString(sql):
+		return p.parseAlterExtension(sql)
+	case p.patterns["DROP_EXT"].MatchString(sql):
+		return p.parseDrop(sql, DropExtension, "DROP_EXT")
+	case p.patterns["CREATE_SCHEMA"].MatchString(sql):
+		return p.parseCreateSchema(sql)
+	case p.patterns["ALTER_SCHEMA"].MatchString(sql):
+		return p.parseAlterSchema(sql)
+	case p.patterns["DROP_SCHEMA"].MatchString(sql):
+		return p.parseDrop(sql, DropSchema, "DROP_SCHEMA")
+	case p.patterns["SEARCH_PATH"].MatchString(sql):
+		return p.parseSearchPath(sql)
 	case p.patterns["COMMENT_ON"].MatchString(sql):
 		return p.parseComment(sql)
 	case p.patterns["DO_BLOCK"].MatchString(sql):
 		return p.parseDoBlock(sql)
 	default:
-		// Unknown statement type - skip silently
+		// Unknown statement type - reported as a diagnostic by the caller
 		return nil, nil
 	}
-
-	return stmt, nil
 }
 
 func (p *Parser) parseCreateTable(sql string) (*Statement, error) {
-	matches := p.patterns["CREATE_TABLE"].FindStringSubmatch(sql)
-	if len(matches) < 2 {
+	loc := p.patterns["CREATE_TABLE"].FindStringSubmatchIndex(sql)
+	if loc == nil {
 		return nil, fmt.Errorf("invalid CREATE TABLE: %s", sql)
 	}
 
-	tableName := matches[1]
+	schema, tableName := SplitQualifiedName(sql[loc[4]:loc[5]])
+	details := &CreateTableDetails{
+		Schema:    schema,
+		TableName: tableName,
+		Unlogged:  loc[2] != -1,
+	}
 
-	// Extract table definition (everything within parentheses)
-	definition := ExtractParenthesesContent(sql)
+	// Parse the definition, partitioning, inheritance and storage clauses after the table name
+	parseTableClauses(sql[loc[1]:], details)
 
 	return &Statement{
 		Type:       CreateTable,
 		Original:   sql,
+		Schema:     schema,
 		ObjectName: tableName,
-		Details: &CreateTableDetails{
-			TableName:  tableName,
-			Definition: definition,
-		},
+		Details:    details,
 	}, nil
 }
 
 func (p *Parser) parseAlterTable(sql string) (*Statement, error) {
-	matches := p.patterns["ALTER_TABLE"].FindStringSubmatch(sql)
-	if len(matches) < 2 {
+	matches := p.patterns["ALTER_TABLE"].FindStringSubmatchIndex(sql)
+	if len(matches) < 4 {
 		return nil, fmt.Errorf("invalid ALTER TABLE: %s", sql)
 	}
 
-	tableName := matches[1]
-	operations := p.parseAlterOperations(sql)
+	schema, tableName := SplitQualifiedName(sql[matches[2]:matches[3]])
+
+	// Everything after the table name is the operations part
+	operations, skipped := p.parseAlterOperations(strings.TrimSpace(sql[matches[1]:]))
 
 	return &Statement{
 		Type:       AlterTable,
 		Original:   sql,
+		Schema:     schema,
 		ObjectName: tableName,
 		Details: &AlterTableDetails{
+			Schema:     schema,
 			TableName:  tableName,
 			Operations: operations,
+			Skipped:    skipped,
 		},
 	}, nil
 }
 
-func (p *Parser) parseAlterOperations(sql string) []AlterOperation {
+// parseAlterOperations parses the actions of an ALTER TABLE statement
+// Actions that are not recognized are returned separately, as written
+func (p *Parser) parseAlterOperations(opsText string) ([]AlterOperation, []string) {
 	var operations []AlterOperation
+	var skipped []string
 
-	// Split by ALTER TABLE tablename to get operations part
-	// Use (?s) flag to make . match newlines
-	re := regexp.MustCompile(`(?is)ALTER\s+TABLE\s+\w+\s+(.+)`)
-	matches := re.FindStringSubmatch(sql)
-	if len(matches) < 2 {
-		return operations
+	for _, action := range splitAlterActions(opsText) {
+		if op, ok := p.parseAlterAction(action); ok {
+			operations = append(operations, op)
+		} else {
+			skipped = append(skipped, action)
+		}
 	}
 
-	opsText := matches[1]
+	return operations, skipped
+}
 
-	// Try to match ALTER COLUMN TYPE
-	if matches := p.patterns["ALTER_COL_TYPE"].FindStringSubmatch(opsText); len(matches) >= 3 {
-		operations = append(operations, AlterOperation{
-			Type:       AlterColumn,
-			ColumnName: matches[1],
-			DataType:   matches[2],
-			Details:    opsText,
-		})
-		return operations
+// parseAlterAction parses a single ALTER TABLE action
+// Returns false for actions that are not tracked
+func (p *Parser) parseAlterAction(action string) (AlterOperation, bool) {
+	// Renames are the only action in their statement
+	if matches := p.patterns["RENAME_TABLE"].FindStringSubmatch(action); len(matches) >= 2 {
+		return AlterOperation{
+			Type:    RenameTable,
+			NewName: NormalizeIdentifier(matches[1]),
+			Details: action,
+		}, true
+	}
+	if matches := p.patterns["RENAME_CONSTRAINT"].FindStringSubmatch(action); len(matches) >= 3 {
+		return AlterOperation{
+			Type:           RenameConstraint,
+			ConstraintName: NormalizeIdentifier(matches[1]),
+			NewName:        NormalizeIdentifier(matches[2]),
+			Details:        action,
+		}, true
+	}
+	if matches := p.patterns["RENAME_COLUMN"].FindStringSubmatch(action); len(matches) >= 3 {
+		return AlterOperation{
+			Type:       RenameColumn,
+			ColumnName: NormalizeIdentifier(matches[1]),
+			NewName:    NormalizeIdentifier(matches[2]),
+			Details:    action,
+		}, true
+	}
+
+	// ENABLE [REPLICA|ALWAYS] TRIGGER name, DISABLE TRIGGER name
+	if matches := p.patterns["TRIGGER_STATE"].FindStringSubmatch(action); len(matches) >= 4 {
+		op := AlterOperation{
+			Type:        SetTriggerEnabled,
+			TriggerName: NormalizeIdentifier(matches[3]),
+			Details:     action,
+		}
+		if name := strings.ToUpper(matches[3]); name == "ALL" || name == "USER" {
+			op.TriggerName = name
+		}
+		switch {
+		case strings.EqualFold(matches[1], "DISABLE"):
+			op.Value = "DISABLE"
+		case matches[2] != "":
+			op.Value = "ENABLE " + strings.ToUpper(matches[2])
+		}
+		return op, true
 	}
 
-	// Try to match ALTER COLUMN SET/DROP NOT NULL
-	if matches := p.patterns["ALTER_COL_NULL"].FindStringSubmatch(opsText); len(matches) >= 3 {
-		operations = append(operations, AlterOperation{
+	// ENABLE, DISABLE, FORCE or NO FORCE ROW LEVEL SECURITY
+	if matches := p.patterns["ROW_SECURITY"].FindStringSubmatch(action); len(matches) >= 2 {
+		return AlterOperation{
+			Type:    SetRowSecurity,
+			Value:   strings.ToUpper(NormalizeWhitespace(matches[1])),
+			Details: action,
+		}, true
+	}
+
+	// OWNER TO role
+	if matches := p.patterns["OWNER_TO"].FindStringSubmatch(action); len(matches) >= 2 {
+		return AlterOperation{
+			Type:    SetOwner,
+			Value:   NormalizeRole(matches[1]),
+			Details: action,
+		}, true
+	}
+
+	// SET (parameter = value, ...), RESET (parameter, ...)
+	if matches := p.patterns["TABLE_OPTIONS"].FindStringSubmatch(action); len(matches) >= 2 {
+		op := AlterOperation{Type: SetStorageParameters, Details: action}
+		if strings.EqualFold(matches[1], "RESET") {
+			op.Type = ResetStorageParameters
+		}
+		op.Options = SplitStorageParameters(ExtractParenthesesContent(action))
+		return op, true
+	}
+
+	// SET LOGGED, SET UNLOGGED
+	if matches := p.patterns["PERSISTENCE"].FindStringSubmatch(action); len(matches) >= 2 {
+		return AlterOperation{
+			Type:    SetPersistence,
+			Value:   strings.ToUpper(matches[1]),
+			Details: action,
+		}, true
+	}
+
+	// SET TABLESPACE name, SET ACCESS METHOD name
+	if matches := p.patterns["TABLESPACE"].FindStringSubmatch(action); len(matches) >= 2 {
+		return AlterOperation{
+			Type:    SetTablespace,
+			Value:   NormalizeIdentifier(matches[1]),
+			Details: action,
+		}, true
+	}
+	if matches := p.patterns["ACCESS_METHOD"].FindStringSubmatch(action); len(matches) >= 2 {
+		op := AlterOperation{
+			Type:    SetAccessMethod,
+			Value:   NormalizeIdentifier(matches[1]),
+			Details: action,
+		}
+		if strings.EqualFold(matches[1], "DEFAULT") {
+			op.Value = ""
+		}
+		return op, true
+	}
+
+	// REPLICA IDENTITY DEFAULT, FULL, NOTHING or USING INDEX name
+	if matches := p.patterns["REPLICA_IDENT"].FindStringSubmatch(action); len(matches) >= 3 {
+		op := AlterOperation{
+			Type:    SetReplicaIdentity,
+			Value:   strings.ToUpper(matches[1]),
+			Details: action,
+		}
+		if matches[2] != "" {
+			op.Value = "INDEX"
+			op.ConstraintName = NormalizeIdentifier(matches[2])
+		}
+		return op, true
+	}
+
+	// ATTACH PARTITION name { FOR VALUES bound | DEFAULT }, DETACH PARTITION name
+	if matches := p.patterns["ATTACH_PART"].FindStringSubmatch(action); len(matches) >= 3 {
+		return AlterOperation{
+			Type:      AttachPartition,
+			Partition: matches[1],
+			Value:     NormalizeWhitespace(matches[2]),
+			Details:   action,
+		}, true
+	}
+	if matches := p.patterns["DETACH_PART"].FindStringSubmatch(action); len(matches) >= 2 {
+		return AlterOperation{
+			Type:      DetachPartition,
+			Partition: matches[1],
+			Details:   action,
+		}, true
+	}
+
+	// ADD [CONSTRAINT name] PRIMARY KEY/UNIQUE/FOREIGN KEY/CHECK
+	// Details holds the constraint definition without the leading ADD
+	if matches := p.patterns["ADD_CONSTRAINT"].FindStringSubmatch(action); len(matches) >= 2 {
+		return AlterOperation{
+			Type:           AddConstraint,
+			ConstraintName: NormalizeIdentifier(matches[1]),
+			Details:        strings.TrimSpace(action[len("ADD"):]),
+		}, true
+	}
+
+	if matches := p.patterns["DROP_CONSTRAINT"].FindStringSubmatch(action); len(matches) >= 2 {
+		return AlterOperation{
+			Type:           DropConstraint,
+			ConstraintName: NormalizeIdentifier(matches[1]),
+			Details:        action,
+			Cascade:        isCascade(action),
+		}, true
+	}
+
+	// ADD [COLUMN] [IF NOT EXISTS]
+	// Details holds the column definition, starting with the column name
+	if loc := p.patterns["ADD_COLUMN"].FindStringSubmatchIndex(action); loc != nil {
+		return AlterOperation{
+			Type:       AddColumn,
+			ColumnName: NormalizeIdentifier(action[loc[2]:loc[3]]),
+			DataType:   action[loc[4]:loc[5]],
+			Details:    action[loc[2]:],
+		}, true
+	}
+
+	if matches := p.patterns["DROP_COLUMN"].FindStringSubmatch(action); len(matches) >= 2 {
+		return AlterOperation{
+			Type:       DropColumn,
+			ColumnName: NormalizeIdentifier(matches[1]),
+			Details:    action,
+			Cascade:    isCascade(action),
+		}, true
+	}
+
+	// ALTER CONSTRAINT changes constraint attributes, which are not tracked
+	if loc := p.patterns["ALTER_COLUMN"].FindStringSubmatchIndex(action); loc != nil && !strings.EqualFold(action[loc[2]:loc[3]], "CONSTRAINT") {
+		op := AlterOperation{
 			Type:       AlterColumn,
-			ColumnName: matches[1],
-			Details:    opsText,
-		})
-		return operations
+			ColumnName: NormalizeIdentifier(action[loc[2]:loc[3]]),
+			Details:    action,
+		}
+		if p.parseAlterColumnAction(&op, strings.TrimSpace(action[loc[1]:])) {
+			return op, true
+		}
+	}
+
+	return AlterOperation{}, false
+}
+
+// parseAlterColumnAction parses the sub-command of an ALTER COLUMN action into op
+// Returns false for sub-commands that are not recognized
+func (p *Parser) parseAlterColumnAction(op *AlterOperation, text string) bool {
+	if matches := p.patterns["COL_TYPE"].FindStringSubmatch(text); len(matches) >= 2 {
+		op.ColumnAction = SetDataType
+		op.DataType = matches[1]
+		op.Value = matches[2]
+		return true
+	}
+
+	if matches := p.patterns["COL_SET_DEFAULT"].FindStringSubmatch(text); len(matches) >= 2 {
+		op.ColumnAction = SetDefault
+		op.Value = strings.TrimSpace(matches[1])
+		return true
+	}
+
+	if p.patterns["COL_DROP_DEFAULT"].MatchString(text) {
+		op.ColumnAction = DropDefault
+		return true
 	}
 
-	// Match ADD COLUMN (can be multiple)
-	addMatches := p.patterns["ADD_COLUMN"].FindAllStringSubmatch(opsText, -1)
-	for _, match := range addMatches {
-		if len(match) >= 3 {
-			operations = append(operations, AlterOperation{
-				Type:       AddColumn,
-				ColumnName: match[1],
-				DataType:   match[2],
-				Details:    strings.TrimSpace(match[0]),
-			})
+	if matches := p.patterns["COL_NOT_NULL"].FindStringSubmatch(text); len(matches) >= 2 {
+		op.ColumnAction = SetNotNull
+		if strings.EqualFold(matches[1], "DROP") {
+			op.ColumnAction = DropNotNull
 		}
+		return true
 	}
 
-	// Match DROP COLUMN (can be multiple)
-	dropMatches := p.patterns["DROP_COLUMN"].FindAllStringSubmatch(opsText, -1)
-	for _, match := range dropMatches {
-		if len(match) >= 2 {
-			operations = append(operations, AlterOperation{
-				Type:       DropColumn,
-				ColumnName: match[1],
-				Details:    strings.TrimSpace(match[0]),
-			})
+	if loc := p.patterns["COL_SET_EXPRESSION"].FindStringIndex(text); loc != nil {
+		op.ColumnAction = SetExpression
+		op.Value = strings.TrimSpace(ExtractParenthesesContent(text[loc[1]-1:]))
+		return true
+	}
+
+	if p.patterns["COL_DROP_EXPR"].MatchString(text) {
+		op.ColumnAction = DropExpression
+		return true
+	}
+
+	if loc := p.patterns["COL_ADD_IDENTITY"].FindStringSubmatchIndex(text); loc != nil {
+		op.ColumnAction = AddIdentity
+		op.Value = strings.ToUpper(strings.Join(strings.Fields(text[loc[2]:loc[3]]), " "))
+		if rest := strings.TrimSpace(text[loc[1]:]); strings.HasPrefix(rest, "(") {
+			op.Options = SplitSequenceOptions(ExtractParenthesesContent(rest))
 		}
+		return true
 	}
 
-	return operations
+	if p.patterns["COL_DROP_IDENTITY"].MatchString(text) {
+		op.ColumnAction = DropIdentity
+		return true
+	}
+
+	if matches := p.patterns["COL_STATISTICS"].FindStringSubmatch(text); len(matches) >= 2 {
+		op.ColumnAction = SetStatistics
+		op.Value = matches[1]
+		return true
+	}
+
+	if matches := p.patterns["COL_STORAGE"].FindStringSubmatch(text); len(matches) >= 2 {
+		op.ColumnAction = SetStorage
+		op.Value = strings.ToUpper(matches[1])
+		return true
+	}
+
+	if matches := p.patterns["COL_COMPRESSION"].FindStringSubmatch(text); len(matches) >= 2 {
+		op.ColumnAction = SetCompression
+		op.Value = NormalizeIdentifier(matches[1])
+		return true
+	}
+
+	if matches := p.patterns["COL_OPTIONS"].FindStringSubmatch(text); len(matches) >= 2 {
+		op.ColumnAction = SetAttributeOptions
+		if strings.EqualFold(matches[1], "RESET") {
+			op.ColumnAction = ResetAttributeOptions
+		}
+		op.Options = SplitStorageParameters(ExtractParenthesesContent(text))
+		return true
+	}
+
+	// Identity alterations: SET GENERATED, SET sequence_option and RESTART, in any combination
+	if p.patterns["COL_IDENTITY"].MatchString(text) {
+		op.ColumnAction = SetIdentity
+		for _, clause := range splitIdentityClauses(text) {
+			if matches := p.patterns["COL_SET_GENERATED"].FindStringSubmatch(clause); len(matches) >= 2 {
+				op.Value = strings.ToUpper(strings.Join(strings.Fields(matches[1]), " "))
+			} else if strings.HasPrefix(strings.ToUpper(clause), "SET ") {
+				op.Options = append(op.Options, SplitSequenceOptions(clause[len("SET "):])...)
+			}
+		}
+		return true
+	}
+
+	return false
 }
 
-func (p *Parser) parseDropTable(sql string) (*Statement, error) {
-	matches := p.patterns["DROP_TABLE"].FindStringSubmatch(sql)
-	if len(matches) < 2 {
-		return nil, fmt.Errorf("invalid DROP TABLE: %s", sql)
+// splitIdentityClauses splits a chain of identity alterations at each SET or RESTART keyword
+func splitIdentityClauses(text string) []string {
+	var clauses []string
+	var current []string
+
+	for _, word := range strings.Fields(text) {
+		upper := strings.ToUpper(word)
+		if (upper == "SET" || upper == "RESTART") && len(current) > 0 {
+			clauses = append(clauses, strings.Join(current, " "))
+			current = nil
+		}
+		current = append(current, word)
+	}
+	if len(current) > 0 {
+		clauses = append(clauses, strings.Join(current, " "))
 	}
 
-	return &Statement{
-		Type:       DropTable,
-		Original:   sql,
-		ObjectName: matches[1],
-	}, nil
+	return clauses
+}
+
+// firstLiteral returns the value of the first string constant in sql
+func firstLiteral(sql string) (string, bool) {
+	for _, tok := range Tokenize(sql) {
+		if tok.IsLiteral() {
+			return UnquoteString(tok.Text), true
+		}
+	}
+	return "", false
+}
+
+// splitAlterActions splits the body of an ALTER TABLE statement into its actions
+// Actions are separated by commas outside parentheses and quotes
+func splitAlterActions(opsText string) []string {
+	var actions []string
+	for _, action := range SplitTopLevel(opsText, ",") {
+		if action = strings.TrimSpace(action); action != "" {
+			actions = append(actions, action)
+		}
+	}
+	return actions
 }
 
 func (p *Parser) parseCreateType(sql string) (*Statement, error) {
@@ -251,51 +709,116 @@ func (p *Parser) parseCreateType(sql string) (*Statement, error) {
 		return nil, fmt.Errorf("invalid CREATE TYPE: %s", sql)
 	}
 
-	typeName := matches[1]
+	schema, typeName := SplitQualifiedName(matches[1])
+	details := &CreateTypeDetails{
+		Schema:   schema,
+		TypeName: typeName,
+	}
 
-	// Extract enum values
 	content := ExtractParenthesesContent(sql)
-	values := p.parseEnumValues(content)
+	switch strings.ToUpper(matches[2]) {
+	case "ENUM":
+		details.Kind = "ENUM"
+		details.Values = p.parseEnumValues(content)
+	case "RANGE":
+		details.Kind = "RANGE"
+		for _, option := range SplitTopLevel(content, ",") {
+			if option = NormalizeWhitespace(option); option != "" {
+				details.RangeOptions = append(details.RangeOptions, option)
+			}
+		}
+	default:
+		details.Kind = "COMPOSITE"
+		for _, def := range SplitTopLevel(content, ",") {
+			if strings.TrimSpace(def) == "" {
+				continue
+			}
+			attr, err := parseTypeAttribute(def)
+			if err != nil {
+				return nil, err
+			}
+			details.Attributes = append(details.Attributes, attr)
+		}
+	}
 
 	return &Statement{
 		Type:       CreateType,
 		Original:   sql,
+		Schema:     schema,
 		ObjectName: typeName,
-		Details: &CreateTypeDetails{
-			TypeName: typeName,
-			Values:   values,
-		},
+		Details:    details,
 	}, nil
 }
 
-func (p *Parser) parseEnumValues(content string) []string {
-	var values []string
+// collateRe matches a COLLATE clause at the start of the text
+var collateRe = regexp.MustCompile(`(?i)^\s*COLLATE\s+(` + NamePattern + `)`)
 
-	// Split by comma, handling quotes
-	var current strings.Builder
-	var inQuote bool
+// readTypeAndCollation reads a data type and an optional COLLATE clause
+func readTypeAndCollation(text string) (dataType, collation, rest string) {
+	dataType, rest = ReadDataType(text)
+	if matches := collateRe.FindStringSubmatch(rest); matches != nil {
+		collation = matches[1]
+		rest = rest[len(matches[0]):]
+	}
+	return NormalizeWhitespace(dataType), collation, rest
+}
 
-	for _, ch := range content {
-		if ch == '\'' {
-			inQuote = !inQuote
-			continue
-		}
+// parseTypeAttribute parses a composite type attribute, e.g. "city text COLLATE "C""
+func parseTypeAttribute(def string) (TypeAttribute, error) {
+	name, rest := ReadIdentifier(def)
+	dataType, collation, _ := readTypeAndCollation(rest)
+	if name == "" || dataType == "" {
+		return TypeAttribute{}, fmt.Errorf("could not parse type attribute: %s", strings.TrimSpace(def))
+	}
+	return TypeAttribute{Name: name, DataType: dataType, Collation: collation}, nil
+}
 
-		if ch == ',' && !inQuote {
-			val := strings.TrimSpace(current.String())
-			if val != "" {
-				values = append(values, val)
-			}
-			current.Reset()
-		} else {
-			current.WriteRune(ch)
+// attributeActionRe matches the start of an attribute change in ALTER TYPE
+var attributeActionRe = regexp.MustCompile(`(?i)^\s*(ADD|DROP|ALTER|RENAME)\s+ATTRIBUTE\s+(?:IF\s+EXISTS\s+)?`)
+
+// parseAttributeOperation parses one ADD, DROP, ALTER or RENAME ATTRIBUTE change
+func parseAttributeOperation(text string) (AttributeOperation, error) {
+	loc := attributeActionRe.FindStringSubmatchIndex(text)
+	if loc == nil {
+		return AttributeOperation{}, fmt.Errorf("unsupported ALTER TYPE action: %s", strings.TrimSpace(text))
+	}
+
+	name, rest := ReadIdentifier(text[loc[1]:])
+	op := AttributeOperation{Name: name}
+
+	switch strings.ToUpper(text[loc[2]:loc[3]]) {
+	case "ADD":
+		op.Action = AddAttribute
+		op.DataType, op.Collation, _ = readTypeAndCollation(rest)
+	case "DROP":
+		op.Action = DropAttribute
+	case "ALTER":
+		op.Action = SetAttributeType
+		typeRe := regexp.MustCompile(`(?i)^\s*(?:SET\s+DATA\s+)?TYPE\s+`)
+		if m := typeRe.FindString(rest); m != "" {
+			op.DataType, op.Collation, _ = readTypeAndCollation(rest[len(m):])
+		}
+	case "RENAME":
+		op.Action = RenameAttribute
+		if m := regexp.MustCompile(`(?i)^\s*TO\s+(` + IdentPattern + `)`).FindStringSubmatch(rest); m != nil {
+			op.NewName = NormalizeIdentifier(m[1])
 		}
 	}
 
-	// Add last value
-	val := strings.TrimSpace(current.String())
-	if val != "" {
-		values = append(values, val)
+	if name == "" || (op.Action != DropAttribute && op.DataType == "" && op.NewName == "") {
+		return AttributeOperation{}, fmt.Errorf("could not parse ALTER TYPE action: %s", strings.TrimSpace(text))
+	}
+	return op, nil
+}
+
+func (p *Parser) parseEnumValues(content string) []string {
+	var values []string
+
+	// Every string constant in the list is a value
+	for _, tok := range Tokenize(content) {
+		if tok.IsLiteral() {
+			values = append(values, UnquoteString(tok.Text))
+		}
 	}
 
 	return values
@@ -307,38 +830,79 @@ func (p *Parser) parseAlterType(sql string) (*Statement, error) {
 		return nil, fmt.Errorf("invalid ALTER TYPE: %s", sql)
 	}
 
-	typeName := matches[1]
+	schema, typeName := SplitQualifiedName(matches[1])
+	details := &AlterTypeDetails{
+		Schema:   schema,
+		TypeName: typeName,
+	}
 
-	// Extract the new value
-	re := regexp.MustCompile(`(?i)ADD\s+VALUE\s+(?:IF\s+NOT\s+EXISTS\s+)?'([^']+)'`)
-	valueMatches := re.FindStringSubmatch(sql)
-	var newValue string
-	if len(valueMatches) >= 2 {
-		newValue = valueMatches[1]
+	loc := p.patterns["ALTER_TYPE"].FindStringSubmatchIndex(sql)
+	action := strings.ToUpper(NormalizeWhitespace(matches[2]))
+
+	switch {
+	case strings.HasSuffix(action, "ATTRIBUTE"):
+		// Attribute changes can be combined in one statement, separated by commas
+		for _, action := range SplitTopLevel(sql[loc[4]:], ",") {
+			op, err := parseAttributeOperation(action)
+			if err != nil {
+				return nil, err
+			}
+			details.Attributes = append(details.Attributes, op)
+		}
+	case action == "RENAME TO":
+		newName, _ := ReadIdentifier(sql[loc[1]:])
+		if newName == "" {
+			return nil, fmt.Errorf("invalid ALTER TYPE ... RENAME TO: %s", sql)
+		}
+		details.NewName = newName
+	case action == "RENAME VALUE":
+		// RENAME VALUE 'old' TO 'new'
+		values := literalValues(sql[loc[1]:])
+		if len(values) < 2 {
+			return nil, fmt.Errorf("invalid ALTER TYPE ... RENAME VALUE: %s", sql)
+		}
+		details.OldValue, details.NewValue = values[0], values[1]
+	default:
+		details.NewValue, details.Before, details.After = parseEnumValuePlacement(sql[loc[1]:])
 	}
 
 	return &Statement{
 		Type:       AlterType,
 		Original:   sql,
+		Schema:     schema,
 		ObjectName: typeName,
-		Details: &AlterTypeDetails{
-			TypeName: typeName,
-			NewValue: newValue,
-		},
+		Details:    details,
 	}, nil
 }
 
-func (p *Parser) parseDropType(sql string) (*Statement, error) {
-	matches := p.patterns["DROP_TYPE"].FindStringSubmatch(sql)
-	if len(matches) < 2 {
-		return nil, fmt.Errorf("invalid DROP TYPE: %s", sql)
+// literalValues returns the values of all string constants in the text, in order
+func literalValues(text string) []string {
+	var values []string
+	for _, tok := range Tokenize(text) {
+		if tok.IsLiteral() {
+			values = append(values, UnquoteString(tok.Text))
+		}
 	}
+	return values
+}
 
-	return &Statement{
-		Type:       DropType,
-		Original:   sql,
-		ObjectName: matches[1],
-	}, nil
+// parseEnumValuePlacement reads the value of ADD VALUE and the value it is placed BEFORE or AFTER, if any
+// e.g. "IF NOT EXISTS 'b' AFTER 'a'" returns b, "", a
+func parseEnumValuePlacement(text string) (value, before, after string) {
+	placement := ""
+	for _, tok := range Tokenize(text) {
+		switch {
+		case tok.IsKeyword("BEFORE") || tok.IsKeyword("AFTER"):
+			placement = strings.ToUpper(tok.Text)
+		case tok.IsLiteral() && value == "":
+			value = UnquoteString(tok.Text)
+		case tok.IsLiteral() && placement == "BEFORE":
+			before = UnquoteString(tok.Text)
+		case tok.IsLiteral() && placement == "AFTER":
+			after = UnquoteString(tok.Text)
+		}
+	}
+	return value, before, after
 }
 
 func (p *Parser) parseCreateDomain(sql string) (*Statement, error) {
@@ -347,55 +911,106 @@ func (p *Parser) parseCreateDomain(sql string) (*Statement, error) {
 		return nil, fmt.Errorf("invalid CREATE DOMAIN: %s", sql)
 	}
 
-	domainName := matches[1]
+	schema, domainName := SplitQualifiedName(matches[1])
+	loc := p.patterns["CREATE_DOMAIN"].FindStringIndex(sql)
 
-	// Extract base type (handles multi-word types like "character varying(3)")
-	typeRe := regexp.MustCompile(`(?i)AS\s+(.+?)(?:\s+DEFAULT|\s+CHECK|\s+NOT\s+NULL|\s+NULL|\s+CONSTRAINT|\s*;|\s*$)`)
-	typeMatches := typeRe.FindStringSubmatch(sql)
-	var baseType string
-	if len(typeMatches) >= 2 {
-		baseType = strings.TrimSpace(typeMatches[1])
+	// The base type (handles multi-word types like "character varying(3)") is followed by the clauses
+	baseType, rest := ReadDataType(sql[loc[1]:])
+	if baseType == "" {
+		return nil, fmt.Errorf("invalid CREATE DOMAIN, no base type: %s", sql)
 	}
 
-	// Extract DEFAULT if present
-	defaultRe := regexp.MustCompile(`(?i)DEFAULT\s+([^\s]+(?:\([^)]+\))?)`)
-	defaultMatches := defaultRe.FindStringSubmatch(sql)
-	var defaultVal string
-	if len(defaultMatches) >= 2 {
-		defaultVal = defaultMatches[1]
+	details := &CreateDomainDetails{
+		Schema:     schema,
+		DomainName: domainName,
+		BaseType:   NormalizeWhitespace(baseType),
 	}
-
-	// Extract CHECK constraint if present
-	checkRe := regexp.MustCompile(`(?i)CHECK\s*\(([^)]+)\)`)
-	checkMatches := checkRe.FindStringSubmatch(sql)
-	var constraint string
-	if len(checkMatches) >= 2 {
-		constraint = checkMatches[1]
+	if err := parseDomainClauses(rest, details); err != nil {
+		return nil, err
 	}
 
 	return &Statement{
 		Type:       CreateDomain,
 		Original:   sql,
+		Schema:     schema,
 		ObjectName: domainName,
-		Details: &CreateDomainDetails{
-			DomainName: domainName,
-			BaseType:   baseType,
-			Default:    defaultVal,
-			Constraint: constraint,
-		},
+		Details:    details,
 	}, nil
 }
 
-func (p *Parser) parseDropDomain(sql string) (*Statement, error) {
-	matches := p.patterns["DROP_DOMAIN"].FindStringSubmatch(sql)
-	if len(matches) < 2 {
-		return nil, fmt.Errorf("invalid DROP DOMAIN: %s", sql)
+func (p *Parser) parseAlterDomain(sql string) (*Statement, error) {
+	loc := p.patterns["ALTER_DOMAIN"].FindStringSubmatchIndex(sql)
+	if loc == nil {
+		return nil, fmt.Errorf("invalid ALTER DOMAIN: %s", sql)
+	}
+
+	schema, domainName := SplitQualifiedName(sql[loc[2]:loc[3]])
+	rest := strings.TrimSpace(sql[loc[1]:])
+	details := &AlterDomainDetails{
+		Schema:     schema,
+		DomainName: domainName,
+	}
+
+	// Constraint names may be preceded by IF EXISTS and followed by CASCADE or RESTRICT
+	ifExists := regexp.MustCompile(`(?i)^IF\s+EXISTS\s+`)
+	readName := func(text string) (string, string) {
+		return ReadIdentifier(ifExists.ReplaceAllString(text, ""))
+	}
+
+	switch strings.ToUpper(NormalizeWhitespace(sql[loc[4]:loc[5]])) {
+	case "SET DEFAULT":
+		details.Action = SetDomainDefault
+		details.Default = rest
+	case "DROP DEFAULT":
+		details.Action = DropDomainDefault
+	case "SET NOT NULL":
+		details.Action = SetDomainNotNull
+	case "DROP NOT NULL":
+		details.Action = DropDomainNotNull
+	case "ADD":
+		constraint, kind, err := parseDomainConstraint(rest)
+		if err != nil {
+			return nil, err
+		}
+		// ADD [CONSTRAINT name] NOT NULL is the same as SET NOT NULL
+		switch kind {
+		case "NOT NULL":
+			details.Action = SetDomainNotNull
+		case "NULL":
+			details.Action = DropDomainNotNull
+		default:
+			details.Action = AddDomainConstraint
+			details.Constraint = constraint
+		}
+	case "DROP CONSTRAINT":
+		details.Action = DropDomainConstraint
+		details.ConstraintName, _ = readName(rest)
+	case "VALIDATE CONSTRAINT":
+		details.Action = ValidateDomainConstraint
+		details.ConstraintName, _ = readName(rest)
+	case "RENAME CONSTRAINT":
+		details.Action = RenameDomainConstraint
+		var after string
+		details.ConstraintName, after = readName(rest)
+		if m := regexp.MustCompile(`(?i)^\s*TO\s+(` + IdentPattern + `)`).FindStringSubmatch(after); m != nil {
+			details.NewName = NormalizeIdentifier(m[1])
+		}
+	case "RENAME TO":
+		details.Action = RenameDomain
+		details.NewName, _ = ReadIdentifier(rest)
+	}
+
+	if details.Action == RenameDomainConstraint && details.NewName == "" ||
+		details.Action == RenameDomain && details.NewName == "" {
+		return nil, fmt.Errorf("invalid ALTER DOMAIN ... RENAME: %s", sql)
 	}
 
 	return &Statement{
-		Type:       DropDomain,
+		Type:       AlterDomain,
 		Original:   sql,
-		ObjectName: matches[1],
+		Schema:     schema,
+		ObjectName: domainName,
+		Details:    details,
 	}, nil
 }
 
@@ -405,117 +1020,539 @@ func (p *Parser) parseCreateView(sql string) (*Statement, error) {
 		return nil, fmt.Errorf("invalid CREATE VIEW: %s", sql)
 	}
 
-	viewName := matches[1]
+	schema, viewName := SplitQualifiedName(matches[1])
 
 	return &Statement{
 		Type:       CreateView,
 		Original:   sql,
+		Schema:     schema,
 		ObjectName: viewName,
 		Details: &CreateViewDetails{
+			Schema:     schema,
 			ViewName:   viewName,
 			Definition: sql,
 		},
 	}, nil
 }
 
-func (p *Parser) parseDropView(sql string) (*Statement, error) {
-	matches := p.patterns["DROP_VIEW"].FindStringSubmatch(sql)
+// withDataRe matches the trailing WITH [NO] DATA clause of CREATE MATERIALIZED VIEW
+var withDataRe = regexp.MustCompile(`(?i)\s+WITH\s+(NO\s+)?DATA\s*$`)
+
+func (p *Parser) parseCreateMaterializedView(sql string) (*Statement, error) {
+	matches := p.patterns["CREATE_MVIEW"].FindStringSubmatch(sql)
+	if len(matches) < 2 {
+		return nil, fmt.Errorf("invalid CREATE MATERIALIZED VIEW: %s", sql)
+	}
+
+	schema, viewName := SplitQualifiedName(matches[1])
+
+	// WITH [NO] DATA is kept apart so the definition is always the query alone
+	definition, withData := sql, true
+	if loc := withDataRe.FindStringSubmatchIndex(MaskLiterals(sql)); loc != nil {
+		definition = strings.TrimSpace(sql[:loc[0]])
+		withData = loc[2] == -1
+	}
+
+	return &Statement{
+		Type:       CreateMaterializedView,
+		Original:   sql,
+		Schema:     schema,
+		ObjectName: viewName,
+		Details: &CreateMaterializedViewDetails{
+			Schema:     schema,
+			ViewName:   viewName,
+			Definition: definition,
+			WithData:   withData,
+		},
+	}, nil
+}
+
+func (p *Parser) parseRefreshMaterializedView(sql string) (*Statement, error) {
+	matches := p.patterns["REFRESH_MVIEW"].FindStringSubmatch(sql)
 	if len(matches) < 2 {
-		return nil, fmt.Errorf("invalid DROP VIEW: %s", sql)
+		return nil, fmt.Errorf("invalid REFRESH MATERIALIZED VIEW: %s", sql)
 	}
 
+	schema, name := SplitQualifiedName(matches[1])
+
 	return &Statement{
-		Type:       DropView,
+		Type:       RefreshMaterializedView,
 		Original:   sql,
-		ObjectName: matches[1],
+		Schema:     schema,
+		ObjectName: name,
 	}, nil
 }
 
 func (p *Parser) parseCreateIndex(sql string) (*Statement, error) {
-	matches := p.patterns["CREATE_INDEX"].FindStringSubmatch(sql)
-	if len(matches) < 3 {
+	loc := p.patterns["CREATE_INDEX"].FindStringSubmatchIndex(sql)
+	if loc == nil {
 		return nil, fmt.Errorf("invalid CREATE INDEX: %s", sql)
 	}
 
-	indexName := matches[1]
-	tableName := matches[2]
+	details := &CreateIndexDetails{Unique: loc[2] != -1}
+	if loc[4] != -1 {
+		details.IndexName = NormalizeIdentifier(sql[loc[4]:loc[5]])
+	}
+	details.TableSchema, details.TableName = SplitQualifiedName(sql[loc[6]:loc[7]])
+
+	// Parse the method, elements and other clauses after the table name
+	parseIndexClauses(sql[loc[1]:], details)
+
+	return &Statement{
+		Type:       CreateIndex,
+		Original:   sql,
+		ObjectName: details.IndexName,
+		Details:    details,
+	}, nil
+}
+
+func (p *Parser) parseAlterIndex(sql string) (*Statement, error) {
+	matches := p.patterns["ALTER_INDEX"].FindStringSubmatch(sql)
+	if len(matches) < 3 {
+		return nil, fmt.Errorf("invalid ALTER INDEX: %s", sql)
+	}
+
+	schema, name := SplitQualifiedName(matches[1])
+	newName := NormalizeIdentifier(matches[2])
+
+	return &Statement{
+		Type:       AlterIndex,
+		Original:   sql,
+		Schema:     schema,
+		ObjectName: name,
+		Details: &AlterIndexDetails{
+			Schema:    schema,
+			IndexName: name,
+			NewName:   newName,
+		},
+	}, nil
+}
+
+func (p *Parser) parseCreateSequence(sql string) (*Statement, error) {
+	loc := p.patterns["CREATE_SEQ"].FindStringSubmatchIndex(sql)
+	if loc == nil {
+		return nil, fmt.Errorf("invalid CREATE SEQUENCE: %s", sql)
+	}
+
+	schema, name := SplitQualifiedName(sql[loc[2]:loc[3]])
+	options, ownedBy, skipped := parseSequenceOptions(sql[loc[1]:])
+
+	return &Statement{
+		Type:       CreateSequence,
+		Original:   sql,
+		Schema:     schema,
+		ObjectName: name,
+		Details: &CreateSequenceDetails{
+			Schema:       schema,
+			SequenceName: name,
+			Options:      options,
+			OwnedBy:      ownedBy,
+			Skipped:      skipped,
+		},
+	}, nil
+}
+
+func (p *Parser) parseAlterSequence(sql string) (*Statement, error) {
+	loc := p.patterns["ALTER_SEQ"].FindStringSubmatchIndex(sql)
+	if loc == nil {
+		return nil, fmt.Errorf("invalid ALTER SEQUENCE: %s", sql)
+	}
+
+	schema, name := SplitQualifiedName(sql[loc[2]:loc[3]])
+	details := &AlterSequenceDetails{
+		Schema:       schema,
+		SequenceName: name,
+	}
+
+	rest := strings.TrimSpace(sql[loc[1]:])
+	if matches := p.patterns["RENAME_TABLE"].FindStringSubmatch(rest); len(matches) >= 2 {
+		details.NewName = NormalizeIdentifier(matches[1])
+	} else {
+		details.Options, details.OwnedBy, details.Skipped = parseSequenceOptions(rest)
+	}
+
+	return &Statement{
+		Type:       AlterSequence,
+		Original:   sql,
+		Schema:     schema,
+		ObjectName: name,
+		Details:    details,
+	}, nil
+}
+
+func (p *Parser) parseCreateFunction(sql string) (*Statement, error) {
+	loc := p.patterns["CREATE_FUNC"].FindStringSubmatchIndex(sql)
+	if loc == nil {
+		return nil, fmt.Errorf("invalid CREATE FUNCTION: %s", sql)
+	}
 
-	// Check if UNIQUE
-	unique := strings.Contains(strings.ToUpper(sql), "UNIQUE")
+	schema, name := SplitQualifiedName(sql[loc[4]:loc[5]])
+	details := &CreateFunctionDetails{
+		Schema:       schema,
+		FunctionName: name,
+		Kind:         strings.ToUpper(sql[loc[2]:loc[3]]),
+	}
 
-	// Extract columns
-	columnsContent := ExtractParenthesesContent(sql)
-	var columns []string
-	for _, col := range strings.Split(columnsContent, ",") {
-		col = strings.TrimSpace(col)
-		// Remove DESC/ASC if present
-		col = strings.Split(col, " ")[0]
-		if col != "" {
-			columns = append(columns, col)
+	// The pattern ends at the opening parenthesis of the argument list
+	details.Arguments = ExtractParenthesesContent(sql[loc[1]-1:])
+	details.Signature = RoutineSignature(details.Arguments)
+	rest := sql[loc[1]+len(details.Arguments)+1:]
+
+	for _, clause := range splitRoutineClauses(rest) {
+		words := strings.Fields(clause)
+		keyword := strings.ToUpper(words[0])
+		value := strings.TrimSpace(clause[len(words[0]):])
+		switch {
+		case keyword == "RETURNS" && !strings.HasPrefix(strings.ToUpper(value), "NULL "):
+			details.Returns = value
+		case keyword == "LANGUAGE":
+			details.Language = strings.ToLower(UnquoteString(value))
+		case keyword == "IMMUTABLE" || keyword == "STABLE" || keyword == "VOLATILE":
+			details.Volatility = keyword
+		case keyword == "SECURITY" || keyword == "EXTERNAL":
+			details.Security = strings.ToUpper(words[len(words)-1])
+		case keyword == "SET":
+			details.SetOptions = append(details.SetOptions, NormalizeWhitespace(clause))
+		case keyword == "AS" || keyword == "BEGIN":
+			details.Body = clause
+		default:
+			details.Attributes = append(details.Attributes, NormalizeWhitespace(clause))
 		}
 	}
 
-	// Extract WHERE clause for partial index
-	whereRe := regexp.MustCompile(`(?i)WHERE\s+(.+)`)
-	whereMatches := whereRe.FindStringSubmatch(sql)
-	var where string
-	if len(whereMatches) >= 2 {
-		where = strings.TrimSpace(whereMatches[1])
+	if details.Body == "" {
+		return nil, fmt.Errorf("invalid CREATE %s, no body: %s", details.Kind, sql)
 	}
 
 	return &Statement{
-		Type:       CreateIndex,
+		Type:       CreateFunction,
+		Original:   sql,
+		Schema:     schema,
+		ObjectName: name,
+		Details:    details,
+	}, nil
+}
+
+func (p *Parser) parseDropFunction(sql string) (*Statement, error) {
+	loc := p.patterns["DROP_FUNC"].FindStringSubmatchIndex(sql)
+	if loc == nil {
+		return nil, fmt.Errorf("invalid DROP FUNCTION: %s", sql)
+	}
+
+	// Without an argument list a name must identify a single function
+	details := &DropFunctionDetails{
+		Kind: strings.ToUpper(sql[loc[2]:loc[3]]),
+		Drop: *parseDropOptions(sql, loc[4], true),
+	}
+	if len(details.Drop.Objects) == 0 {
+		return nil, fmt.Errorf("invalid DROP FUNCTION: %s", sql)
+	}
+
+	return &Statement{
+		Type:       DropFunction,
+		Original:   sql,
+		Schema:     details.Drop.Objects[0].Schema,
+		ObjectName: details.Drop.Objects[0].Name,
+		Details:    details,
+	}, nil
+}
+
+func (p *Parser) parseCreateTrigger(sql string) (*Statement, error) {
+	// Keywords are matched on a masked copy, so literals in WHEN or the arguments cannot confuse them
+	masked := MaskLiterals(sql)
+	loc := p.patterns["CREATE_TRIG"].FindStringSubmatchIndex(masked)
+	if loc == nil {
+		return nil, fmt.Errorf("invalid CREATE TRIGGER: %s", sql)
+	}
+
+	schema, tableName := SplitQualifiedName(sql[loc[10]:loc[11]])
+	details := &CreateTriggerDetails{
+		TriggerName: NormalizeIdentifier(sql[loc[4]:loc[5]]),
+		Schema:      schema,
+		TableName:   tableName,
+		Constraint:  loc[2] != -1,
+		Timing:      strings.ToUpper(NormalizeWhitespace(sql[loc[6]:loc[7]])),
+		ForEach:     "STATEMENT",
+	}
+
+	// Events are joined with OR; UPDATE may list the columns it fires on
+	eventRe := regexp.MustCompile(`(?i)^(INSERT|UPDATE|DELETE|TRUNCATE)(?:\s+OF\s+(.+))?$`)
+	for _, event := range regexp.MustCompile(`(?i)\s+OR\s+`).Split(sql[loc[8]:loc[9]], -1) {
+		matches := eventRe.FindStringSubmatch(strings.TrimSpace(event))
+		if matches == nil {
+			return nil, fmt.Errorf("invalid CREATE TRIGGER event %q: %s", event, sql)
+		}
+		details.Events = append(details.Events, strings.ToUpper(matches[1]))
+		if matches[2] != "" {
+			details.UpdateColumns = SplitIdentifierList(matches[2])
+		}
+	}
+
+	// EXECUTE FUNCTION name(arguments) ends the statement
+	executeRe := regexp.MustCompile(`(?i)\bEXECUTE\s+(?:FUNCTION|PROCEDURE)\s+(` + NamePattern + `)\s*\(`)
+	execLoc := executeRe.FindStringSubmatchIndex(masked)
+	if execLoc == nil || execLoc[0] < loc[1] {
+		return nil, fmt.Errorf("invalid CREATE TRIGGER, no EXECUTE FUNCTION: %s", sql)
+	}
+	details.FunctionSchema, details.FunctionName = SplitQualifiedName(sql[execLoc[2]:execLoc[3]])
+	details.Arguments = strings.TrimSpace(ExtractParenthesesContent(sql[execLoc[1]-1:]))
+
+	// Between the table and EXECUTE come FROM, DEFERRABLE, REFERENCING, FOR EACH and WHEN, in that order
+	clausesEnd := execLoc[0]
+	whenRe := regexp.MustCompile(`(?i)\bWHEN\s*\(`)
+	if whenLoc := whenRe.FindStringIndex(masked[loc[1]:execLoc[0]]); whenLoc != nil {
+		details.When = strings.TrimSpace(ExtractParenthesesContent(sql[loc[1]+whenLoc[1]-1 : execLoc[0]]))
+		clausesEnd = loc[1] + whenLoc[0]
+	}
+	forEachRe := regexp.MustCompile(`(?i)\bFOR\s+(?:EACH\s+)?(ROW|STATEMENT)\b`)
+	if forLoc := forEachRe.FindStringSubmatchIndex(masked[loc[1]:clausesEnd]); forLoc != nil {
+		details.ForEach = strings.ToUpper(masked[loc[1]+forLoc[2] : loc[1]+forLoc[3]])
+		clausesEnd = loc[1] + forLoc[0]
+	}
+	details.Options = NormalizeWhitespace(sql[loc[1]:clausesEnd])
+
+	return &Statement{
+		Type:       CreateTrigger,
+		Original:   sql,
+		Schema:     schema,
+		ObjectName: details.TriggerName,
+		Details:    details,
+	}, nil
+}
+
+func (p *Parser) parseAlterTrigger(sql string) (*Statement, error) {
+	matches := p.patterns["ALTER_TRIG"].FindStringSubmatch(sql)
+	if len(matches) < 4 {
+		return nil, fmt.Errorf("invalid ALTER TRIGGER: %s", sql)
+	}
+
+	schema, tableName := SplitQualifiedName(matches[2])
+	name := NormalizeIdentifier(matches[1])
+
+	return &Statement{
+		Type:       AlterTrigger,
 		Original:   sql,
-		ObjectName: indexName,
-		Details: &CreateIndexDetails{
-			IndexName: indexName,
-			TableName: tableName,
-			Columns:   columns,
-			Unique:    unique,
-			Where:     where,
+		Schema:     schema,
+		ObjectName: name,
+		Details: &TriggerDetails{
+			TriggerName: name,
+			Schema:      schema,
+			TableName:   tableName,
+			NewName:     NormalizeIdentifier(matches[3]),
 		},
 	}, nil
 }
 
-func (p *Parser) parseDropIndex(sql string) (*Statement, error) {
-	matches := p.patterns["DROP_INDEX"].FindStringSubmatch(sql)
-	if len(matches) < 2 {
-		return nil, fmt.Errorf("invalid DROP INDEX: %s", sql)
+func (p *Parser) parseDropTrigger(sql string) (*Statement, error) {
+	matches := p.patterns["DROP_TRIG"].FindStringSubmatch(sql)
+	if len(matches) < 3 {
+		return nil, fmt.Errorf("invalid DROP TRIGGER: %s", sql)
 	}
 
+	schema, tableName := SplitQualifiedName(matches[2])
+	name := NormalizeIdentifier(matches[1])
+
 	return &Statement{
-		Type:       DropIndex,
+		Type:       DropTrigger,
 		Original:   sql,
-		ObjectName: matches[1],
+		Schema:     schema,
+		ObjectName: name,
+		Details: &TriggerDetails{
+			TriggerName: name,
+			Schema:      schema,
+			TableName:   tableName,
+		},
 	}, nil
 }
 
-func (p *Parser) parseComment(sql string) (*Statement, error) {
-	matches := p.patterns["COMMENT_ON"].FindStringSubmatch(sql)
+// extensionVersionRe matches the version of CREATE EXTENSION ... VERSION or ALTER EXTENSION ... UPDATE TO
+var extensionVersionRe = regexp.MustCompile(`(?i)\b(?:VERSION|TO)\s+('(?:[^']|'')*'|[\w.]+)`)
+
+// extensionVersion returns the version given in an extension statement, unquoted
+func extensionVersion(text string) string {
+	matches := extensionVersionRe.FindStringSubmatch(text)
+	if matches == nil {
+		return ""
+	}
+	return UnquoteString(matches[1])
+}
+
+func (p *Parser) parseCreateExtension(sql string) (*Statement, error) {
+	loc := p.patterns["CREATE_EXT"].FindStringSubmatchIndex(sql)
+	if loc == nil {
+		return nil, fmt.Errorf("invalid CREATE EXTENSION: %s", sql)
+	}
+
+	name := NormalizeIdentifier(sql[loc[2]:loc[3]])
+	rest := sql[loc[1]:]
+
+	details := &ExtensionDetails{
+		ExtensionName: name,
+		Version:       extensionVersion(rest),
+		Cascade:       regexp.MustCompile(`(?i)\bCASCADE\b`).MatchString(rest),
+	}
+	if matches := regexp.MustCompile(`(?i)\bSCHEMA\s+(` + IdentPattern + `)`).FindStringSubmatch(rest); matches != nil {
+		details.Schema = NormalizeIdentifier(matches[1])
+	}
+
+	return &Statement{
+		Type:       CreateExtension,
+		Original:   sql,
+		ObjectName: name,
+		Details:    details,
+	}, nil
+}
+
+func (p *Parser) parseAlterExtension(sql string) (*Statement, error) {
+	loc := p.patterns["ALTER_EXT"].FindStringSubmatchIndex(sql)
+	if loc == nil {
+		return nil, fmt.Errorf("invalid ALTER EXTENSION: %s", sql)
+	}
+
+	name := NormalizeIdentifier(sql[loc[2]:loc[3]])
+	rest := sql[loc[1]:]
+	details := &ExtensionDetails{ExtensionName: name}
+
+	if strings.EqualFold(sql[loc[4]:loc[5]], "UPDATE") {
+		details.Update = true
+		details.Version = extensionVersion(rest)
+	} else if schema, _ := ReadIdentifier(rest); schema != "" {
+		details.Schema = schema
+	}
+
+	return &Statement{
+		Type:       AlterExtension,
+		Original:   sql,
+		ObjectName: name,
+		Details:    details,
+	}, nil
+}
+
+func (p *Parser) parseCreateSchema(sql string) (*Statement, error) {
+	matches := p.patterns["CREATE_SCHEMA"].FindStringSubmatch(sql)
+	if matches == nil {
+		return nil, fmt.Errorf("invalid CREATE SCHEMA: %s", sql)
+	}
+
+	// Without a name the schema is named after its owner
+	details := &SchemaDetails{
+		SchemaName:    NormalizeIdentifier(matches[2]),
+		Authorization: NormalizeIdentifier(matches[1] + matches[3]),
+	}
+	if details.SchemaName == "" {
+		details.SchemaName = details.Authorization
+	}
+
+	return &Statement{
+		Type:       CreateSchema,
+		Original:   sql,
+		ObjectName: details.SchemaName,
+		Details:    details,
+	}, nil
+}
+
+func (p *Parser) parseAlterSchema(sql string) (*Statement, error) {
+	matches := p.patterns["ALTER_SCHEMA"].FindStringSubmatch(sql)
 	if len(matches) < 3 {
+		return nil, fmt.Errorf("invalid ALTER SCHEMA: %s", sql)
+	}
+
+	name := NormalizeIdentifier(matches[1])
+
+	return &Statement{
+		Type:       AlterSchema,
+		Original:   sql,
+		ObjectName: name,
+		Details: &SchemaDetails{
+			SchemaName: name,
+			NewName:    NormalizeIdentifier(matches[2]),
+		},
+	}, nil
+}
+
+// parseSearchPath parses SET search_path, and the set_config('search_path', ...) call pg_dump writes
+func (p *Parser) parseSearchPath(sql string) (*Statement, error) {
+	matches := p.patterns["SEARCH_PATH"].FindStringSubmatch(sql)
+	if matches == nil {
+		return nil, fmt.Errorf("invalid SET search_path: %s", sql)
+	}
+
+	// set_config takes the whole list as one string
+	list := matches[1]
+	if matches[2] != "" {
+		list = UnquoteString(matches[2])
+	}
+
+	details := &SearchPathDetails{}
+	if !strings.EqualFold(strings.TrimSpace(list), "DEFAULT") {
+		for _, item := range SplitTopLevel(list, ",") {
+			item = strings.TrimSpace(item)
+			if strings.HasPrefix(item, "'") {
+				item = UnquoteString(item)
+			} else {
+				item = NormalizeIdentifier(item)
+			}
+			if item != "" {
+				details.Schemas = append(details.Schemas, item)
+			}
+		}
+	}
+
+	return &Statement{
+		Type:     SetSearchPath,
+		Original: sql,
+		Details:  details,
+	}, nil
+}
+
+func (p *Parser) parseComment(sql string) (*Statement, error) {
+	loc := p.patterns["COMMENT_ON"].FindStringSubmatchIndex(sql)
+	if loc == nil {
 		return nil, fmt.Errorf("invalid COMMENT: %s", sql)
 	}
+	matches := p.patterns["COMMENT_ON"].FindStringSubmatch(sql)
+
+	objectType := NormalizeWhitespace(strings.ToUpper(matches[1]))
 
-	objectType := strings.ToUpper(matches[1])
-	objectName := matches[2]
+	// Columns are named [schema.]table.column, everything else [schema.]name
+	parts := SplitNameParts(matches[2])
+	var schema, columnName string
+	if objectType == "COLUMN" && len(parts) >= 2 {
+		columnName = parts[len(parts)-1]
+		parts = parts[:len(parts)-1]
+	}
+	objectName := parts[len(parts)-1]
+	if len(parts) >= 2 {
+		schema = parts[len(parts)-2]
+	}
+
+	// Functions and procedures are identified by their argument types
+	var signature string
+	if objectType == "FUNCTION" || objectType == "PROCEDURE" {
+		rest := strings.TrimSpace(sql[loc[1]:])
+		if strings.HasPrefix(rest, "(") {
+			signature = RoutineSignature(ExtractParenthesesContent(rest))
+		}
+	}
 
-	// Extract comment text
-	// Handle escaped quotes ('') in the comment string
-	commentRe := regexp.MustCompile(`(?i)IS\s+'((?:[^']|'')+)'`)
-	commentMatches := commentRe.FindStringSubmatch(sql)
+	// Extract comment text, the string constant after IS
+	// IS NULL leaves the comment empty, which removes it
 	var comment string
-	if len(commentMatches) >= 2 {
-		// Replace escaped quotes ('') with single quotes (')
-		comment = strings.ReplaceAll(commentMatches[1], "''", "'")
+	if value, ok := firstLiteral(sql); ok {
+		comment = value
 	}
 
 	return &Statement{
 		Type:       Comment,
 		Original:   sql,
+		Schema:     schema,
 		ObjectName: objectName,
 		Details: &CommentDetails{
 			ObjectType: objectType,
+			Schema:     schema,
 			ObjectName: objectName,
+			ColumnName: columnName,
+			Signature:  signature,
 			Comment:    comment,
 		},
 	}, nil
@@ -523,23 +1560,27 @@ func (p *Parser) parseComment(sql string) (*Statement, error) {
 
 func (p *Parser) parseDoBlock(sql string) (*Statement, error) {
 	// Try to extract ALTER TYPE ADD VALUE from DO block
-	alterTypeRe := regexp.MustCompile(`(?i)ALTER\s+TYPE\s+(\w+)\s+ADD\s+VALUE\s+'([^']+)'`)
+	alterTypeRe := regexp.MustCompile(`(?i)ALTER\s+TYPE\s+(` + NamePattern + `)\s+ADD\s+VALUE\s+((?:IF\s+NOT\s+EXISTS\s+)?'[^']+'(?:\s+(?:BEFORE|AFTER)\s+'[^']+')?)`)
 	matches := alterTypeRe.FindStringSubmatch(sql)
 
-	var typeName, value string
+	var schema, typeName, value, before, after string
 	if len(matches) >= 3 {
-		typeName = matches[1]
-		value = matches[2]
+		schema, typeName = SplitQualifiedName(matches[1])
+		value, before, after = parseEnumValuePlacement(matches[2])
 	}
 
 	return &Statement{
 		Type:       DoBlock,
 		Original:   sql,
+		Schema:     schema,
 		ObjectName: typeName,
 		Details: &DoBlockDetails{
 			Content:  sql,
+			Schema:   schema,
 			TypeName: typeName,
 			Value:    value,
+			Before:   before,
+			After:    after,
 		},
 	}, nil
 }
@@ -0,0 +1,99 @@
+package parser
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+var (
+	// policyExpressionRe matches the start of a policy's USING or WITH CHECK expression
+	policyExpressionRe = regexp.MustCompile(`(?i)\b(USING|WITH\s+CHECK)\s*\(`)
+
+	policyRenameRe  = regexp.MustCompile(`(?i)^\s*RENAME\s+TO\s+(` + IdentPattern + `)\s*$`)
+	policyAsRe      = regexp.MustCompile(`(?i)\bAS\s+(PERMISSIVE|RESTRICTIVE)\b`)
+	policyCommandRe = regexp.MustCompile(`(?i)\bFOR\s+(ALL|SELECT|INSERT|UPDATE|DELETE)\b`)
+	policyRolesRe   = regexp.MustCompile(`(?is)\bTO\s+(.+)$`)
+)
+
+// parsePolicy parses CREATE POLICY, ALTER POLICY and DROP POLICY statements
+// CREATE POLICY name ON table [AS kind] [FOR command] [TO roles] [USING (expr)] [WITH CHECK (expr)]
+func (p *Parser) parsePolicy(sql string, stmtType StatementType, pattern string) (*Statement, error) {
+	// Clauses are found on a masked copy, so literals in the expressions cannot confuse them
+	masked := MaskLiterals(sql)
+	loc := p.patterns[pattern].FindStringSubmatchIndex(masked)
+	if loc == nil {
+		return nil, fmt.Errorf("invalid %s: %s", stmtType, sql)
+	}
+
+	schema, tableName := SplitQualifiedName(sql[loc[4]:loc[5]])
+	details := &PolicyDetails{
+		PolicyName: NormalizeIdentifier(sql[loc[2]:loc[3]]),
+		Schema:     schema,
+		TableName:  tableName,
+	}
+
+	if stmtType != DropPolicy {
+		if err := parsePolicyClauses(sql[loc[1]:], masked[loc[1]:], details); err != nil {
+			return nil, fmt.Errorf("%v: %s", err, sql)
+		}
+	}
+
+	return &Statement{
+		Type:       stmtType,
+		Original:   sql,
+		Schema:     schema,
+		ObjectName: details.PolicyName,
+		Details:    details,
+	}, nil
+}
+
+// parsePolicyClauses parses the clauses after a policy's table into details
+// masked is text with its literals masked, see MaskLiterals
+func parsePolicyClauses(text, masked string, details *PolicyDetails) error {
+	// AS, FOR and TO come before the expressions
+	header := masked
+	if loc := policyExpressionRe.FindStringIndex(masked); loc != nil {
+		header = masked[:loc[0]]
+	}
+
+	if matches := policyRenameRe.FindStringSubmatch(header); matches != nil {
+		details.NewName = NormalizeIdentifier(matches[1])
+		return nil
+	}
+	if matches := policyAsRe.FindStringSubmatch(header); matches != nil {
+		details.Restrictive = strings.EqualFold(matches[1], "RESTRICTIVE")
+	}
+	if matches := policyCommandRe.FindStringSubmatch(header); matches != nil {
+		details.Command = strings.ToUpper(matches[1])
+	}
+	if loc := policyRolesRe.FindStringSubmatchIndex(header); loc != nil {
+		details.Roles = SplitRoleList(text[loc[2]:loc[3]])
+	}
+
+	// USING comes before WITH CHECK, each expression is searched for after the previous one
+	offset := len(header)
+	for {
+		loc := policyExpressionRe.FindStringSubmatchIndex(masked[offset:])
+		if loc == nil {
+			break
+		}
+		open := offset + loc[1] - 1
+		expr := ExtractParenthesesContent(text[open:])
+		if expr == "" && !strings.HasPrefix(strings.TrimSpace(text[open+1:]), ")") {
+			return fmt.Errorf("unterminated policy expression")
+		}
+
+		if strings.EqualFold(masked[offset+loc[2]:offset+loc[3]], "USING") {
+			details.Using = strings.TrimSpace(expr)
+		} else {
+			details.WithCheck = strings.TrimSpace(expr)
+		}
+		offset = open + len(expr) + 2
+		if offset > len(masked) {
+			break
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,220 @@
+package parser
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+var (
+	// grantRe matches GRANT and REVOKE on objects, against text with parenthesized lists blanked
+	grantRe = regexp.MustCompile(`(?is)^\s*(GRANT|REVOKE)\s+(GRANT\s+OPTION\s+FOR\s+)?(.+?)\s+ON\s+(.+?)\s+(TO|FROM)\s+(.+?)(\s+WITH\s+GRANT\s+OPTION)?(?:\s+GRANTED\s+BY\s+\S+)?(?:\s+(?:CASCADE|RESTRICT))?\s*$`)
+
+	grantAllInSchemaRe = regexp.MustCompile(`(?is)^ALL\s+(TABLES|SEQUENCES|FUNCTIONS|PROCEDURES|ROUTINES)\s+IN\s+SCHEMA\s+(.+)$`)
+	grantObjectTypeRe  = regexp.MustCompile(`(?is)^(TABLE|SEQUENCE|FUNCTION|PROCEDURE|ROUTINE|TYPE|DOMAIN|SCHEMA)\s+(.+)$`)
+	grantOtherTypeRe   = regexp.MustCompile(`(?i)^(DATABASE|LANGUAGE|LARGE\s+OBJECT|FOREIGN|TABLESPACE|PARAMETER)\b`)
+	defaultTypeRe      = regexp.MustCompile(`(?i)^(TABLES|SEQUENCES|FUNCTIONS|ROUTINES|TYPES|SCHEMAS)$`)
+	grantGroupRe       = regexp.MustCompile(`(?i)^\s*GROUP\s+`)
+
+	defaultForRoleRe  = regexp.MustCompile(`(?is)^FOR\s+(?:ROLE|USER)\s+(.+?)\s+(IN\s+SCHEMA|GRANT|REVOKE)\b`)
+	defaultInSchemaRe = regexp.MustCompile(`(?is)^IN\s+SCHEMA\s+(.+?)\s+(GRANT|REVOKE)\b`)
+)
+
+// parseGrant parses GRANT and REVOKE statements on objects
+// Role memberships and privileges on databases, languages and the like are not tracked and left unknown
+func (p *Parser) parseGrant(sql string) (*Statement, error) {
+	details := parseGrantClause(sql, false)
+	if details == nil {
+		return nil, nil
+	}
+
+	stmtType := Grant
+	if details.Revoke {
+		stmtType = Revoke
+	}
+	stmt := &Statement{Type: stmtType, Original: sql, Details: details}
+	if len(details.Objects) > 0 {
+		stmt.Schema = details.Objects[0].Schema
+		stmt.ObjectName = details.Objects[0].Name
+	}
+	return stmt, nil
+}
+
+// parseAlterDefaultPrivileges parses ALTER DEFAULT PRIVILEGES statements
+// ALTER DEFAULT PRIVILEGES [FOR ROLE roles] [IN SCHEMA schemas] GRANT ... | REVOKE ...
+func (p *Parser) parseAlterDefaultPrivileges(sql string) (*Statement, error) {
+	loc := p.patterns["DEFAULT_PRIVS"].FindStringIndex(sql)
+	if loc == nil {
+		return nil, fmt.Errorf("invalid ALTER DEFAULT PRIVILEGES: %s", sql)
+	}
+
+	details := &DefaultPrivilegesDetails{}
+	rest := strings.TrimSpace(sql[loc[1]:])
+	if matches := defaultForRoleRe.FindStringSubmatchIndex(rest); matches != nil {
+		details.Roles = SplitRoleList(rest[matches[2]:matches[3]])
+		rest = rest[matches[4]:]
+	}
+	if matches := defaultInSchemaRe.FindStringSubmatchIndex(rest); matches != nil {
+		details.Schemas = SplitIdentifierList(rest[matches[2]:matches[3]])
+		rest = rest[matches[4]:]
+	}
+
+	grant := parseGrantClause(rest, true)
+	if grant == nil {
+		return nil, fmt.Errorf("invalid ALTER DEFAULT PRIVILEGES: %s", sql)
+	}
+	details.Grant = *grant
+
+	return &Statement{
+		Type:     AlterDefaultPrivileges,
+		Original: sql,
+		Details:  details,
+	}, nil
+}
+
+// parseGrantClause parses a GRANT or REVOKE, returning nil when it is not on objects that are tracked
+// With defaults set the ON clause is the object type of ALTER DEFAULT PRIVILEGES, such as TABLES
+func parseGrantClause(text string, defaults bool) *GrantDetails {
+	masked := blankParentheses(MaskLiterals(text))
+	loc := grantRe.FindStringSubmatchIndex(masked)
+	if loc == nil {
+		return nil
+	}
+
+	details := &GrantDetails{Revoke: strings.EqualFold(masked[loc[2]:loc[3]], "REVOKE")}
+	if details.Revoke != strings.EqualFold(masked[loc[10]:loc[11]], "FROM") {
+		return nil
+	}
+	details.GrantOption = loc[4] != -1 || loc[14] != -1
+
+	for _, part := range SplitTopLevel(text[loc[6]:loc[7]], ",") {
+		name, columns := part, ""
+		if open := strings.Index(part, "("); open != -1 {
+			name, columns = part[:open], ExtractParenthesesContent(part[open:])
+		}
+		spec := PrivilegeSpec{Name: strings.ToUpper(NormalizeWhitespace(name)), Columns: SplitIdentifierList(columns)}
+		if spec.Name == "ALL PRIVILEGES" {
+			spec.Name = "ALL"
+		}
+		details.Privileges = append(details.Privileges, spec)
+	}
+
+	objects, objectsMasked := text[loc[8]:loc[9]], masked[loc[8]:loc[9]]
+	if defaults {
+		if !defaultTypeRe.MatchString(objectsMasked) {
+			return nil
+		}
+		details.ObjectType = strings.ToUpper(objectsMasked)
+		if details.ObjectType == "ROUTINES" {
+			details.ObjectType = "FUNCTIONS"
+		}
+	} else if !parseGrantObjects(objects, objectsMasked, details) {
+		return nil
+	}
+
+	// GROUP is an obsolete noise word before a role
+	for _, part := range splitOutsideQuotes(text[loc[12]:loc[13]], ',') {
+		if role := NormalizeRole(grantGroupRe.ReplaceAllString(part, "")); role != "" {
+			details.Grantees = append(details.Grantees, role)
+		}
+	}
+
+	return details
+}
+
+// parseGrantObjects parses the ON clause of a GRANT or REVOKE into details
+// Returns false for object types that are not tracked
+func parseGrantObjects(text, masked string, details *GrantDetails) bool {
+	if matches := grantAllInSchemaRe.FindStringSubmatchIndex(masked); matches != nil {
+		details.ObjectType = strings.TrimSuffix(strings.ToUpper(masked[matches[2]:matches[3]]), "S")
+		details.AllInSchema = SplitIdentifierList(text[matches[4]:matches[5]])
+		return true
+	}
+	if grantOtherTypeRe.MatchString(masked) {
+		return false
+	}
+
+	details.ObjectType = "TABLE"
+	if matches := grantObjectTypeRe.FindStringSubmatchIndex(masked); matches != nil {
+		details.ObjectType = strings.ToUpper(masked[matches[2]:matches[3]])
+		text = text[matches[4]:matches[5]]
+	}
+
+	for _, part := range SplitTopLevel(text, ",") {
+		var object GrantObject
+		switch details.ObjectType {
+		case "SCHEMA":
+			object.Name = NormalizeIdentifier(part)
+		case "FUNCTION", "PROCEDURE", "ROUTINE":
+			var rest string
+			object.Schema, object.Name, rest = ReadQualifiedName(part)
+			if strings.HasPrefix(strings.TrimSpace(rest), "(") {
+				object.HasSignature = true
+				object.Signature = RoutineSignature(ExtractParenthesesContent(rest))
+			}
+		default:
+			object.Schema, object.Name = SplitQualifiedName(part)
+		}
+		if object.Name != "" {
+			details.Objects = append(details.Objects, object)
+		}
+	}
+	return len(details.Objects) > 0
+}
+
+// parseAlterOwner parses ALTER ... OWNER TO on views, sequences, types, domains, functions and schemas
+func (p *Parser) parseAlterOwner(sql string) (*Statement, error) {
+	loc := p.patterns["ALTER_OWNER"].FindStringSubmatchIndex(sql)
+	if loc == nil {
+		return nil, fmt.Errorf("invalid ALTER ... OWNER TO: %s", sql)
+	}
+
+	details := &OwnerDetails{
+		ObjectType: strings.ToUpper(NormalizeWhitespace(sql[loc[2]:loc[3]])),
+		Owner:      NormalizeRole(sql[loc[8]:loc[9]]),
+	}
+	if details.ObjectType == "SCHEMA" {
+		details.Name = NormalizeIdentifier(sql[loc[4]:loc[5]])
+	} else {
+		details.Schema, details.Name = SplitQualifiedName(sql[loc[4]:loc[5]])
+	}
+	if loc[6] != -1 {
+		details.HasSignature = true
+		details.Signature = RoutineSignature(ExtractParenthesesContent(sql[loc[6]:loc[7]]))
+	}
+
+	return &Statement{
+		Type:       AlterOwner,
+		Original:   sql,
+		Schema:     details.Schema,
+		ObjectName: details.Name,
+		Details:    details,
+	}, nil
+}
+
+// blankParentheses blanks out everything inside parentheses, keeping the parentheses themselves
+// The result has the same length as the input, so match positions can be mapped back
+func blankParentheses(sql string) string {
+	blanked := []byte(sql)
+	depth := 0
+	for _, tok := range Tokenize(sql) {
+		switch {
+		case tok.IsOperator("("):
+			depth++
+			if depth == 1 {
+				continue
+			}
+		case tok.IsOperator(")") && depth > 0:
+			depth--
+			if depth == 0 {
+				continue
+			}
+		}
+		if depth > 0 {
+			for i := tok.Offset; i < tok.Offset+len(tok.Text); i++ {
+				blanked[i] = ' '
+			}
+		}
+	}
+	return string(blanked)
+}
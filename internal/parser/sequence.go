@@ -0,0 +1,61 @@
+package parser
+
+import (
+	"strings"
+)
+
+// sequenceOptionKeywords are the keywords that start a sequence option
+var sequenceOptionKeywords = map[string]bool{
+	"AS":        true,
+	"INCREMENT": true,
+	"MINVALUE":  true,
+	"MAXVALUE":  true,
+	"NO":        true,
+	"START":     true,
+	"RESTART":   true,
+	"CACHE":     true,
+	"CYCLE":     true,
+	"OWNED":     true,
+	"SEQUENCE":  true,
+}
+
+// SplitSequenceOptions splits a list of sequence options into one string per option
+// e.g. "START WITH 10 INCREMENT BY 2 NO CYCLE" becomes "START WITH 10", "INCREMENT BY 2", "NO CYCLE"
+func SplitSequenceOptions(text string) []string {
+	var options []string
+	var current []string
+
+	for _, word := range strings.Fields(text) {
+		upper := strings.ToUpper(word)
+		startsOption := sequenceOptionKeywords[upper] &&
+			!(len(current) == 1 && strings.ToUpper(current[0]) == "NO")
+		if startsOption && len(current) > 0 {
+			options = append(options, strings.Join(current, " "))
+			current = nil
+		}
+		current = append(current, word)
+	}
+	if len(current) > 0 {
+		options = append(options, strings.Join(current, " "))
+	}
+
+	return options
+}
+
+// parseSequenceOptions splits the options of a CREATE or ALTER SEQUENCE statement
+// OWNED BY is returned separately, and anything that is not a sequence option is returned as skipped
+func parseSequenceOptions(text string) (options []string, ownedBy string, skipped []string) {
+	for _, option := range SplitSequenceOptions(text) {
+		words := strings.Fields(option)
+		keyword := strings.ToUpper(words[0])
+		switch {
+		case keyword == "OWNED" && len(words) == 3 && strings.EqualFold(words[1], "BY"):
+			ownedBy = words[2]
+		case keyword == "SEQUENCE" || !sequenceOptionKeywords[keyword]:
+			skipped = append(skipped, option)
+		default:
+			options = append(options, option)
+		}
+	}
+	return options, ownedBy, skipped
+}
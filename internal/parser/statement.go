@@ -1,5 +1,9 @@
 package parser
 
+import (
+	"fmt"
+)
+
 // StatementType represents the type of SQL DDL statement
 type StatementType int
 
@@ -12,11 +16,38 @@ const (
 	AlterType
 	DropType
 	CreateDomain
+	AlterDomain
 	DropDomain
 	CreateView
 	DropView
+	CreateMaterializedView
+	DropMaterializedView
+	RefreshMaterializedView
 	CreateIndex
+	AlterIndex
 	DropIndex
+	CreateSequence
+	AlterSequence
+	DropSequence
+	CreateFunction
+	DropFunction
+	CreateTrigger
+	AlterTrigger
+	DropTrigger
+	CreateExtension
+	AlterExtension
+	DropExtension
+	CreateSchema
+	AlterSchema
+	DropSchema
+	SetSearchPath
+	CreatePolicy
+	AlterPolicy
+	DropPolicy
+	Grant
+	Revoke
+	AlterDefaultPrivileges
+	AlterOwner
 	Comment
 	DoBlock
 )
@@ -37,16 +68,70 @@ func (st StatementType) String() string {
 		return "DROP TYPE"
 	case CreateDomain:
 		return "CREATE DOMAIN"
+	case AlterDomain:
+		return "ALTER DOMAIN"
 	case DropDomain:
 		return "DROP DOMAIN"
 	case CreateView:
 		return "CREATE VIEW"
 	case DropView:
 		return "DROP VIEW"
+	case CreateMaterializedView:
+		return "CREATE MATERIALIZED VIEW"
+	case DropMaterializedView:
+		return "DROP MATERIALIZED VIEW"
+	case RefreshMaterializedView:
+		return "REFRESH MATERIALIZED VIEW"
 	case CreateIndex:
 		return "CREATE INDEX"
+	case AlterIndex:
+		return "ALTER INDEX"
 	case DropIndex:
 		return "DROP INDEX"
+	case CreateSequence:
+		return "CREATE SEQUENCE"
+	case AlterSequence:
+		return "ALTER SEQUENCE"
+	case DropSequence:
+		return "DROP SEQUENCE"
+	case CreateFunction:
+		return "CREATE FUNCTION"
+	case DropFunction:
+		return "DROP FUNCTION"
+	case CreateTrigger:
+		return "CREATE TRIGGER"
+	case AlterTrigger:
+		return "ALTER TRIGGER"
+	case DropTrigger:
+		return "DROP TRIGGER"
+	case CreateExtension:
+		return "CREATE EXTENSION"
+	case AlterExtension:
+		return "ALTER EXTENSION"
+	case DropExtension:
+		return "DROP EXTENSION"
+	case CreateSchema:
+		return "CREATE SCHEMA"
+	case AlterSchema:
+		return "ALTER SCHEMA"
+	case DropSchema:
+		return "DROP SCHEMA"
+	case SetSearchPath:
+		return "SET search_path"
+	case CreatePolicy:
+		return "CREATE POLICY"
+	case AlterPolicy:
+		return "ALTER POLICY"
+	case DropPolicy:
+		return "DROP POLICY"
+	case Grant:
+		return "GRANT"
+	case Revoke:
+		return "REVOKE"
+	case AlterDefaultPrivileges:
+		return "ALTER DEFAULT PRIVILEGES"
+	case AlterOwner:
+		return "ALTER ... OWNER TO"
 	case Comment:
 		return "COMMENT"
 	case DoBlock:
@@ -65,81 +150,436 @@ const (
 	AlterColumn
 	AddConstraint
 	DropConstraint
+	RenameColumn
+	RenameTable
+	RenameConstraint
+	SetTriggerEnabled
+	SetRowSecurity
+	SetOwner
+	AttachPartition
+	DetachPartition
+	SetStorageParameters
+	ResetStorageParameters
+	SetPersistence
+	SetTablespace
+	SetAccessMethod
+	SetReplicaIdentity
 )
 
+// AlterColumnAction represents the sub-command of an ALTER TABLE ... ALTER COLUMN operation
+type AlterColumnAction int
+
+const (
+	SetDataType AlterColumnAction = iota
+	SetDefault
+	DropDefault
+	SetNotNull
+	DropNotNull
+	SetExpression
+	DropExpression
+	AddIdentity
+	SetIdentity
+	DropIdentity
+	SetStatistics
+	SetAttributeOptions
+	ResetAttributeOptions
+	SetStorage
+	SetCompression
+)
+
+// Position is a location in a migration file
+type Position struct {
+	File   string // Empty when the SQL did not come from a file
+	Offset int    // Byte offset from the start of the file
+	Line   int    // 1-based line number
+	Column int    // 1-based column, counted in characters
+}
+
+// String formats the position as file:line:col
+func (p Position) String() string {
+	file := p.File
+	if file == "" {
+		file = "<input>"
+	}
+	return fmt.Sprintf("%s:%d:%d", file, p.Line, p.Column)
+}
+
 // Statement represents a parsed SQL DDL statement
 type Statement struct {
 	Type       StatementType
 	Original   string
+	Pos        Position // Where the statement starts
+	Schema     string   // Empty when the object name is unqualified
 	ObjectName string
 	Details    interface{}
 }
 
 // CreateTableDetails contains details for CREATE TABLE statements
 type CreateTableDetails struct {
+	Schema     string
 	TableName  string
 	Definition string // Full table definition including columns and constraints
+
+	// Declarative partitioning
+	PartitionStrategy string // RANGE, LIST or HASH of PARTITION BY
+	PartitionKey      string // Columns and expressions of PARTITION BY, as written
+	ParentSchema      string
+	PartitionOf       string // Parent table of PARTITION OF
+	PartitionBound    string // FOR VALUES clause of PARTITION OF, or DEFAULT
+
+	// Inheritance and storage
+	Unlogged      bool
+	Inherits      []string // Parent tables of INHERITS, as written
+	AccessMethod  string   // USING method
+	StorageParams []string // WITH (...) storage parameters, as name=value
+	Tablespace    string
 }
 
 // AlterTableDetails contains details for ALTER TABLE statements
 type AlterTableDetails struct {
+	Schema     string
 	TableName  string
 	Operations []AlterOperation
+	Skipped    []string // Actions that were not recognized, as written
 }
 
 // AlterOperation represents a single operation within an ALTER TABLE statement
 type AlterOperation struct {
-	Type       AlterTableOperation
-	ColumnName string
-	DataType   string
-	Details    string // Full operation text for complex operations
+	Type           AlterTableOperation
+	ColumnName     string
+	DataType       string
+	ConstraintName string // For ADD/DROP/RENAME CONSTRAINT, and the index of REPLICA IDENTITY USING INDEX
+	NewName        string // For RENAME operations
+	TriggerName    string // For ENABLE/DISABLE TRIGGER, ALL or USER for several triggers
+	Partition      string // For ATTACH/DETACH PARTITION, as written, possibly schema-qualified
+	Details        string // Full operation text for complex operations
+	Cascade        bool   // For DROP COLUMN and DROP CONSTRAINT ... CASCADE
+
+	// ALTER COLUMN sub-command and its arguments
+	ColumnAction AlterColumnAction
+	Value        string   // Default, generation expression, identity kind, collation, setting, trigger state, row security state, owner, partition bound or table setting
+	Options      []string // Identity sequence options, attribute options or storage parameters
 }
 
-// CreateTypeDetails contains details for CREATE TYPE (enum) statements
+// CreateTypeDetails contains details for CREATE TYPE statements
 type CreateTypeDetails struct {
-	TypeName string
-	Values   []string
+	Schema       string
+	TypeName     string
+	Kind         string          // ENUM, COMPOSITE or RANGE
+	Values       []string        // Enum values
+	Attributes   []TypeAttribute // Composite type attributes
+	RangeOptions []string        // Range type options as written, e.g. "SUBTYPE = float8"
+}
+
+// TypeAttribute is an attribute of a composite type
+type TypeAttribute struct {
+	Name      string
+	DataType  string
+	Collation string // As written, empty if not given
+}
+
+// AttributeAction represents an attribute change made by ALTER TYPE on a composite type
+type AttributeAction int
+
+const (
+	AddAttribute AttributeAction = iota
+	DropAttribute
+	SetAttributeType
+	RenameAttribute
+)
+
+// AttributeOperation is a single attribute change of an ALTER TYPE statement
+type AttributeOperation struct {
+	Action    AttributeAction
+	Name      string
+	NewName   string // RENAME ATTRIBUTE
+	DataType  string // ADD ATTRIBUTE and ALTER ATTRIBUTE ... TYPE
+	Collation string
 }
 
 // AlterTypeDetails contains details for ALTER TYPE statements
+// ADD VALUE sets NewValue and at most one of Before and After, RENAME VALUE sets OldValue and NewValue,
+// RENAME TO sets NewName and composite type changes set Attributes
 type AlterTypeDetails struct {
-	TypeName string
-	NewValue string
+	Schema     string
+	TypeName   string
+	NewValue   string
+	Before     string // ADD VALUE ... BEFORE
+	After      string // ADD VALUE ... AFTER
+	OldValue   string // RENAME VALUE
+	NewName    string // RENAME TO
+	Attributes []AttributeOperation
 }
 
 // CreateDomainDetails contains details for CREATE DOMAIN statements
 type CreateDomainDetails struct {
-	DomainName string
-	BaseType   string
-	Default    string
-	Constraint string
+	Schema      string
+	DomainName  string
+	BaseType    string
+	Collation   string // As written, empty if not given
+	Default     string
+	NotNull     bool
+	Constraints []DomainConstraint // CHECK constraints, in order
+}
+
+// DomainConstraint is a CHECK constraint of a domain
+type DomainConstraint struct {
+	Name       string // Empty when unnamed
+	Expression string // Without the surrounding parentheses
+	NotValid   bool
+}
+
+// AlterDomainAction represents the change an ALTER DOMAIN statement makes
+type AlterDomainAction int
+
+const (
+	SetDomainDefault AlterDomainAction = iota
+	DropDomainDefault
+	SetDomainNotNull
+	DropDomainNotNull
+	AddDomainConstraint
+	DropDomainConstraint
+	RenameDomainConstraint
+	ValidateDomainConstraint
+	RenameDomain
+)
+
+// AlterDomainDetails contains details for ALTER DOMAIN statements
+type AlterDomainDetails struct {
+	Schema         string
+	DomainName     string
+	Action         AlterDomainAction
+	Default        string           // SET DEFAULT
+	Constraint     DomainConstraint // ADD CONSTRAINT
+	ConstraintName string           // DROP, RENAME and VALIDATE CONSTRAINT
+	NewName        string           // RENAME CONSTRAINT ... TO and RENAME TO
 }
 
 // CreateViewDetails contains details for CREATE VIEW statements
 type CreateViewDetails struct {
+	Schema     string
 	ViewName   string
 	Definition string // Full view SQL
 }
 
+// CreateMaterializedViewDetails contains details for CREATE MATERIALIZED VIEW statements
+type CreateMaterializedViewDetails struct {
+	Schema     string
+	ViewName   string
+	Definition string // Full view SQL, without the WITH [NO] DATA clause
+	WithData   bool   // False for WITH NO DATA
+}
+
 // CreateIndexDetails contains details for CREATE INDEX statements
 type CreateIndexDetails struct {
+	IndexName        string // Empty when PostgreSQL names the index
+	TableSchema      string
+	TableName        string
+	Elements         []IndexElement
+	Include          []string // INCLUDE columns
+	Unique           bool
+	NullsNotDistinct bool
+	Method           string   // USING method, empty when not given
+	Params           []string // WITH (...) storage parameters, as name=value
+	Tablespace       string
+	Where            string // Partial index WHERE clause
+}
+
+// IndexElement is a key column or expression of an index
+type IndexElement struct {
+	Column     string // Column name, empty for an expression
+	Expression string // Expression as written, a function call or parenthesized
+	Collation  string // COLLATE name as written
+	Opclass    string // Operator class as written, with its parameters
+	Order      string // ASC or DESC, empty when not given
+	Nulls      string // FIRST or LAST of NULLS, empty when not given
+}
+
+// AlterIndexDetails contains details for ALTER INDEX ... RENAME TO statements
+type AlterIndexDetails struct {
+	Schema    string
 	IndexName string
-	TableName string
-	Columns   []string
-	Unique    bool
-	Where     string // Partial index WHERE clause
+	NewName   string
+}
+
+// CreateSequenceDetails contains details for CREATE SEQUENCE statements
+type CreateSequenceDetails struct {
+	Schema       string
+	SequenceName string
+	Options      []string // One entry per option, e.g. "START WITH 10"
+	OwnedBy      string   // [schema.]table.column as written, or NONE
+	Skipped      []string // Options that were not recognized, as written
+}
+
+// AlterSequenceDetails contains details for ALTER SEQUENCE statements
+type AlterSequenceDetails struct {
+	Schema       string
+	SequenceName string
+	Options      []string // One entry per option, e.g. "INCREMENT BY 5"
+	OwnedBy      string   // [schema.]table.column as written, or NONE
+	NewName      string   // For RENAME TO
+	Skipped      []string // Actions that were not recognized, as written
+}
+
+// CreateFunctionDetails contains details for CREATE FUNCTION and CREATE PROCEDURE statements
+type CreateFunctionDetails struct {
+	Schema       string
+	FunctionName string
+	Kind         string   // FUNCTION or PROCEDURE
+	Arguments    string   // Argument list as written, without the parentheses
+	Signature    string   // Canonical argument types that identify the overload
+	Returns      string   // Return type as written, empty for procedures
+	Language     string   // Lower-cased language name
+	Volatility   string   // IMMUTABLE, STABLE or VOLATILE, empty for the default
+	Security     string   // DEFINER or INVOKER, empty for the default
+	SetOptions   []string // SET configuration clauses, as written
+	Attributes   []string // Other clauses such as STRICT or PARALLEL SAFE, as written
+	Body         string   // AS clause or BEGIN ATOMIC ... END block, as written
+}
+
+// DropFunctionDetails contains details for DROP FUNCTION and DROP PROCEDURE statements
+type DropFunctionDetails struct {
+	Kind string      // FUNCTION, PROCEDURE or ROUTINE
+	Drop DropDetails // Objects carry the argument types of each routine
+}
+
+// CreateTriggerDetails contains details for CREATE TRIGGER statements
+type CreateTriggerDetails struct {
+	TriggerName    string
+	Schema         string // Schema of the table
+	TableName      string
+	Constraint     bool     // CREATE CONSTRAINT TRIGGER
+	Timing         string   // BEFORE, AFTER or INSTEAD OF
+	Events         []string // INSERT, UPDATE, DELETE or TRUNCATE
+	UpdateColumns  []string // Columns of UPDATE OF
+	ForEach        string   // ROW or STATEMENT
+	When           string   // WHEN condition without parentheses
+	Options        string   // FROM, DEFERRABLE and REFERENCING clauses, as written
+	FunctionSchema string
+	FunctionName   string
+	Arguments      string // Function arguments as written, without parentheses
+}
+
+// TriggerDetails contains details for ALTER TRIGGER ... RENAME TO and DROP TRIGGER statements
+type TriggerDetails struct {
+	TriggerName string
+	Schema      string // Schema of the table
+	TableName   string
+	NewName     string // For RENAME TO
+}
+
+// PolicyDetails contains details for CREATE, ALTER and DROP POLICY statements
+// ALTER POLICY leaves the clauses it does not give empty
+type PolicyDetails struct {
+	PolicyName  string
+	Schema      string // Schema of the table
+	TableName   string
+	Restrictive bool     // AS RESTRICTIVE, policies are permissive by default
+	Command     string   // ALL, SELECT, INSERT, UPDATE or DELETE, empty when not given
+	Roles       []string // Normalized roles of the TO clause, see NormalizeRole
+	Using       string   // USING expression without parentheses
+	WithCheck   string   // WITH CHECK expression without parentheses
+	NewName     string   // For ALTER POLICY ... RENAME TO
+}
+
+// GrantDetails contains details for GRANT and REVOKE statements on objects
+// Role memberships (GRANT role TO role) are not tracked
+type GrantDetails struct {
+	Revoke      bool
+	Privileges  []PrivilegeSpec
+	ObjectType  string        // TABLE, SEQUENCE, FUNCTION, PROCEDURE, ROUTINE, TYPE, DOMAIN or SCHEMA; plural for ALTER DEFAULT PRIVILEGES
+	Objects     []GrantObject // Objects named in the ON clause
+	AllInSchema []string      // Schemas of ON ALL TABLES/SEQUENCES/FUNCTIONS/PROCEDURES/ROUTINES IN SCHEMA
+	Grantees    []string      // Normalized roles, see NormalizeRole
+	GrantOption bool          // WITH GRANT OPTION, or REVOKE GRANT OPTION FOR
+}
+
+// PrivilegeSpec is one privilege of a GRANT or REVOKE, optionally limited to some columns
+type PrivilegeSpec struct {
+	Name    string // Upper-case privilege, ALL for ALL [PRIVILEGES]
+	Columns []string
+}
+
+// GrantObject is an object named in the ON clause of a GRANT or REVOKE
+type GrantObject struct {
+	Schema       string
+	Name         string
+	Signature    string // Canonical argument types of a function, empty with HasSignature false when omitted
+	HasSignature bool
+}
+
+// DefaultPrivilegesDetails contains details for ALTER DEFAULT PRIVILEGES statements
+type DefaultPrivilegesDetails struct {
+	Roles   []string // FOR ROLE, empty for the role running the statement
+	Schemas []string // IN SCHEMA, empty for every schema
+	Grant   GrantDetails
+}
+
+// OwnerDetails contains details for ALTER ... OWNER TO statements on objects other than tables
+// Tables take OWNER TO as an ALTER TABLE action, see SetOwner
+type OwnerDetails struct {
+	ObjectType   string // VIEW, MATERIALIZED VIEW, SEQUENCE, TYPE, DOMAIN, FUNCTION, PROCEDURE, ROUTINE or SCHEMA
+	Schema       string
+	Name         string
+	Signature    string // Canonical argument types of a function, empty with HasSignature false when omitted
+	HasSignature bool
+	Owner        string // Normalized role, see NormalizeRole
+}
+
+// ExtensionDetails contains details for CREATE EXTENSION and ALTER EXTENSION statements
+// Extensions are database-wide, so the schema is where the extension's objects go, not part of its name
+type ExtensionDetails struct {
+	ExtensionName string
+	Schema        string // WITH SCHEMA or SET SCHEMA, empty if not given
+	Version       string // VERSION or UPDATE TO, empty if not given
+	Cascade       bool   // CREATE EXTENSION ... CASCADE
+	Update        bool   // ALTER EXTENSION ... UPDATE, which without TO moves to the default version
+}
+
+// DropDetails contains details for DROP statements that name one or more objects
+type DropDetails struct {
+	Objects      []DropObject // Every object named, in order
+	IfExists     bool
+	Cascade      bool // CASCADE, which drops the objects that depend on the dropped ones too
+	Concurrently bool // DROP INDEX CONCURRENTLY
+}
+
+// DropObject is an object named in a DROP statement
+type DropObject struct {
+	Schema       string // Empty when the name is unqualified
+	Name         string
+	Signature    string // Canonical argument types of a routine, empty with HasSignature false when omitted
+	HasSignature bool
+}
+
+// SchemaDetails contains details for CREATE and ALTER SCHEMA statements
+type SchemaDetails struct {
+	SchemaName    string
+	Authorization string // CREATE SCHEMA ... AUTHORIZATION
+	NewName       string // ALTER SCHEMA ... RENAME TO
+}
+
+// SearchPathDetails contains details for SET search_path statements
+type SearchPathDetails struct {
+	Schemas []string // Normalized schema names in order, empty for the default path
 }
 
 // CommentDetails contains details for COMMENT ON statements
 type CommentDetails struct {
-	ObjectType string // TABLE, COLUMN, TYPE, VIEW
-	ObjectName string
+	ObjectType string // TABLE, COLUMN, TYPE, VIEW, MATERIALIZED VIEW, SEQUENCE, FUNCTION, PROCEDURE, EXTENSION
+	Schema     string
+	ObjectName string // For COLUMN, the table name
+	ColumnName string // Only set for COLUMN
+	Signature  string // Only set for FUNCTION and PROCEDURE
 	Comment    string
 }
 
 // DoBlockDetails contains details for DO $$ blocks
 type DoBlockDetails struct {
-	Content string // Full block content
+	Content  string // Full block content
+	Schema   string // If it's an ALTER TYPE, the type schema
 	TypeName string // If it's an ALTER TYPE, the type name
 	Value    string // If it's an ALTER TYPE ADD VALUE, the value
+	Before   string // The value it is placed before, if given
+	After    string // The value it is placed after, if given
 }
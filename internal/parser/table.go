@@ -0,0 +1,83 @@
+package parser
+
+import (
+	"regexp"
+	"strings"
+)
+
+var (
+	// Table clauses are matched against text with literals masked and parenthesized lists blanked
+	partitionOfRe    = regexp.MustCompile(`(?is)^\s*PARTITION\s+OF\s+(` + NamePattern + `)`)
+	partitionBoundRe = regexp.MustCompile(`(?is)^\s*(FOR\s+VALUES\s+(?:FROM\s*\(\s*\)\s*TO\s*\(\s*\)|IN\s*\(\s*\)|WITH\s*\(\s*\))|DEFAULT)`)
+	partitionByRe    = regexp.MustCompile(`(?is)\bPARTITION\s+BY\s+(RANGE|LIST|HASH)\s*\(`)
+	inheritsRe       = regexp.MustCompile(`(?i)\bINHERITS\s*\(`)
+	accessMethodRe   = regexp.MustCompile(`(?i)\bUSING\s+(` + IdentPattern + `)`)
+	storageParamsRe  = regexp.MustCompile(`(?i)\bWITH\s*\(`)
+	tablespaceRe     = regexp.MustCompile(`(?i)\bTABLESPACE\s+(` + IdentPattern + `)`)
+)
+
+// parseTableClauses parses what follows the table name of CREATE TABLE into details
+// CREATE TABLE name ( definition ) [INHERITS ( parents )] [PARTITION BY strategy ( key )] [storage]
+// CREATE TABLE name PARTITION OF parent [( definition )] { FOR VALUES bound | DEFAULT } [PARTITION BY strategy ( key )] [storage]
+// where storage is [USING method] [WITH ( parameters )] [TABLESPACE name]
+func parseTableClauses(text string, details *CreateTableDetails) {
+	masked := blankParentheses(MaskLiterals(text))
+
+	if loc := partitionOfRe.FindStringSubmatchIndex(masked); loc != nil {
+		details.ParentSchema, details.PartitionOf = SplitQualifiedName(text[loc[2]:loc[3]])
+		text, masked = text[loc[1]:], masked[loc[1]:]
+	}
+
+	// The definition is optional for partitions, whose columns come from the parent
+	if trimmed := strings.TrimLeft(masked, " \t\r\n"); strings.HasPrefix(trimmed, "(") {
+		open := len(masked) - len(trimmed)
+		end := closingParenthesis(masked, open)
+		details.Definition = ExtractParenthesesContent(text[open:end])
+		text, masked = text[end:], masked[end:]
+	}
+
+	if details.PartitionOf != "" {
+		if loc := partitionBoundRe.FindStringSubmatchIndex(masked); loc != nil {
+			details.PartitionBound = NormalizeWhitespace(text[loc[2]:loc[3]])
+			text, masked = text[loc[1]:], masked[loc[1]:]
+		}
+	}
+
+	if loc := inheritsRe.FindStringIndex(masked); loc != nil {
+		for _, parent := range SplitTopLevel(ExtractParenthesesContent(text[loc[1]-1:]), ",") {
+			details.Inherits = append(details.Inherits, strings.TrimSpace(parent))
+		}
+	}
+	if loc := partitionByRe.FindStringSubmatchIndex(masked); loc != nil {
+		details.PartitionStrategy = strings.ToUpper(masked[loc[2]:loc[3]])
+		details.PartitionKey = strings.TrimSpace(ExtractParenthesesContent(text[loc[1]-1:]))
+	}
+	if loc := accessMethodRe.FindStringSubmatchIndex(masked); loc != nil {
+		details.AccessMethod = NormalizeIdentifier(text[loc[2]:loc[3]])
+	}
+	if loc := storageParamsRe.FindStringIndex(masked); loc != nil {
+		details.StorageParams = SplitStorageParameters(ExtractParenthesesContent(text[loc[1]-1:]))
+	}
+	if loc := tablespaceRe.FindStringSubmatchIndex(masked); loc != nil {
+		details.Tablespace = NormalizeIdentifier(text[loc[2]:loc[3]])
+	}
+}
+
+// closingParenthesis returns the offset just past the ) that closes the ( at open in text blanked by
+// blankParentheses, or the length of the text when it is never closed
+func closingParenthesis(blanked string, open int) int {
+	if i := strings.Index(blanked[open:], ")"); i != -1 {
+		return open + i + 1
+	}
+	return len(blanked)
+}
+
+// SplitStorageParameters splits a parenthesized list of storage parameters or attribute options
+// into its entries, each with its whitespace normalized, e.g. fillfactor=70
+func SplitStorageParameters(list string) []string {
+	var parameters []string
+	for _, parameter := range splitAlterActions(list) {
+		parameters = append(parameters, strings.Join(strings.Fields(parameter), " "))
+	}
+	return parameters
+}
@@ -1,13 +1,104 @@
 package state
 
+import (
+	"strings"
+)
+
 // Column represents a table column with its metadata
 type Column struct {
-	Name      string
-	Type      string
-	Nullable  bool
-	Default   string
-	Generated string
-	Comment   string
+	Name            string
+	Type            string
+	Collation       string // COLLATE name as written, empty for the type default
+	Nullable        bool
+	Default         string
+	Generated       string   // Expression of a GENERATED ALWAYS AS (...) STORED column
+	Identity        string   // ALWAYS or BY DEFAULT for identity columns
+	IdentityOptions []string // Sequence options of an identity column, one per option
+	Statistics      string   // Per-column statistics target set with SET STATISTICS
+	Storage         string   // Storage mode set with SET STORAGE
+	Compression     string   // Compression method set with SET COMPRESSION
+	Options         []string // Attribute options set with SET (...), as name=value
+	Comment         string
+}
+
+// MergeSequenceOptions applies updated sequence options on top of existing ones
+// An update replaces the existing option of the same kind, e.g. INCREMENT BY 2 replaces INCREMENT 1
+// RESTART options are dropped, since they only affect the current sequence value
+func MergeSequenceOptions(options, updates []string) []string {
+	merged := append([]string{}, options...)
+
+	for _, update := range updates {
+		key := sequenceOptionKey(update)
+		if key == "RESTART" {
+			continue
+		}
+
+		replaced := false
+		for i, option := range merged {
+			if sequenceOptionKey(option) == key {
+				merged[i] = update
+				replaced = true
+				break
+			}
+		}
+		if !replaced {
+			merged = append(merged, update)
+		}
+	}
+
+	return merged
+}
+
+// sequenceOptionKey returns the kind of a sequence option, e.g. MAXVALUE for both MAXVALUE 10 and NO MAXVALUE
+func sequenceOptionKey(option string) string {
+	words := strings.Fields(strings.ToUpper(option))
+	if len(words) == 0 {
+		return ""
+	}
+	if words[0] == "NO" && len(words) > 1 {
+		return words[1]
+	}
+	return words[0]
+}
+
+// SetOptions applies name=value attribute options, replacing existing values of the same name
+func (c *Column) SetOptions(options []string) {
+	c.Options = setOptions(c.Options, options)
+}
+
+// ResetOptions removes the named attribute options
+// Entries may be given as name or name=value
+func (c *Column) ResetOptions(names []string) {
+	c.Options = resetOptions(c.Options, names)
+}
+
+// setOptions applies name=value options to a list of options, replacing existing values of the same name
+func setOptions(options, updates []string) []string {
+	for _, update := range updates {
+		options = append(resetOptions(options, []string{update}), update)
+	}
+	return options
+}
+
+// resetOptions removes the named options from a list of options
+func resetOptions(options, names []string) []string {
+	for _, name := range names {
+		key := attributeOptionKey(name)
+		var remaining []string
+		for _, option := range options {
+			if attributeOptionKey(option) != key {
+				remaining = append(remaining, option)
+			}
+		}
+		options = remaining
+	}
+	return options
+}
+
+// attributeOptionKey returns the lower-cased name of a name=value attribute option
+func attributeOptionKey(option string) string {
+	name, _, _ := strings.Cut(option, "=")
+	return strings.ToLower(strings.TrimSpace(name))
 }
 
 // PrimaryKey represents a primary key constraint
@@ -18,12 +109,13 @@ type PrimaryKey struct {
 
 // ForeignKey represents a foreign key constraint
 type ForeignKey struct {
-	Name               string
-	Columns            []string
-	ReferencedTable    string
-	ReferencedColumns  []string
-	OnDelete           string
-	OnUpdate           string
+	Name              string
+	Columns           []string
+	ReferencedTable   string // Schema-qualified state key
+	ReferencedColumns []string
+	OnDelete          string
+	OnUpdate          string
+	Deferrable        string // e.g. DEFERRABLE INITIALLY DEFERRED
 }
 
 // UniqueConstraint represents a unique constraint
@@ -39,10 +131,41 @@ type CheckConstraint struct {
 }
 
 // Index represents a table index
+// Indexes always live in the schema of their table
 type Index struct {
-	Name    string
-	Columns []string
-	Unique  bool
-	Where   string
-	Method  string
+	Schema           string
+	Name             string
+	Elements         []IndexElement
+	Include          []string // INCLUDE columns
+	Unique           bool
+	NullsNotDistinct bool
+	Method           string   // USING method, empty when not given
+	Params           []string // WITH (...) storage parameters, as name=value
+	Tablespace       string
+	Where            string
+}
+
+// IndexElement is a key column or expression of an index
+type IndexElement struct {
+	Column     string // Column name, empty for an expression
+	Expression string // Expression as written, a function call or parenthesized
+	Collation  string
+	Opclass    string // Operator class with its parameters, as written
+	Order      string // ASC or DESC, empty when not given
+	Nulls      string // FIRST or LAST, empty when not given
+}
+
+// QualifiedName returns the schema-qualified index name
+func (i *Index) QualifiedName() string {
+	return QualifiedName(i.Schema, i.Name)
+}
+
+// removeIndex returns the indexes without the given one
+func removeIndex(indexes []*Index, idx *Index) []*Index {
+	for i, existing := range indexes {
+		if existing == idx {
+			return append(indexes[:i], indexes[i+1:]...)
+		}
+	}
+	return indexes
 }
@@ -0,0 +1,74 @@
+package state
+
+// CompositeType represents a composite type, CREATE TYPE ... AS (...)
+type CompositeType struct {
+	Schema     string
+	Name       string
+	Attributes []*Attribute
+	Privileges Privileges
+	Comment    string
+	CreatedIn  int
+}
+
+// Attribute is an attribute of a composite type
+type Attribute struct {
+	Name      string
+	Type      string
+	Collation string // As written, empty for the default
+}
+
+// NewCompositeType creates a new composite type
+func NewCompositeType(schema, name string) *CompositeType {
+	return &CompositeType{
+		Schema:     schema,
+		Name:       name,
+		Attributes: []*Attribute{},
+	}
+}
+
+// QualifiedName returns the schema-qualified type name
+func (c *CompositeType) QualifiedName() string {
+	return QualifiedName(c.Schema, c.Name)
+}
+
+// GetAttribute returns the attribute with the given name
+func (c *CompositeType) GetAttribute(name string) (*Attribute, bool) {
+	for _, attr := range c.Attributes {
+		if attr.Name == name {
+			return attr, true
+		}
+	}
+	return nil, false
+}
+
+// AddAttribute appends an attribute, replacing an existing attribute of the same name
+func (c *CompositeType) AddAttribute(attr *Attribute) {
+	for i, existing := range c.Attributes {
+		if existing.Name == attr.Name {
+			c.Attributes[i] = attr
+			return
+		}
+	}
+	c.Attributes = append(c.Attributes, attr)
+}
+
+// DropAttribute removes the attribute with the given name
+func (c *CompositeType) DropAttribute(name string) {
+	for i, attr := range c.Attributes {
+		if attr.Name == name {
+			c.Attributes = append(c.Attributes[:i], c.Attributes[i+1:]...)
+			return
+		}
+	}
+}
+
+// RenameAttribute renames an attribute
+// Returns false when the type has no such attribute
+func (c *CompositeType) RenameAttribute(oldName, newName string) bool {
+	attr, ok := c.GetAttribute(oldName)
+	if !ok {
+		return false
+	}
+	attr.Name = newName
+	return true
+}
@@ -0,0 +1,170 @@
+package state
+
+import (
+	"regexp"
+	"strings"
+)
+
+// expressionIdentifier matches identifiers within a constraint expression
+var expressionIdentifier = regexp.MustCompile(`"(?:[^"]|"")+"|[A-Za-z_][\w$]*`)
+
+// stringLiteral matches single-quoted string literals
+var stringLiteral = regexp.MustCompile(`'(?:[^']|'')*'`)
+
+// DefaultPrimaryKeyName returns the name PostgreSQL assigns to an unnamed primary key
+func (t *Table) DefaultPrimaryKeyName() string {
+	return t.Name + "_pkey"
+}
+
+// DefaultUniqueName returns the name PostgreSQL assigns to an unnamed unique constraint
+func (t *Table) DefaultUniqueName(columns []string) string {
+	return t.Name + "_" + strings.Join(columns, "_") + "_key"
+}
+
+// DefaultForeignKeyName returns the name PostgreSQL assigns to an unnamed foreign key
+func (t *Table) DefaultForeignKeyName(columns []string) string {
+	return t.Name + "_" + strings.Join(columns, "_") + "_fkey"
+}
+
+// DefaultCheckName returns the name PostgreSQL assigns to an unnamed check constraint
+// The name is derived from the first table column the expression references
+func (t *Table) DefaultCheckName(expression string) string {
+	for _, col := range t.ExpressionColumns(expression) {
+		return t.Name + "_" + col + "_check"
+	}
+	return t.Name + "_check"
+}
+
+// DefaultColumnCheckName returns the name PostgreSQL assigns to an unnamed column-level check constraint
+func (t *Table) DefaultColumnCheckName(column string) string {
+	return t.Name + "_" + column + "_check"
+}
+
+// ExpressionColumns returns the table columns referenced by an expression, in order of appearance
+func (t *Table) ExpressionColumns(expression string) []string {
+	var columns []string
+	for _, name := range expressionIdentifiers(expression) {
+		if _, exists := t.Columns[name]; exists && !contains(columns, name) {
+			columns = append(columns, name)
+		}
+	}
+	return columns
+}
+
+// expressionIdentifiers returns the normalized identifiers appearing in an expression
+// String literals are skipped so their contents are never mistaken for names
+func expressionIdentifiers(expression string) []string {
+	var names []string
+	for _, ident := range expressionIdentifier.FindAllString(stringLiteral.ReplaceAllString(expression, "''"), -1) {
+		if strings.HasPrefix(ident, `"`) {
+			names = append(names, strings.ReplaceAll(ident[1:len(ident)-1], `""`, `"`))
+		} else {
+			names = append(names, strings.ToLower(ident))
+		}
+	}
+	return names
+}
+
+// renameExpressionIdentifier replaces references to oldName in an expression with newName
+// String literals are left untouched
+func renameExpressionIdentifier(expression, oldName, newName string) string {
+	masked := stringLiteral.ReplaceAllStringFunc(expression, func(literal string) string {
+		return strings.Repeat(" ", len(literal))
+	})
+
+	var result strings.Builder
+	last := 0
+	for _, loc := range expressionIdentifier.FindAllStringIndex(masked, -1) {
+		names := expressionIdentifiers(expression[loc[0]:loc[1]])
+		if len(names) != 1 || names[0] != oldName {
+			continue
+		}
+		result.WriteString(expression[last:loc[0]])
+		result.WriteString(QuoteIdentifier(newName))
+		last = loc[1]
+	}
+	result.WriteString(expression[last:])
+
+	return result.String()
+}
+
+// RenameConstraint renames the constraint with the given name
+// Returns false when the table has no such constraint
+func (t *Table) RenameConstraint(oldName, newName string) bool {
+	if t.ReplicaIndex == oldName {
+		t.ReplicaIndex = newName
+	}
+
+	if t.PrimaryKey != nil && t.PrimaryKey.Name == oldName {
+		t.PrimaryKey.Name = newName
+		return true
+	}
+
+	for _, fk := range t.ForeignKeys {
+		if fk.Name == oldName {
+			fk.Name = newName
+			return true
+		}
+	}
+
+	for _, unique := range t.Uniques {
+		if unique.Name == oldName {
+			unique.Name = newName
+			return true
+		}
+	}
+
+	for _, check := range t.Checks {
+		if check.Name == oldName {
+			check.Name = newName
+			return true
+		}
+	}
+
+	return false
+}
+
+// DropConstraint removes the constraint with the given name
+// Returns false when the table has no such constraint
+func (t *Table) DropConstraint(name string) bool {
+	t.dropReplicaIndex(name)
+
+	if t.PrimaryKey != nil && t.PrimaryKey.Name == name {
+		t.PrimaryKey = nil
+		return true
+	}
+
+	for i, fk := range t.ForeignKeys {
+		if fk.Name == name {
+			t.ForeignKeys = append(t.ForeignKeys[:i], t.ForeignKeys[i+1:]...)
+			t.rebuildDependsOn()
+			return true
+		}
+	}
+
+	for i, unique := range t.Uniques {
+		if unique.Name == name {
+			t.Uniques = append(t.Uniques[:i], t.Uniques[i+1:]...)
+			return true
+		}
+	}
+
+	for i, check := range t.Checks {
+		if check.Name == name {
+			t.Checks = append(t.Checks[:i], t.Checks[i+1:]...)
+			return true
+		}
+	}
+
+	return false
+}
+
+// rebuildDependsOn recomputes table dependencies from the remaining foreign keys
+func (t *Table) rebuildDependsOn() {
+	t.DependsOn = []string{}
+	for _, fk := range t.ForeignKeys {
+		if !contains(t.DependsOn, fk.ReferencedTable) {
+			t.DependsOn = append(t.DependsOn, fk.ReferencedTable)
+		}
+	}
+}
@@ -1,41 +1,90 @@
 package state
 
+import (
+	"sort"
+	"strings"
+)
+
+// DefaultSchema is the schema unqualified object names resolve to
+const DefaultSchema = "public"
+
+// QualifiedName returns the schema-qualified name used to key objects in the state
+func QualifiedName(schema, name string) string {
+	if schema == "" {
+		schema = DefaultSchema
+	}
+	return schema + "." + name
+}
+
 // DatabaseState represents the cumulative database state after all migrations
+// All object maps are keyed by schema-qualified name
 type DatabaseState struct {
-	Domains map[string]*Domain
-	Enums   map[string]*Enum
-	Tables  map[string]*Table
-	Views   map[string]*View
+	Domains           map[string]*Domain
+	Enums             map[string]*Enum
+	CompositeTypes    map[string]*CompositeType
+	RangeTypes        map[string]*RangeType
+	Tables            map[string]*Table
+	Views             map[string]*View
+	MaterializedViews map[string]*MaterializedView
+	Sequences         map[string]*Sequence
+	Functions         map[string]*Function  // Keyed by qualified name and signature, see Function.Key
+	Extensions        map[string]*Extension // Keyed by extension name, extensions have no schema
+	Schemas           map[string]*Schema    // Keyed by schema name, without the default schema
 
 	// Track dropped objects to avoid recreating them
-	DroppedTables  map[string]bool
-	DroppedDomains map[string]bool
-	DroppedEnums   map[string]bool
-	DroppedViews   map[string]bool
+	DroppedTables            map[string]bool
+	DroppedDomains           map[string]bool
+	DroppedEnums             map[string]bool
+	DroppedCompositeTypes    map[string]bool
+	DroppedRangeTypes        map[string]bool
+	DroppedViews             map[string]bool
+	DroppedMaterializedViews map[string]bool
+	DroppedSequences         map[string]bool
+	DroppedFunctions         map[string]bool
+	DroppedExtensions        map[string]bool
+	DroppedSchemas           map[string]bool
 
 	// Track indexes separately for later removal if table is modified
 	Indexes map[string]*Index
+
+	// ALTER DEFAULT PRIVILEGES entries, in the order they were first changed
+	DefaultPrivileges []*DefaultPrivileges
 }
 
 // NewDatabaseState creates a new empty database state
 func NewDatabaseState() *DatabaseState {
 	return &DatabaseState{
-		Domains:        make(map[string]*Domain),
-		Enums:          make(map[string]*Enum),
-		Tables:         make(map[string]*Table),
-		Views:          make(map[string]*View),
-		DroppedTables:  make(map[string]bool),
-		DroppedDomains: make(map[string]bool),
-		DroppedEnums:   make(map[string]bool),
-		DroppedViews:   make(map[string]bool),
-		Indexes:        make(map[string]*Index),
+		Domains:                  make(map[string]*Domain),
+		Enums:                    make(map[string]*Enum),
+		CompositeTypes:           make(map[string]*CompositeType),
+		RangeTypes:               make(map[string]*RangeType),
+		Tables:                   make(map[string]*Table),
+		Views:                    make(map[string]*View),
+		MaterializedViews:        make(map[string]*MaterializedView),
+		Sequences:                make(map[string]*Sequence),
+		Functions:                make(map[string]*Function),
+		Extensions:               make(map[string]*Extension),
+		Schemas:                  make(map[string]*Schema),
+		DroppedTables:            make(map[string]bool),
+		DroppedDomains:           make(map[string]bool),
+		DroppedEnums:             make(map[string]bool),
+		DroppedCompositeTypes:    make(map[string]bool),
+		DroppedRangeTypes:        make(map[string]bool),
+		DroppedViews:             make(map[string]bool),
+		DroppedMaterializedViews: make(map[string]bool),
+		DroppedSequences:         make(map[string]bool),
+		DroppedFunctions:         make(map[string]bool),
+		DroppedExtensions:        make(map[string]bool),
+		DroppedSchemas:           make(map[string]bool),
+		Indexes:                  make(map[string]*Index),
 	}
 }
 
 // AddOrUpdateTable adds or updates a table
 func (ds *DatabaseState) AddOrUpdateTable(table *Table) {
-	ds.Tables[table.Name] = table
-	delete(ds.DroppedTables, table.Name)
+	key := table.QualifiedName()
+	ds.Tables[key] = table
+	delete(ds.DroppedTables, key)
 }
 
 // GetTable returns a table by name
@@ -44,16 +93,135 @@ func (ds *DatabaseState) GetTable(name string) (*Table, bool) {
 	return table, ok
 }
 
-// DropTable marks a table as dropped
+// DropTable marks a table as dropped, along with the sequences it owns
 func (ds *DatabaseState) DropTable(name string) {
+	table, ok := ds.Tables[name]
+	if ok && table.IsPartition() {
+		ds.DetachPartition(table.PartitionOf, name)
+	}
+
 	delete(ds.Tables, name)
 	ds.DroppedTables[name] = true
+	ds.DropOwnedSequences(name, "")
+
+	// Dropping a partitioned table drops its partitions, and tables inheriting from it go with it
+	if ok {
+		for _, partition := range table.Partitions {
+			ds.DropTable(partition)
+		}
+	}
+	for childName, child := range ds.Tables {
+		if contains(child.Inherits, name) {
+			ds.DropTable(childName)
+		}
+	}
+}
+
+// RenameTable renames a table and updates foreign keys and views that reference it
+// resolve turns a reference as written in a view definition into a schema-qualified state key
+func (ds *DatabaseState) RenameTable(name, newName string, resolve func(ref string) string) {
+	table, ok := ds.Tables[name]
+	if !ok {
+		return
+	}
+
+	delete(ds.Tables, name)
+	table.Name = newName
+	newKey := table.QualifiedName()
+	ds.Tables[newKey] = table
+	delete(ds.DroppedTables, newKey)
+
+	for _, other := range ds.Tables {
+		for _, fk := range other.ForeignKeys {
+			if fk.ReferencedTable == name {
+				fk.ReferencedTable = newKey
+			}
+		}
+		renameInList(other.DependsOn, name, newKey)
+		renameInList(other.Partitions, name, newKey)
+		renameInList(other.Inherits, name, newKey)
+		if other.PartitionOf == name {
+			other.PartitionOf = newKey
+		}
+	}
+
+	for _, view := range ds.Views {
+		if contains(view.DependsOn, name) {
+			view.RenameDependency(resolve, name, newKey)
+		}
+	}
+
+	for _, mview := range ds.MaterializedViews {
+		if contains(mview.DependsOn, name) {
+			mview.RenameDependency(resolve, name, newKey)
+		}
+	}
+
+	for _, seq := range ds.Sequences {
+		if seq.OwnedBy == name {
+			seq.OwnedBy = newKey
+		}
+	}
+}
+
+// RenameColumn renames a column of a table and updates foreign keys and views referencing it
+// Returns the views and materialized views where an unqualified reference may mean the column or one
+// of another table, which are left as they are
+// resolve turns each table reference as written into a schema-qualified state key
+func (ds *DatabaseState) RenameColumn(tableName, oldName, newName string, resolve func(ref string) string) []string {
+	table, ok := ds.Tables[tableName]
+	if !ok {
+		return nil
+	}
+
+	table.RenameColumn(oldName, newName)
+
+	// Partitions without columns of their own take them from the table
+	var unchanged []string
+	for _, name := range table.Partitions {
+		if partition, ok := ds.Tables[name]; ok && len(partition.ColumnOrder) == 0 {
+			unchanged = append(unchanged, ds.RenameColumn(name, oldName, newName, resolve)...)
+		}
+	}
+
+	for _, seq := range ds.Sequences {
+		if seq.OwnedBy == tableName && seq.OwnerCol == oldName {
+			seq.OwnerCol = newName
+		}
+	}
+
+	for _, other := range ds.Tables {
+		for _, fk := range other.ForeignKeys {
+			if fk.ReferencedTable == tableName {
+				renameInList(fk.ReferencedColumns, oldName, newName)
+			}
+		}
+	}
+
+	for _, key := range sortedKeys(ds.Views) {
+		if view := ds.Views[key]; contains(view.DependsOn, tableName) {
+			var renamed bool
+			if view.Definition, renamed = renameQueryColumn(view.Definition, resolve, tableName, oldName, newName); !renamed {
+				unchanged = append(unchanged, key)
+			}
+		}
+	}
+	for _, key := range sortedKeys(ds.MaterializedViews) {
+		if mview := ds.MaterializedViews[key]; contains(mview.DependsOn, tableName) {
+			var renamed bool
+			if mview.Definition, renamed = renameQueryColumn(mview.Definition, resolve, tableName, oldName, newName); !renamed {
+				unchanged = append(unchanged, key)
+			}
+		}
+	}
+	return unchanged
 }
 
 // AddOrUpdateDomain adds or updates a domain
 func (ds *DatabaseState) AddOrUpdateDomain(domain *Domain) {
-	ds.Domains[domain.Name] = domain
-	delete(ds.DroppedDomains, domain.Name)
+	key := domain.QualifiedName()
+	ds.Domains[key] = domain
+	delete(ds.DroppedDomains, key)
 }
 
 // GetDomain returns a domain by name
@@ -70,8 +238,9 @@ func (ds *DatabaseState) DropDomain(name string) {
 
 // AddOrUpdateEnum adds or updates an enum
 func (ds *DatabaseState) AddOrUpdateEnum(enum *Enum) {
-	ds.Enums[enum.Name] = enum
-	delete(ds.DroppedEnums, enum.Name)
+	key := enum.QualifiedName()
+	ds.Enums[key] = enum
+	delete(ds.DroppedEnums, key)
 }
 
 // GetEnum returns an enum by name
@@ -86,15 +255,228 @@ func (ds *DatabaseState) DropEnum(name string) {
 	ds.DroppedEnums[name] = true
 }
 
+// RenameEnumValue renames a value of an enum and updates the table, view and domain expressions that use the value
+// A literal is rewritten where it is cast to the enum, compared against a column of the enum, or is
+// the default of such a column
+// resolve turns a type name as written into a schema-qualified state key
+// Returns the expressions where the value appears but could not be told apart from a plain string,
+// and false when the enum has no such value
+func (ds *DatabaseState) RenameEnumValue(name, oldValue, newValue string, resolve func(ref string) string) ([]string, bool) {
+	enum, ok := ds.Enums[name]
+	if !ok || !enum.RenameValue(oldValue, newValue) {
+		return nil, false
+	}
+
+	isEnum := func(ref string) bool {
+		return resolve(ref) == name
+	}
+	oldLiteral, newLiteral := quoteLiteral(oldValue), quoteLiteral(newValue)
+
+	enumColumns := func(tableKeys ...string) []string {
+		var columns []string
+		for _, key := range tableKeys {
+			if table, ok := ds.Tables[key]; ok {
+				for _, colName := range table.ColumnOrder {
+					if typeName, _ := splitTypeReference(table.Columns[colName].Type); typeName != "" && isEnum(typeName) {
+						columns = append(columns, colName)
+					}
+				}
+			}
+		}
+		return columns
+	}
+
+	var unchanged []string
+	rename := func(expr *string, owner string, columns []string, typed bool) {
+		renamed, resolved := renameEnumLiteral(*expr, oldLiteral, newLiteral, columns, isEnum, typed)
+		*expr = renamed
+		if !resolved && mentionsEnum(renamed, columns, isEnum) {
+			unchanged = append(unchanged, owner)
+		}
+	}
+	for _, key := range sortedKeys(ds.Tables) {
+		table := ds.Tables[key]
+		columns := enumColumns(key)
+		for _, e := range table.expressions() {
+			typed := false
+			for _, col := range columns {
+				typed = typed || e.expr == &table.Columns[col].Default
+			}
+			rename(e.expr, e.owner+" of "+key, columns, typed)
+		}
+	}
+
+	// Views compare the columns of the tables they read, domain checks their VALUE
+	for _, key := range sortedKeys(ds.Views) {
+		view := ds.Views[key]
+		rename(&view.Definition, "view "+key, enumColumns(view.DependsOn...), false)
+	}
+	for _, key := range sortedKeys(ds.MaterializedViews) {
+		mview := ds.MaterializedViews[key]
+		rename(&mview.Definition, "materialized view "+key, enumColumns(mview.DependsOn...), false)
+	}
+	for _, key := range sortedKeys(ds.Domains) {
+		domain := ds.Domains[key]
+		var columns []string
+		if typeName, _ := splitTypeReference(domain.BaseType); typeName != "" && isEnum(typeName) {
+			columns = []string{"value"}
+		}
+		rename(&domain.Default, "default of domain "+key, columns, len(columns) > 0)
+		for _, constraint := range domain.Constraints {
+			rename(&constraint.Expression, "constraint "+constraint.Name+" of domain "+key, columns, false)
+		}
+	}
+	return unchanged, true
+}
+
+// RenameType renames an enum, domain, composite or range type and updates the column, attribute,
+// domain and range definitions that use it
+// resolve turns a type name as written into a schema-qualified state key
+func (ds *DatabaseState) RenameType(name, newName string, resolve func(ref string) string) bool {
+	schema, _ := SplitQualifiedName(name)
+	newKey := QualifiedName(schema, newName)
+
+	switch {
+	case ds.Enums[name] != nil:
+		enum := ds.Enums[name]
+		delete(ds.Enums, name)
+		enum.Name = newName
+		ds.AddOrUpdateEnum(enum)
+	case ds.Domains[name] != nil:
+		domain := ds.Domains[name]
+		delete(ds.Domains, name)
+		domain.Name = newName
+		ds.AddOrUpdateDomain(domain)
+	case ds.CompositeTypes[name] != nil:
+		ct := ds.CompositeTypes[name]
+		delete(ds.CompositeTypes, name)
+		ct.Name = newName
+		ds.AddOrUpdateCompositeType(ct)
+	case ds.RangeTypes[name] != nil:
+		rt := ds.RangeTypes[name]
+		delete(ds.RangeTypes, name)
+		rt.Name = newName
+		ds.AddOrUpdateRangeType(rt)
+	default:
+		return false
+	}
+
+	rename := func(typ string) string {
+		return renameTypeReference(typ, resolve, name, newKey, false)
+	}
+	for _, table := range ds.Tables {
+		for _, col := range table.Columns {
+			col.Type = rename(col.Type)
+		}
+	}
+	for _, ct := range ds.CompositeTypes {
+		for _, attr := range ct.Attributes {
+			attr.Type = rename(attr.Type)
+		}
+	}
+	for _, domain := range ds.Domains {
+		domain.BaseType = rename(domain.BaseType)
+	}
+
+	// Functions are keyed by their signature, which changes with the type
+	for _, key := range sortedKeys(ds.Functions) {
+		fn := ds.Functions[key]
+		fn.RenameType(resolve, name, newKey)
+		if fn.Key() != key {
+			delete(ds.Functions, key)
+			ds.Functions[fn.Key()] = fn
+		}
+	}
+
+	// Casts to the type are written with its name too
+	renameCasts := func(expr string) string {
+		return renameCastReferences(expr, resolve, name, newKey)
+	}
+	for _, table := range ds.Tables {
+		for _, e := range table.expressions() {
+			*e.expr = renameCasts(*e.expr)
+		}
+	}
+	for _, domain := range ds.Domains {
+		domain.Default = renameCasts(domain.Default)
+		for _, constraint := range domain.Constraints {
+			constraint.Expression = renameCasts(constraint.Expression)
+		}
+	}
+	for _, view := range ds.Views {
+		view.Definition = renameCasts(view.Definition)
+	}
+	for _, mview := range ds.MaterializedViews {
+		mview.Definition = renameCasts(mview.Definition)
+		for _, idx := range mview.Indexes {
+			for i := range idx.Elements {
+				idx.Elements[i].Expression = renameCasts(idx.Elements[i].Expression)
+			}
+			idx.Where = renameCasts(idx.Where)
+		}
+	}
+
+	for _, rt := range ds.RangeTypes {
+		for i, option := range rt.Options {
+			if key, value, found := strings.Cut(option, "="); found && strings.EqualFold(strings.TrimSpace(key), "SUBTYPE") {
+				rt.Options[i] = strings.TrimSpace(key) + " = " + rename(strings.TrimSpace(value))
+			}
+		}
+	}
+	return true
+}
+
+// AddOrUpdateCompositeType adds or updates a composite type
+func (ds *DatabaseState) AddOrUpdateCompositeType(ct *CompositeType) {
+	key := ct.QualifiedName()
+	ds.CompositeTypes[key] = ct
+	delete(ds.DroppedCompositeTypes, key)
+}
+
+// GetCompositeType returns a composite type by name
+func (ds *DatabaseState) GetCompositeType(name string) (*CompositeType, bool) {
+	ct, ok := ds.CompositeTypes[name]
+	return ct, ok
+}
+
+// DropCompositeType marks a composite type as dropped
+func (ds *DatabaseState) DropCompositeType(name string) {
+	delete(ds.CompositeTypes, name)
+	ds.DroppedCompositeTypes[name] = true
+}
+
+// AddOrUpdateRangeType adds or updates a range type
+func (ds *DatabaseState) AddOrUpdateRangeType(rt *RangeType) {
+	key := rt.QualifiedName()
+	ds.RangeTypes[key] = rt
+	delete(ds.DroppedRangeTypes, key)
+}
+
+// GetRangeType returns a range type by name
+func (ds *DatabaseState) GetRangeType(name string) (*RangeType, bool) {
+	rt, ok := ds.RangeTypes[name]
+	return rt, ok
+}
+
+// DropRangeType marks a range type as dropped
+func (ds *DatabaseState) DropRangeType(name string) {
+	delete(ds.RangeTypes, name)
+	ds.DroppedRangeTypes[name] = true
+}
+
 // AddOrUpdateView adds or updates a view
 func (ds *DatabaseState) AddOrUpdateView(view *View) {
+	key := view.QualifiedName()
+
 	// Check if view already exists (for versioning)
-	if existingView, ok := ds.Views[view.Name]; ok {
+	if existingView, ok := ds.Views[key]; ok {
 		view.Version = existingView.Version + 1
+		// CREATE OR REPLACE keeps the privileges
+		view.Privileges = existingView.Privileges
 	}
 
-	ds.Views[view.Name] = view
-	delete(ds.DroppedViews, view.Name)
+	ds.Views[key] = view
+	delete(ds.DroppedViews, key)
 }
 
 // GetView returns a view by name
@@ -109,9 +491,363 @@ func (ds *DatabaseState) DropView(name string) {
 	ds.DroppedViews[name] = true
 }
 
+// AddOrUpdateMaterializedView adds or updates a materialized view
+func (ds *DatabaseState) AddOrUpdateMaterializedView(mview *MaterializedView) {
+	key := mview.QualifiedName()
+	ds.MaterializedViews[key] = mview
+	delete(ds.DroppedMaterializedViews, key)
+}
+
+// GetMaterializedView returns a materialized view by name
+func (ds *DatabaseState) GetMaterializedView(name string) (*MaterializedView, bool) {
+	mview, ok := ds.MaterializedViews[name]
+	return mview, ok
+}
+
+// DropMaterializedView marks a materialized view as dropped, along with its indexes
+func (ds *DatabaseState) DropMaterializedView(name string) {
+	if mview, ok := ds.MaterializedViews[name]; ok {
+		for _, idx := range mview.Indexes {
+			delete(ds.Indexes, idx.QualifiedName())
+		}
+	}
+	delete(ds.MaterializedViews, name)
+	ds.DroppedMaterializedViews[name] = true
+}
+
+// AddOrUpdateSequence adds or updates a sequence
+func (ds *DatabaseState) AddOrUpdateSequence(seq *Sequence) {
+	key := seq.QualifiedName()
+	ds.Sequences[key] = seq
+	delete(ds.DroppedSequences, key)
+}
+
+// GetSequence returns a sequence by name
+func (ds *DatabaseState) GetSequence(name string) (*Sequence, bool) {
+	seq, ok := ds.Sequences[name]
+	return seq, ok
+}
+
+// DropSequence marks a sequence as dropped
+func (ds *DatabaseState) DropSequence(name string) {
+	delete(ds.Sequences, name)
+	ds.DroppedSequences[name] = true
+}
+
+// DropOwnedSequences drops the sequences owned by a column of a table, or by any column if column is empty
+func (ds *DatabaseState) DropOwnedSequences(tableName, column string) {
+	for key, seq := range ds.Sequences {
+		if seq.OwnedBy == tableName && (column == "" || seq.OwnerCol == column) {
+			ds.DropSequence(key)
+		}
+	}
+}
+
+// RenameSequence renames a sequence and updates the column defaults that call nextval() on it
+// resolve turns a sequence name as written in a default into a schema-qualified state key
+func (ds *DatabaseState) RenameSequence(name, newName string, resolve func(ref string) string) {
+	seq, ok := ds.Sequences[name]
+	if !ok {
+		return
+	}
+
+	delete(ds.Sequences, name)
+	seq.Name = newName
+	newKey := seq.QualifiedName()
+	ds.Sequences[newKey] = seq
+	delete(ds.DroppedSequences, newKey)
+
+	for _, table := range ds.Tables {
+		for _, col := range table.Columns {
+			col.Default = renameNextvalReferences(col.Default, resolve, name, newKey, false)
+		}
+	}
+}
+
+// AddOrUpdateFunction adds a function, replacing an existing one with the same signature
+func (ds *DatabaseState) AddOrUpdateFunction(fn *Function) {
+	key := fn.Key()
+	if existing, ok := ds.Functions[key]; ok {
+		// CREATE OR REPLACE keeps the original creation order, comment and privileges
+		fn.CreatedIn = existing.CreatedIn
+		fn.Comment = existing.Comment
+		fn.Privileges = existing.Privileges
+	}
+	ds.Functions[key] = fn
+	delete(ds.DroppedFunctions, key)
+}
+
+// GetFunction returns a function by key
+func (ds *DatabaseState) GetFunction(key string) (*Function, bool) {
+	fn, ok := ds.Functions[key]
+	return fn, ok
+}
+
+// FunctionOverloads returns the keys of all functions with the given qualified name
+func (ds *DatabaseState) FunctionOverloads(qualifiedName string) []string {
+	var keys []string
+	for key, fn := range ds.Functions {
+		if fn.QualifiedName() == qualifiedName {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// DropFunction marks a function as dropped
+func (ds *DatabaseState) DropFunction(key string) {
+	delete(ds.Functions, key)
+	ds.DroppedFunctions[key] = true
+}
+
+// AddOrUpdateExtension adds or updates an extension
+func (ds *DatabaseState) AddOrUpdateExtension(ext *Extension) {
+	ds.Extensions[ext.Name] = ext
+	delete(ds.DroppedExtensions, ext.Name)
+}
+
+// GetExtension returns an extension by name
+func (ds *DatabaseState) GetExtension(name string) (*Extension, bool) {
+	ext, ok := ds.Extensions[name]
+	return ext, ok
+}
+
+// DropExtension marks an extension as dropped
+func (ds *DatabaseState) DropExtension(name string) {
+	delete(ds.Extensions, name)
+	ds.DroppedExtensions[name] = true
+}
+
+// AddOrUpdateSchema adds or updates a schema
+func (ds *DatabaseState) AddOrUpdateSchema(schema *Schema) {
+	ds.Schemas[schema.Name] = schema
+	delete(ds.DroppedSchemas, schema.Name)
+}
+
+// GetSchema returns a schema by name
+func (ds *DatabaseState) GetSchema(name string) (*Schema, bool) {
+	schema, ok := ds.Schemas[name]
+	return schema, ok
+}
+
+// HasSchema reports whether a schema exists, which the default schema always does
+func (ds *DatabaseState) HasSchema(name string) bool {
+	_, ok := ds.Schemas[name]
+	return ok || name == DefaultSchema
+}
+
+// HasObject reports whether a table, view, sequence, index, type or function of the given key exists
+func (ds *DatabaseState) HasObject(key string) bool {
+	_, isTable := ds.Tables[key]
+	_, isView := ds.Views[key]
+	_, isMaterializedView := ds.MaterializedViews[key]
+	_, isSequence := ds.Sequences[key]
+	_, isIndex := ds.Indexes[key]
+	_, isEnum := ds.Enums[key]
+	_, isDomain := ds.Domains[key]
+	_, isComposite := ds.CompositeTypes[key]
+	_, isRange := ds.RangeTypes[key]
+	return isTable || isView || isMaterializedView || isSequence || isIndex ||
+		isEnum || isDomain || isComposite || isRange || len(ds.FunctionOverloads(key)) > 0
+}
+
+// SchemaObjects returns the keys of the tables, views, types, sequences and functions in a schema, sorted
+func (ds *DatabaseState) SchemaObjects(name string) []string {
+	var keys []string
+	add := func(key, schema string) {
+		if schema == name {
+			keys = append(keys, key)
+		}
+	}
+	for key, table := range ds.Tables {
+		add(key, table.Schema)
+	}
+	for key, view := range ds.Views {
+		add(key, view.Schema)
+	}
+	for key, mview := range ds.MaterializedViews {
+		add(key, mview.Schema)
+	}
+	for key, seq := range ds.Sequences {
+		add(key, seq.Schema)
+	}
+	for key, enum := range ds.Enums {
+		add(key, enum.Schema)
+	}
+	for key, domain := range ds.Domains {
+		add(key, domain.Schema)
+	}
+	for key, ct := range ds.CompositeTypes {
+		add(key, ct.Schema)
+	}
+	for key, rt := range ds.RangeTypes {
+		add(key, rt.Schema)
+	}
+	for key, fn := range ds.Functions {
+		add(key, fn.Schema)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// DropSchema marks a schema as dropped, along with every object in it
+// Extensions installed into the schema are dropped as well, as DROP SCHEMA ... CASCADE does
+func (ds *DatabaseState) DropSchema(name string) {
+	for _, key := range ds.SchemaObjects(name) {
+		ds.DropView(key)
+		ds.DropMaterializedView(key)
+		ds.DropTable(key)
+		ds.DropSequence(key)
+		ds.DropFunction(key)
+		ds.DropEnum(key)
+		ds.DropDomain(key)
+		ds.DropCompositeType(key)
+		ds.DropRangeType(key)
+	}
+	for key, ext := range ds.Extensions {
+		if ext.Schema == name {
+			ds.DropExtension(key)
+		}
+	}
+	for key, idx := range ds.Indexes {
+		if idx.Schema == name {
+			ds.DropIndex(key)
+		}
+	}
+
+	delete(ds.Schemas, name)
+	ds.DroppedSchemas[name] = true
+}
+
+// RenameSchema renames a schema, moving every object in it and updating the keys and qualified
+// references that point at them
+// resolve turns a reference as written in a definition into a schema-qualified state key
+func (ds *DatabaseState) RenameSchema(name, newName string, resolve func(ref string) string) bool {
+	schema, ok := ds.Schemas[name]
+	if !ok || name == newName {
+		return false
+	}
+
+	delete(ds.Schemas, name)
+	schema.Name = newName
+	ds.AddOrUpdateSchema(schema)
+
+	// Views are rewritten while their dependencies still carry the old keys
+	for _, view := range ds.Views {
+		for _, dep := range append([]string{}, view.DependsOn...) {
+			view.RenameDependency(resolve, dep, renameKeySchema(dep, name, newName))
+		}
+	}
+	for _, mview := range ds.MaterializedViews {
+		for _, dep := range append([]string{}, mview.DependsOn...) {
+			mview.RenameDependency(resolve, dep, renameKeySchema(dep, name, newName))
+		}
+	}
+
+	moveObjects(ds.Tables, func(t *Table) *string { return &t.Schema }, (*Table).QualifiedName, name, newName)
+	moveObjects(ds.Views, func(v *View) *string { return &v.Schema }, (*View).QualifiedName, name, newName)
+	moveObjects(ds.MaterializedViews, func(m *MaterializedView) *string { return &m.Schema }, (*MaterializedView).QualifiedName, name, newName)
+	moveObjects(ds.Sequences, func(s *Sequence) *string { return &s.Schema }, (*Sequence).QualifiedName, name, newName)
+	moveObjects(ds.Enums, func(e *Enum) *string { return &e.Schema }, (*Enum).QualifiedName, name, newName)
+	moveObjects(ds.Domains, func(d *Domain) *string { return &d.Schema }, (*Domain).QualifiedName, name, newName)
+	moveObjects(ds.CompositeTypes, func(c *CompositeType) *string { return &c.Schema }, (*CompositeType).QualifiedName, name, newName)
+	moveObjects(ds.RangeTypes, func(r *RangeType) *string { return &r.Schema }, (*RangeType).QualifiedName, name, newName)
+	moveObjects(ds.Functions, func(f *Function) *string { return &f.Schema }, (*Function).Key, name, newName)
+	moveObjects(ds.Indexes, func(i *Index) *string { return &i.Schema }, (*Index).QualifiedName, name, newName)
+
+	rename := func(typ string) string {
+		return renameTypeSchema(typ, resolve, name, newName)
+	}
+	for _, table := range ds.Tables {
+		for _, fk := range table.ForeignKeys {
+			fk.ReferencedTable = renameKeySchema(fk.ReferencedTable, name, newName)
+		}
+		for i, dep := range table.DependsOn {
+			table.DependsOn[i] = renameKeySchema(dep, name, newName)
+		}
+		for _, trigger := range table.Triggers {
+			trigger.Function = renameKeySchema(trigger.Function, name, newName)
+		}
+		for _, col := range table.Columns {
+			col.Type = rename(col.Type)
+			for _, ref := range NextvalReferences(col.Default) {
+				key := resolve(ref)
+				col.Default = renameNextvalReferences(col.Default, resolve, key, renameKeySchema(key, name, newName), false)
+			}
+		}
+	}
+	for _, fn := range ds.Functions {
+		for i, dep := range fn.DependsOn {
+			fn.DependsOn[i] = renameKeySchema(dep, name, newName)
+		}
+	}
+	for _, seq := range ds.Sequences {
+		if seq.OwnedBy != "" {
+			seq.OwnedBy = renameKeySchema(seq.OwnedBy, name, newName)
+		}
+	}
+	for _, ct := range ds.CompositeTypes {
+		for _, attr := range ct.Attributes {
+			attr.Type = rename(attr.Type)
+		}
+	}
+	for _, domain := range ds.Domains {
+		domain.BaseType = rename(domain.BaseType)
+	}
+	for _, ext := range ds.Extensions {
+		if ext.Schema == name {
+			ext.Schema = newName
+		}
+	}
+	for _, view := range ds.Views {
+		renameInList(view.SearchPath, name, newName)
+	}
+	for _, mview := range ds.MaterializedViews {
+		renameInList(mview.SearchPath, name, newName)
+	}
+	return true
+}
+
+// QualifyType qualifies a type as written when it names a type of a schema other than the default,
+// so the type still resolves once the search path that found it is gone
+// resolve turns the type name as written into a schema-qualified state key
+func (ds *DatabaseState) QualifyType(typ string, resolve func(ref string) string) string {
+	ref, _ := splitTypeReference(typ)
+	if ref == "" || strings.Contains(ref, ".") {
+		return typ
+	}
+
+	key := resolve(ref)
+	_, isEnum := ds.Enums[key]
+	_, isDomain := ds.Domains[key]
+	_, isComposite := ds.CompositeTypes[key]
+	_, isRange := ds.RangeTypes[key]
+	_, isTable := ds.Tables[key]
+	if schema, _ := SplitQualifiedName(key); schema == DefaultSchema || !(isEnum || isDomain || isComposite || isRange || isTable) {
+		return typ
+	}
+	return renameTypeReference(typ, resolve, key, key, true)
+}
+
+// QualifyNextval qualifies the nextval() calls of an expression on sequences of a schema other than the default
+// resolve turns each sequence name as written into a schema-qualified state key
+func (ds *DatabaseState) QualifyNextval(expr string, resolve func(ref string) string) string {
+	for _, ref := range NextvalReferences(expr) {
+		key := resolve(ref)
+		if _, exists := ds.Sequences[key]; !exists || strings.Contains(ref, ".") {
+			continue
+		}
+		if schema, _ := SplitQualifiedName(key); schema != DefaultSchema {
+			expr = renameNextvalReferences(expr, resolve, key, key, true)
+		}
+	}
+	return expr
+}
+
 // AddIndex adds an index to the state
 func (ds *DatabaseState) AddIndex(idx *Index) {
-	ds.Indexes[idx.Name] = idx
+	ds.Indexes[idx.QualifiedName()] = idx
 }
 
 // GetIndex returns an index by name
@@ -120,7 +856,40 @@ func (ds *DatabaseState) GetIndex(name string) (*Index, bool) {
 	return idx, ok
 }
 
-// DropIndex removes an index from the state
+// RenameIndex renames an index, keeping it attached to its table
+func (ds *DatabaseState) RenameIndex(name, newName string) {
+	idx, ok := ds.Indexes[name]
+	if !ok {
+		return
+	}
+
+	delete(ds.Indexes, name)
+	oldName := idx.Name
+	idx.Name = newName
+	ds.Indexes[idx.QualifiedName()] = idx
+
+	for _, table := range ds.Tables {
+		if table.Schema == idx.Schema && table.ReplicaIndex == oldName {
+			table.ReplicaIndex = newName
+		}
+	}
+}
+
+// DropIndex removes an index from the state and from the table or materialized view it belongs to
 func (ds *DatabaseState) DropIndex(name string) {
+	idx, ok := ds.Indexes[name]
+	if !ok {
+		return
+	}
+
 	delete(ds.Indexes, name)
+	for _, table := range ds.Tables {
+		table.Indexes = removeIndex(table.Indexes, idx)
+		if table.Schema == idx.Schema {
+			table.dropReplicaIndex(idx.Name)
+		}
+	}
+	for _, mview := range ds.MaterializedViews {
+		mview.Indexes = removeIndex(mview.Indexes, idx)
+	}
 }
@@ -0,0 +1,420 @@
+package state
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// Dependent is an object that depends on another, and goes with it when that one is dropped with CASCADE
+type Dependent struct {
+	Kind string // TABLE, VIEW, MATERIALIZED VIEW, DOMAIN, TYPE, FUNCTION, COLUMN, DEFAULT, ATTRIBUTE, CONSTRAINT, POLICY or TRIGGER
+	Key  string // State key of the object, or of the table or type a column, default, attribute, constraint, policy or trigger belongs to
+	Name string // Name of the column, attribute, constraint, policy or trigger, or the column of a default, empty for other objects
+}
+
+// String describes the dependent the way PostgreSQL does in its errors
+func (d Dependent) String() string {
+	if d.Kind == "DEFAULT" {
+		return fmt.Sprintf("default of column %s of %s", d.Name, d.Key)
+	}
+	if d.Name != "" {
+		return fmt.Sprintf("%s %s of %s", strings.ToLower(d.Kind), d.Name, d.Key)
+	}
+	return strings.ToLower(d.Kind) + " " + d.Key
+}
+
+// castReference matches the type of a :: cast
+var castReference = regexp.MustCompile(`::\s*(` + referencePattern + `)`)
+
+// TableDependents returns the objects that depend on a table or view: the views reading from it,
+// foreign keys referencing it and tables inheriting from it
+// Partitions are dropped along with their parent anyway, so only what depends on them is included
+func (ds *DatabaseState) TableDependents(name string) []Dependent {
+	var dependents []Dependent
+	for _, key := range sortedKeys(ds.Views) {
+		if contains(ds.Views[key].DependsOn, name) {
+			dependents = append(dependents, Dependent{Kind: "VIEW", Key: key})
+		}
+	}
+	for _, key := range sortedKeys(ds.MaterializedViews) {
+		if contains(ds.MaterializedViews[key].DependsOn, name) {
+			dependents = append(dependents, Dependent{Kind: "MATERIALIZED VIEW", Key: key})
+		}
+	}
+
+	for _, key := range sortedKeys(ds.Tables) {
+		table := ds.Tables[key]
+		switch {
+		case key == name || table.PartitionOf == name:
+			continue
+		case contains(table.Inherits, name):
+			dependents = append(dependents, Dependent{Kind: "TABLE", Key: key})
+			continue
+		}
+		for _, fk := range table.ForeignKeys {
+			if fk.ReferencedTable == name {
+				dependents = append(dependents, Dependent{Kind: "CONSTRAINT", Key: key, Name: fk.Name})
+			}
+		}
+	}
+
+	if table, ok := ds.Tables[name]; ok {
+		for _, partition := range table.Partitions {
+			dependents = append(dependents, ds.TableDependents(partition)...)
+		}
+	}
+	return dependents
+}
+
+// ColumnDependents returns the objects that depend on a column of a table: generated columns and policies
+// of the table using it, foreign keys referencing it and views reading from the table that use it
+// Indexes and constraints of the table itself go with the column anyway
+// Also returns the views where an unqualified reference may mean the column or one of another table
+// resolve turns each table reference as written into a schema-qualified state key
+func (ds *DatabaseState) ColumnDependents(tableName, column string, resolve func(ref string) string) ([]Dependent, []string) {
+	var dependents []Dependent
+	var ambiguous []string
+	useColumn := func(kind, key, definition string, dependsOn []string) {
+		if !contains(dependsOn, tableName) {
+			return
+		}
+		switch use := queryColumnUse(definition, resolve, tableName, column); {
+		case len(use.refs) > 0 || use.star:
+			dependents = append(dependents, Dependent{Kind: kind, Key: key})
+		case use.ambiguous:
+			ambiguous = append(ambiguous, key)
+		}
+	}
+	for _, key := range sortedKeys(ds.Views) {
+		view := ds.Views[key]
+		useColumn("VIEW", key, view.Definition, view.DependsOn)
+	}
+	for _, key := range sortedKeys(ds.MaterializedViews) {
+		mview := ds.MaterializedViews[key]
+		useColumn("MATERIALIZED VIEW", key, mview.Definition, mview.DependsOn)
+	}
+
+	// A foreign key without referenced columns references the primary key
+	var primaryKey []string
+	if table, ok := ds.Tables[tableName]; ok {
+		if table.PrimaryKey != nil {
+			primaryKey = table.PrimaryKey.Columns
+		}
+		for _, colName := range table.ColumnOrder {
+			if generated := table.Columns[colName].Generated; colName != column && containsColumn(expressionIdentifiers(generated), column) {
+				dependents = append(dependents, Dependent{Kind: "COLUMN", Key: tableName, Name: colName})
+			}
+		}
+		for _, policy := range table.Policies {
+			if containsColumn(expressionIdentifiers(policy.Using), column) || containsColumn(expressionIdentifiers(policy.WithCheck), column) {
+				dependents = append(dependents, Dependent{Kind: "POLICY", Key: tableName, Name: policy.Name})
+			}
+		}
+	}
+	for _, key := range sortedKeys(ds.Tables) {
+		for _, fk := range ds.Tables[key].ForeignKeys {
+			referenced := fk.ReferencedColumns
+			if len(referenced) == 0 {
+				referenced = primaryKey
+			}
+			if fk.ReferencedTable != tableName || !containsColumn(referenced, column) || (key == tableName && containsColumn(fk.Columns, column)) {
+				continue
+			}
+			dependents = append(dependents, Dependent{Kind: "CONSTRAINT", Key: key, Name: fk.Name})
+		}
+	}
+	return dependents, ambiguous
+}
+
+// ConstraintDependents returns the foreign keys that reference the columns of a primary key or unique constraint
+func (ds *DatabaseState) ConstraintDependents(tableName, constraint string) []Dependent {
+	table, ok := ds.Tables[tableName]
+	if !ok {
+		return nil
+	}
+
+	var columns []string
+	isPrimaryKey := table.PrimaryKey != nil && table.PrimaryKey.Name == constraint
+	if isPrimaryKey {
+		columns = table.PrimaryKey.Columns
+	}
+	for _, unique := range table.Uniques {
+		if unique.Name == constraint {
+			columns = unique.Columns
+		}
+	}
+	if len(columns) == 0 {
+		return nil
+	}
+
+	var dependents []Dependent
+	for _, key := range sortedKeys(ds.Tables) {
+		for _, fk := range ds.Tables[key].ForeignKeys {
+			if fk.ReferencedTable != tableName {
+				continue
+			}
+			if (len(fk.ReferencedColumns) == 0 && isPrimaryKey) || sameColumns(fk.ReferencedColumns, columns) {
+				dependents = append(dependents, Dependent{Kind: "CONSTRAINT", Key: key, Name: fk.Name})
+			}
+		}
+	}
+	return dependents
+}
+
+// TypeDependents returns the objects that depend on an enum, domain, composite or range type: columns and
+// attributes of that type, functions taking or returning it, domains and range types over it, and checks
+// casting to it
+// resolve turns a type name as written into a schema-qualified state key
+func (ds *DatabaseState) TypeDependents(name string, resolve func(ref string) string) []Dependent {
+	usesType := func(typ string) bool {
+		ref, _ := splitTypeReference(typ)
+		return ref != "" && resolve(ref) == name
+	}
+
+	var dependents []Dependent
+	for _, key := range sortedKeys(ds.Tables) {
+		table := ds.Tables[key]
+		for _, colName := range table.ColumnOrder {
+			if usesType(table.Columns[colName].Type) {
+				dependents = append(dependents, Dependent{Kind: "COLUMN", Key: key, Name: colName})
+			}
+		}
+		for _, check := range table.Checks {
+			for _, match := range castReference.FindAllStringSubmatch(stringLiteral.ReplaceAllString(check.Expression, "''"), -1) {
+				if resolve(match[1]) == name {
+					dependents = append(dependents, Dependent{Kind: "CONSTRAINT", Key: key, Name: check.Name})
+					break
+				}
+			}
+		}
+	}
+	for _, key := range sortedKeys(ds.Functions) {
+		if ds.Functions[key].UsesType(resolve, name) {
+			dependents = append(dependents, Dependent{Kind: "FUNCTION", Key: key})
+		}
+	}
+	for _, key := range sortedKeys(ds.CompositeTypes) {
+		for _, attr := range ds.CompositeTypes[key].Attributes {
+			if usesType(attr.Type) {
+				dependents = append(dependents, Dependent{Kind: "ATTRIBUTE", Key: key, Name: attr.Name})
+			}
+		}
+	}
+	for _, key := range sortedKeys(ds.Domains) {
+		if usesType(ds.Domains[key].BaseType) {
+			dependents = append(dependents, Dependent{Kind: "DOMAIN", Key: key})
+		}
+	}
+	for _, key := range sortedKeys(ds.RangeTypes) {
+		if subtype := ds.RangeTypes[key].Option("SUBTYPE"); subtype != "" && usesType(subtype) {
+			dependents = append(dependents, Dependent{Kind: "TYPE", Key: key})
+		}
+	}
+	return dependents
+}
+
+// FunctionDependents returns the objects that depend on a function: triggers executing it, and column
+// defaults, generated columns, checks and views calling it
+// Calls are matched by name, so they only count when the function has no other overloads
+// resolve turns a function name as written into a schema-qualified state key
+func (ds *DatabaseState) FunctionDependents(key string, resolve func(ref string) string) []Dependent {
+	fn, ok := ds.Functions[key]
+	if !ok {
+		return nil
+	}
+
+	name := fn.QualifiedName()
+	overloaded := len(ds.FunctionOverloads(name)) > 1
+	calls := func(expr string) bool {
+		if overloaded {
+			return false
+		}
+		for _, call := range FunctionCalls(stringLiteral.ReplaceAllString(expr, "''")) {
+			if resolve(call) == name {
+				return true
+			}
+		}
+		return false
+	}
+
+	var dependents []Dependent
+	for _, tableKey := range sortedKeys(ds.Tables) {
+		table := ds.Tables[tableKey]
+		for _, colName := range table.ColumnOrder {
+			col := table.Columns[colName]
+			switch {
+			case calls(col.Generated):
+				dependents = append(dependents, Dependent{Kind: "COLUMN", Key: tableKey, Name: colName})
+			case calls(col.Default):
+				dependents = append(dependents, Dependent{Kind: "DEFAULT", Key: tableKey, Name: colName})
+			}
+		}
+		for _, check := range table.Checks {
+			if calls(check.Expression) {
+				dependents = append(dependents, Dependent{Kind: "CONSTRAINT", Key: tableKey, Name: check.Name})
+			}
+		}
+
+		// Trigger functions take no arguments
+		for _, trigger := range table.Triggers {
+			if trigger.Function == name && fn.Signature == "" {
+				dependents = append(dependents, Dependent{Kind: "TRIGGER", Key: tableKey, Name: trigger.Name})
+			}
+		}
+	}
+	for _, viewKey := range sortedKeys(ds.Views) {
+		if calls(ds.Views[viewKey].Definition) {
+			dependents = append(dependents, Dependent{Kind: "VIEW", Key: viewKey})
+		}
+	}
+	for _, viewKey := range sortedKeys(ds.MaterializedViews) {
+		if calls(ds.MaterializedViews[viewKey].Definition) {
+			dependents = append(dependents, Dependent{Kind: "MATERIALIZED VIEW", Key: viewKey})
+		}
+	}
+	return dependents
+}
+
+// SequenceDependents returns the column defaults that take their values from a sequence
+// resolve turns a sequence name as written into a schema-qualified state key
+func (ds *DatabaseState) SequenceDependents(key string, resolve func(ref string) string) []Dependent {
+	var dependents []Dependent
+	for _, tableKey := range sortedKeys(ds.Tables) {
+		table := ds.Tables[tableKey]
+		for _, colName := range table.ColumnOrder {
+			for _, ref := range NextvalReferences(table.Columns[colName].Default) {
+				if resolve(ref) == key {
+					dependents = append(dependents, Dependent{Kind: "DEFAULT", Key: tableKey, Name: colName})
+					break
+				}
+			}
+		}
+	}
+	return dependents
+}
+
+// ExtensionDependents returns the objects that depend on the types an extension provides
+// Only the extensions in KnownExtensions are known to provide any
+// resolve turns a type name as written into a schema-qualified state key
+func (ds *DatabaseState) ExtensionDependents(name string, resolve func(ref string) string) []Dependent {
+	schema := DefaultSchema
+	if ext, ok := ds.Extensions[name]; ok && ext.Schema != "" {
+		schema = ext.Schema
+	}
+
+	var dependents []Dependent
+	for _, typ := range KnownExtensions[name].Types {
+		dependents = append(dependents, ds.TypeDependents(QualifiedName(schema, typ), resolve)...)
+	}
+	return dependents
+}
+
+// DropDependents drops the given dependents, and in turn whatever depends on them
+// resolve turns a type name as written into a schema-qualified state key
+func (ds *DatabaseState) DropDependents(dependents []Dependent, resolve func(ref string) string) {
+	for _, dependent := range dependents {
+		switch dependent.Kind {
+		case "TABLE":
+			if _, ok := ds.Tables[dependent.Key]; ok {
+				ds.DropDependents(ds.TableDependents(dependent.Key), resolve)
+				ds.DropTable(dependent.Key)
+			}
+		case "VIEW":
+			if _, ok := ds.Views[dependent.Key]; ok {
+				ds.DropDependents(ds.TableDependents(dependent.Key), resolve)
+				ds.DropView(dependent.Key)
+			}
+		case "MATERIALIZED VIEW":
+			if _, ok := ds.MaterializedViews[dependent.Key]; ok {
+				ds.DropDependents(ds.TableDependents(dependent.Key), resolve)
+				ds.DropMaterializedView(dependent.Key)
+			}
+		case "DOMAIN":
+			if _, ok := ds.Domains[dependent.Key]; ok {
+				ds.DropDependents(ds.TypeDependents(dependent.Key, resolve), resolve)
+				ds.DropDomain(dependent.Key)
+			}
+		case "TYPE":
+			if _, ok := ds.RangeTypes[dependent.Key]; ok {
+				ds.DropDependents(ds.TypeDependents(dependent.Key, resolve), resolve)
+				ds.DropRangeType(dependent.Key)
+			}
+		case "FUNCTION":
+			if _, ok := ds.Functions[dependent.Key]; ok {
+				ds.DropDependents(ds.FunctionDependents(dependent.Key, resolve), resolve)
+				ds.DropFunction(dependent.Key)
+			}
+		case "DEFAULT":
+			if table, ok := ds.Tables[dependent.Key]; ok && table.Columns[dependent.Name] != nil {
+				table.Columns[dependent.Name].Default = ""
+			}
+		case "TRIGGER":
+			if table, ok := ds.Tables[dependent.Key]; ok {
+				table.DropTrigger(dependent.Name)
+			}
+		case "COLUMN":
+			if table, ok := ds.Tables[dependent.Key]; ok {
+				columnDependents, _ := ds.ColumnDependents(dependent.Key, dependent.Name, resolve)
+				ds.DropDependents(columnDependents, resolve)
+				for _, partition := range ds.AllPartitions(table) {
+					ds.DropColumn(partition.QualifiedName(), dependent.Name)
+				}
+				ds.DropColumn(dependent.Key, dependent.Name)
+			}
+		case "ATTRIBUTE":
+			if ct, ok := ds.CompositeTypes[dependent.Key]; ok {
+				ct.DropAttribute(dependent.Name)
+			}
+		case "CONSTRAINT":
+			if table, ok := ds.Tables[dependent.Key]; ok {
+				ds.DropDependents(ds.ConstraintDependents(dependent.Key, dependent.Name), resolve)
+				table.DropConstraint(dependent.Name)
+			}
+		case "POLICY":
+			if table, ok := ds.Tables[dependent.Key]; ok {
+				table.DropPolicy(dependent.Name)
+			}
+		}
+	}
+}
+
+// DropColumn drops a column of a table, along with the indexes that use it and the sequences it owns
+func (ds *DatabaseState) DropColumn(tableName, column string) {
+	table, ok := ds.Tables[tableName]
+	if !ok {
+		return
+	}
+
+	for _, idx := range table.Indexes {
+		if idx.ReferencesColumn(column) {
+			ds.DropIndex(idx.QualifiedName())
+		}
+	}
+	table.DropColumn(column)
+	ds.DropOwnedSequences(tableName, column)
+}
+
+// sameColumns checks if two column lists hold the same columns, in any order
+func sameColumns(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for _, col := range a {
+		if !containsColumn(b, col) {
+			return false
+		}
+	}
+	return true
+}
+
+// sortedKeys returns the keys of a map in sorted order
+func sortedKeys[T any](objects map[string]T) []string {
+	keys := make([]string, 0, len(objects))
+	for key := range objects {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
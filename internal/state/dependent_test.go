@@ -0,0 +1,53 @@
+package state
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestDropDependentsOfRoutinesSequencesAndExtensions(t *testing.T) {
+	ds := NewDatabaseState()
+	ds.AddOrUpdateEnum(NewEnum(DefaultSchema, "mood"))
+	ds.AddOrUpdateExtension(NewExtension("citext"))
+
+	table := NewTable(DefaultSchema, "t")
+	table.AddColumn(&Column{Name: "id", Type: "int", Default: "nextval('s1')"})
+	table.AddColumn(&Column{Name: "name", Type: "citext"})
+	table.AddTrigger(&Trigger{Name: "t_trg", Function: "public.trg"})
+	ds.AddOrUpdateTable(table)
+
+	ds.AddOrUpdateFunction(NewFunction(DefaultSchema, "trg", ""))
+	fm := NewFunction(DefaultSchema, "fm", "mood")
+	fm.Arguments, fm.Returns = "m mood", "int"
+	ds.AddOrUpdateFunction(fm)
+
+	tests := []struct {
+		name       string
+		dependents []Dependent
+		want       []string
+	}{
+		{"function", ds.FunctionDependents("public.trg()", resolvePublic), []string{"trigger t_trg of public.t"}},
+		{"sequence", ds.SequenceDependents("public.s1", resolvePublic), []string{"default of column id of public.t"}},
+		{"type", ds.TypeDependents("public.mood", resolvePublic), []string{"function public.fm(mood)"}},
+		{"extension", ds.ExtensionDependents("citext", resolvePublic), []string{"column name of public.t"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got []string
+			for _, dependent := range tt.dependents {
+				got = append(got, dependent.String())
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("dependents = %q, want %q", got, tt.want)
+			}
+			ds.DropDependents(tt.dependents, resolvePublic)
+		})
+	}
+
+	if len(table.Triggers) != 0 || table.Columns["id"].Default != "" || table.Columns["name"] != nil {
+		t.Errorf("table still has dependents: triggers %v, default %q, columns %v", table.Triggers, table.Columns["id"].Default, table.ColumnOrder)
+	}
+	if _, ok := ds.GetFunction("public.fm(mood)"); ok {
+		t.Error("function public.fm(mood) not dropped")
+	}
+}
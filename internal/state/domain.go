@@ -1,18 +1,85 @@
 package state
 
+import (
+	"strconv"
+)
+
 // Domain represents a PostgreSQL domain type
 type Domain struct {
+	Schema      string
+	Name        string
+	BaseType    string
+	Collation   string // As written, empty for the default
+	Default     string
+	NotNull     bool
+	Constraints []*DomainConstraint
+	Privileges  Privileges
+	Comment     string
+	CreatedIn   int
+}
+
+// DomainConstraint represents a CHECK constraint of a domain
+type DomainConstraint struct {
 	Name       string
-	BaseType   string
-	Default    string
-	Constraint string
-	Comment    string
-	CreatedIn  int
+	Expression string // Without the surrounding parentheses
+	NotValid   bool
 }
 
 // NewDomain creates a new domain
-func NewDomain(name string) *Domain {
+func NewDomain(schema, name string) *Domain {
 	return &Domain{
-		Name: name,
+		Schema:      schema,
+		Name:        name,
+		Constraints: []*DomainConstraint{},
+	}
+}
+
+// QualifiedName returns the schema-qualified domain name
+func (d *Domain) QualifiedName() string {
+	return QualifiedName(d.Schema, d.Name)
+}
+
+// DefaultCheckName returns the name PostgreSQL assigns to the first unnamed check constraint of the domain
+func (d *Domain) DefaultCheckName() string {
+	return d.Name + "_check"
+}
+
+// AddConstraint adds a check constraint
+// Unnamed constraints get the name PostgreSQL would give them, so they can be dropped by name later
+func (d *Domain) AddConstraint(constraint *DomainConstraint) {
+	if constraint.Name == "" {
+		constraint.Name = d.DefaultCheckName()
+		for i := 1; d.hasConstraint(constraint.Name); i++ {
+			constraint.Name = d.DefaultCheckName() + strconv.Itoa(i)
+		}
 	}
+	d.Constraints = append(d.Constraints, constraint)
+}
+
+// GetConstraint returns the check constraint with the given name
+func (d *Domain) GetConstraint(name string) (*DomainConstraint, bool) {
+	for _, constraint := range d.Constraints {
+		if constraint.Name == name {
+			return constraint, true
+		}
+	}
+	return nil, false
+}
+
+// DropConstraint removes the check constraint with the given name
+// Returns false when the domain has no such constraint
+func (d *Domain) DropConstraint(name string) bool {
+	for i, constraint := range d.Constraints {
+		if constraint.Name == name {
+			d.Constraints = append(d.Constraints[:i], d.Constraints[i+1:]...)
+			return true
+		}
+	}
+	return false
+}
+
+// hasConstraint reports whether the domain has a check constraint with the given name
+func (d *Domain) hasConstraint(name string) bool {
+	_, ok := d.GetConstraint(name)
+	return ok
 }
@@ -1,23 +1,44 @@
 package state
 
+import (
+	"regexp"
+	"strings"
+)
+
+// Where a literal is compared against a column or cast, around the literal
+var (
+	enumComparedBefore = regexp.MustCompile(`(` + referencePattern + `)\s*(?:=|<>|!=|<=|>=|<|>)\s*$`)
+	enumComparedAfter  = regexp.MustCompile(`^\s*(?:=|<>|!=|<=|>=|<|>)\s*(` + referencePattern + `)`)
+	enumInListBefore   = regexp.MustCompile(`(?i)(` + referencePattern + `)\s+(?:NOT\s+)?IN\s*\(\s*(?:'(?:[^']|'')*'\s*,\s*)*$`)
+	enumCastAfter      = regexp.MustCompile(`^\s*::\s*(` + referencePattern + `)`)
+)
+
 // Enum represents a PostgreSQL enum type
 type Enum struct {
+	Schema      string
 	Name        string
 	Values      []string
+	Privileges  Privileges
 	TypeComment string
 	CreatedIn   int
 	UsedBy      []string
 }
 
 // NewEnum creates a new enum
-func NewEnum(name string) *Enum {
+func NewEnum(schema, name string) *Enum {
 	return &Enum{
+		Schema: schema,
 		Name:   name,
 		Values: []string{},
 		UsedBy: []string{},
 	}
 }
 
+// QualifiedName returns the schema-qualified enum name
+func (e *Enum) QualifiedName() string {
+	return QualifiedName(e.Schema, e.Name)
+}
+
 // AddValue adds a value to the enum
 func (e *Enum) AddValue(value string) {
 	// Check if value already exists
@@ -29,6 +50,48 @@ func (e *Enum) AddValue(value string) {
 	e.Values = append(e.Values, value)
 }
 
+// InsertValue adds a value next to an existing one, before it or after it
+// Returns false, leaving the enum unchanged, when the neighbor is not a value of the enum
+func (e *Enum) InsertValue(value, neighbor string, after bool) bool {
+	if e.HasValue(value) {
+		return true
+	}
+
+	for i, v := range e.Values {
+		if v != neighbor {
+			continue
+		}
+		if after {
+			i++
+		}
+		e.Values = append(e.Values[:i], append([]string{value}, e.Values[i:]...)...)
+		return true
+	}
+	return false
+}
+
+// HasValue reports whether the enum has the given value
+func (e *Enum) HasValue(value string) bool {
+	for _, v := range e.Values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
+// RenameValue renames a value, keeping its position
+// Returns false when the enum has no such value
+func (e *Enum) RenameValue(oldValue, newValue string) bool {
+	for i, v := range e.Values {
+		if v == oldValue {
+			e.Values[i] = newValue
+			return true
+		}
+	}
+	return false
+}
+
 // AddUsedBy records that a table uses this enum
 func (e *Enum) AddUsedBy(tableName string) {
 	for _, t := range e.UsedBy {
@@ -38,3 +101,63 @@ func (e *Enum) AddUsedBy(tableName string) {
 	}
 	e.UsedBy = append(e.UsedBy, tableName)
 }
+
+// renameEnumLiteral rewrites oldLiteral to newLiteral where it is cast to the enum or compared against one
+// of the columns of the enum
+// typed reports whether the expression itself is of the enum, as the default of such a column is
+// Returns false when the literal is left alone somewhere
+func renameEnumLiteral(expr, oldLiteral, newLiteral string, columns []string, isEnum func(ref string) bool, typed bool) (string, bool) {
+	isColumn := func(ref string) bool {
+		names := expressionIdentifiers(ref)
+		return len(names) > 0 && contains(columns, names[len(names)-1])
+	}
+
+	var result strings.Builder
+	last, resolved := 0, true
+	for _, loc := range stringLiteral.FindAllStringIndex(expr, -1) {
+		if expr[loc[0]:loc[1]] != oldLiteral {
+			continue
+		}
+
+		before, after := expr[:loc[0]], expr[loc[1]:]
+		var enumValue bool
+		if match := enumCastAfter.FindStringSubmatch(after); match != nil {
+			enumValue = isEnum(match[1])
+		} else if typed && strings.TrimSpace(before) == "" && strings.TrimSpace(after) == "" {
+			enumValue = true
+		} else if match := enumComparedBefore.FindStringSubmatch(before); match != nil && isColumn(match[1]) {
+			enumValue = true
+		} else if match := enumComparedAfter.FindStringSubmatch(after); match != nil && isColumn(match[1]) {
+			enumValue = true
+		} else if match := enumInListBefore.FindStringSubmatch(before); match != nil && isColumn(match[1]) {
+			enumValue = true
+		}
+
+		if !enumValue {
+			resolved = false
+			continue
+		}
+		result.WriteString(expr[last:loc[0]])
+		result.WriteString(newLiteral)
+		last = loc[1]
+	}
+	result.WriteString(expr[last:])
+
+	return result.String(), resolved
+}
+
+// mentionsEnum reports whether an expression uses one of the columns of an enum or casts to it
+func mentionsEnum(expr string, columns []string, isEnum func(ref string) bool) bool {
+	masked := stringLiteral.ReplaceAllString(expr, "''")
+	for _, name := range expressionIdentifiers(masked) {
+		if contains(columns, name) {
+			return true
+		}
+	}
+	for _, match := range castReference.FindAllStringSubmatch(masked, -1) {
+		if isEnum(match[1]) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,116 @@
+package state
+
+import (
+	"strings"
+)
+
+// Extension represents an installed extension
+// Extensions are database-wide, so they are keyed by name alone
+type Extension struct {
+	Name      string
+	Schema    string // Schema the extension's objects are created in, empty for the default
+	Version   string // Empty for the default version
+	Cascade   bool   // Install required extensions as well
+	Comment   string
+	CreatedIn int
+}
+
+// NewExtension creates a new extension
+func NewExtension(name string) *Extension {
+	return &Extension{Name: name}
+}
+
+// ExtensionObjects lists the objects an extension provides that migrations commonly use
+type ExtensionObjects struct {
+	Types           []string
+	Functions       []string
+	OperatorClasses []string
+}
+
+// KnownExtensions lists what the commonly used contrib and third-party extensions provide
+var KnownExtensions = map[string]ExtensionObjects{
+	"citext": {
+		Types:           []string{"citext"},
+		OperatorClasses: []string{"citext_ops", "citext_pattern_ops"},
+	},
+	"hstore": {
+		Types:           []string{"hstore"},
+		Functions:       []string{"hstore", "akeys", "avals", "skeys", "svals", "hstore_to_json", "hstore_to_jsonb"},
+		OperatorClasses: []string{"gin_hstore_ops", "gist_hstore_ops"},
+	},
+	"ltree": {
+		Types:           []string{"ltree", "lquery", "ltxtquery"},
+		Functions:       []string{"subltree", "subpath", "nlevel", "text2ltree", "ltree2text", "lca"},
+		OperatorClasses: []string{"gist_ltree_ops", "gist__ltree_ops"},
+	},
+	"cube": {
+		Types:     []string{"cube"},
+		Functions: []string{"cube", "cube_dim", "cube_distance", "cube_union", "cube_inter"},
+	},
+	"earthdistance": {
+		Types:     []string{"earth"},
+		Functions: []string{"ll_to_earth", "earth_distance", "earth_box"},
+	},
+	"isn": {
+		Types: []string{"ean13", "isbn", "isbn13", "ismn", "ismn13", "issn", "issn13", "upc"},
+	},
+	"pgcrypto": {
+		Functions: []string{"gen_random_uuid", "gen_random_bytes", "crypt", "gen_salt", "digest", "hmac", "encrypt", "decrypt", "pgp_sym_encrypt", "pgp_sym_decrypt", "pgp_pub_encrypt", "pgp_pub_decrypt", "armor", "dearmor"},
+	},
+	"uuid-ossp": {
+		Functions: []string{"uuid_generate_v1", "uuid_generate_v1mc", "uuid_generate_v3", "uuid_generate_v4", "uuid_generate_v5", "uuid_nil", "uuid_ns_dns", "uuid_ns_url", "uuid_ns_oid", "uuid_ns_x500"},
+	},
+	"pg_trgm": {
+		Functions:       []string{"similarity", "word_similarity", "strict_word_similarity", "show_trgm", "show_limit", "set_limit"},
+		OperatorClasses: []string{"gin_trgm_ops", "gist_trgm_ops"},
+	},
+	"btree_gist": {
+		OperatorClasses: []string{"gist_int2_ops", "gist_int4_ops", "gist_int8_ops", "gist_float4_ops", "gist_float8_ops", "gist_timestamp_ops", "gist_timestamptz_ops", "gist_text_ops", "gist_uuid_ops", "gist_enum_ops"},
+	},
+	"unaccent": {
+		Functions: []string{"unaccent"},
+	},
+	"fuzzystrmatch": {
+		Functions: []string{"levenshtein", "levenshtein_less_equal", "soundex", "difference", "metaphone", "dmetaphone", "dmetaphone_alt", "daitch_mokotoff"},
+	},
+	"intarray": {
+		Functions:       []string{"icount", "sort_asc", "sort_desc", "intset"},
+		OperatorClasses: []string{"gist__int_ops", "gist__intbig_ops", "gin__int_ops"},
+	},
+	"postgis": {
+		Types:           []string{"geometry", "geography", "box2d", "box3d"},
+		Functions:       []string{"st_makepoint", "st_setsrid", "st_geomfromtext", "st_geogfromtext", "st_geomfromgeojson", "st_distance", "st_dwithin", "st_contains", "st_intersects", "st_within", "st_transform", "st_point", "st_x", "st_y", "st_area", "st_length", "st_buffer"},
+		OperatorClasses: []string{"gist_geometry_ops_2d", "gist_geometry_ops_nd", "gist_geography_ops", "brin_geometry_inclusion_ops_2d"},
+	},
+	"vector": {
+		Types:           []string{"vector", "halfvec", "sparsevec"},
+		Functions:       []string{"cosine_distance", "l2_distance", "inner_product", "l1_distance", "vector_dims", "vector_norm", "l2_normalize"},
+		OperatorClasses: []string{"vector_l2_ops", "vector_ip_ops", "vector_cosine_ops", "vector_l1_ops", "halfvec_l2_ops", "halfvec_ip_ops", "halfvec_cosine_ops"},
+	},
+}
+
+// ExtensionProviding returns the extension that provides a type, function or operator class
+// kind is "type", "function" or "opclass"; the name is matched without its schema, case-insensitively
+// Only the extensions in KnownExtensions are consulted, and no name appears under two of them
+func ExtensionProviding(kind, name string) (string, bool) {
+	if i := strings.LastIndex(name, "."); i != -1 {
+		name = name[i+1:]
+	}
+	name = strings.ToLower(strings.Trim(name, `"`))
+
+	for ext, objects := range KnownExtensions {
+		var names []string
+		switch kind {
+		case "type":
+			names = objects.Types
+		case "function":
+			names = objects.Functions
+		case "opclass":
+			names = objects.OperatorClasses
+		}
+		if contains(names, name) {
+			return ext, true
+		}
+	}
+	return "", false
+}
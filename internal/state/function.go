@@ -0,0 +1,189 @@
+package state
+
+import (
+	"regexp"
+	"strings"
+)
+
+// Function represents a function or procedure
+// Overloads share a name, so functions are keyed by name and argument signature
+type Function struct {
+	Schema     string
+	Name       string
+	Kind       string // FUNCTION or PROCEDURE
+	Arguments  string // Argument list as written
+	Signature  string // Canonical argument types, e.g. "integer, text"
+	Returns    string // Empty for procedures
+	Language   string
+	Volatility string   // IMMUTABLE, STABLE or VOLATILE, empty for the default
+	Security   string   // DEFINER or INVOKER, empty for the default
+	SetOptions []string // SET configuration clauses
+	Attributes []string // Other clauses such as STRICT or PARALLEL SAFE
+	Body       string   // AS clause or BEGIN ATOMIC ... END block
+	DependsOn  []string // Tables and views a SQL-language body reads, which must exist first
+	Privileges Privileges
+	Comment    string
+	CreatedIn  int
+}
+
+// NewFunction creates a new function
+func NewFunction(schema, name, signature string) *Function {
+	return &Function{
+		Schema:    schema,
+		Name:      name,
+		Kind:      "FUNCTION",
+		Signature: signature,
+		DependsOn: []string{},
+	}
+}
+
+// QualifiedName returns the schema-qualified function name, without the signature
+func (f *Function) QualifiedName() string {
+	return QualifiedName(f.Schema, f.Name)
+}
+
+// Key returns the state key of the function, its qualified name followed by its signature
+func (f *Function) Key() string {
+	return FunctionKey(f.QualifiedName(), f.Signature)
+}
+
+// FunctionKey returns the state key for a function's qualified name and signature
+func FunctionKey(qualifiedName, signature string) string {
+	return qualifiedName + "(" + signature + ")"
+}
+
+// functionCallPattern matches a possibly schema-qualified name followed by an opening parenthesis
+var functionCallPattern = regexp.MustCompile(`(` + referencePattern + `)\s*\(`)
+
+// FunctionCalls returns the names called as functions in an expression, as written
+// String literals should be masked first, so names inside them are not reported
+func FunctionCalls(expr string) []string {
+	var calls []string
+	for _, match := range functionCallPattern.FindAllStringSubmatch(expr, -1) {
+		if !contains(calls, match[1]) {
+			calls = append(calls, match[1])
+		}
+	}
+	return calls
+}
+
+var (
+	// Parts of argument lists and RETURNS clauses, matched against text with string literals blanked
+	argumentMode    = regexp.MustCompile(`(?i)^\s*(?:IN|OUT|INOUT|VARIADIC)\s+`)
+	argumentDefault = regexp.MustCompile(`(?i)\s+DEFAULT\s|=`)
+	returnsSetof    = regexp.MustCompile(`(?i)^\s*SETOF\s+`)
+	returnsTable    = regexp.MustCompile(`(?i)^\s*TABLE\s*\(`)
+)
+
+// RenameType rewrites the argument and return types of the function that name a renamed type,
+// and its signature along with them
+// resolve turns a type name as written into a schema-qualified state key
+func (f *Function) RenameType(resolve func(ref string) string, oldKey, newKey string) {
+	f.mapTypes(func(typ string) string {
+		return renameTypeReference(typ, resolve, oldKey, newKey, false)
+	})
+
+	// Signatures hold the canonical type names, unquoted
+	_, newName := SplitQualifiedName(newKey)
+	types := strings.Split(f.Signature, ", ")
+	for i, typ := range types {
+		name, array := strings.CutSuffix(typ, "[]")
+		schema, found := "", false
+		if dot := strings.Index(name, "."); dot != -1 {
+			schema, name, found = name[:dot+1], name[dot+1:], true
+		}
+		ref := QuoteIdentifier(name)
+		if found {
+			ref = QuoteIdentifier(schema[:len(schema)-1]) + "." + ref
+		}
+		if typ == "" || resolve(ref) != oldKey {
+			continue
+		}
+		types[i] = schema + newName
+		if array {
+			types[i] += "[]"
+		}
+	}
+	f.Signature = strings.Join(types, ", ")
+}
+
+// UsesType reports whether one of the argument or return types of the function names the given type
+// resolve turns a type name as written into a schema-qualified state key
+func (f *Function) UsesType(resolve func(ref string) string, key string) bool {
+	uses := false
+	f.mapTypes(func(typ string) string {
+		ref, _ := splitTypeReference(typ)
+		uses = uses || (ref != "" && resolve(ref) == key)
+		return typ
+	})
+	return uses
+}
+
+// mapTypes replaces each argument and return type of the function with what rename makes of it
+func (f *Function) mapTypes(rename func(typ string) string) {
+	f.Arguments = mapArgumentTypes(f.Arguments, rename)
+
+	masked := stringLiteral.ReplaceAllStringFunc(f.Returns, func(literal string) string {
+		return strings.Repeat(" ", len(literal))
+	})
+	switch {
+	case returnsTable.MatchString(masked):
+		open := strings.Index(masked, "(")
+		end := strings.LastIndex(masked, ")")
+		if end > open {
+			f.Returns = f.Returns[:open+1] + mapArgumentTypes(f.Returns[open+1:end], rename) + f.Returns[end:]
+		}
+	case returnsSetof.MatchString(masked):
+		prefix := returnsSetof.FindString(masked)
+		f.Returns = prefix + rename(f.Returns[len(prefix):])
+	case f.Returns != "":
+		f.Returns = rename(f.Returns)
+	}
+}
+
+// mapArgumentTypes replaces the type of each argument in a list with what rename makes of it
+// Each argument is [mode] [name] type [DEFAULT value], and names are told from types by what follows them
+func mapArgumentTypes(arguments string, rename func(typ string) string) string {
+	masked := stringLiteral.ReplaceAllStringFunc(arguments, func(literal string) string {
+		return strings.Repeat(" ", len(literal))
+	})
+
+	var result strings.Builder
+	start, depth := 0, 0
+	for i := 0; i <= len(masked); i++ {
+		if i < len(masked) {
+			switch masked[i] {
+			case '(':
+				depth++
+			case ')':
+				depth--
+			}
+			if masked[i] != ',' || depth != 0 {
+				continue
+			}
+		}
+
+		arg, argMasked := arguments[start:i], masked[start:i]
+		end := len(arg)
+		if loc := argumentDefault.FindStringIndex(argMasked); loc != nil {
+			end = loc[0]
+		}
+		begin := len(argumentMode.FindString(argMasked))
+
+		// A lone type is followed by nothing but its modifiers, otherwise the first word is the argument name
+		decl := arg[begin:end]
+		if name, rest := splitTypeReference(decl); strings.TrimSpace(rest) != "" && !strings.ContainsAny(strings.TrimSpace(rest)[:1], "([") {
+			offset := strings.Index(decl, name) + len(name)
+			decl = decl[:offset] + rename(decl[offset:])
+		} else if strings.TrimSpace(decl) != "" {
+			decl = rename(decl)
+		}
+
+		result.WriteString(arg[:begin] + decl + arg[end:])
+		if i < len(masked) {
+			result.WriteString(",")
+		}
+		start = i + 1
+	}
+	return result.String()
+}
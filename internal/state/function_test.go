@@ -0,0 +1,91 @@
+package state
+
+import (
+	"strings"
+	"testing"
+)
+
+// resolvePublic resolves unqualified names to the default schema
+func resolvePublic(ref string) string {
+	names := expressionIdentifiers(ref)
+	if len(names) == 2 {
+		return QualifiedName(names[0], names[1])
+	}
+	return QualifiedName(DefaultSchema, strings.Join(names, "."))
+}
+
+func TestFunctionRenameType(t *testing.T) {
+	tests := []struct {
+		name          string
+		arguments     string
+		signature     string
+		returns       string
+		wantArguments string
+		wantSignature string
+		wantReturns   string
+	}{
+		{
+			name:          "lone type and return type",
+			arguments:     "status",
+			signature:     "status",
+			returns:       "status",
+			wantArguments: "user_status",
+			wantSignature: "user_status",
+			wantReturns:   "user_status",
+		},
+		{
+			name:          "argument named like the type, array and default",
+			arguments:     "IN status status, fallback public.status[] DEFAULT '{status}', n int",
+			signature:     "status, public.status[], integer",
+			returns:       "SETOF status",
+			wantArguments: "IN status user_status, fallback public.user_status[] DEFAULT '{status}', n int",
+			wantSignature: "user_status, public.user_status[], integer",
+			wantReturns:   "SETOF user_status",
+		},
+		{
+			name:          "table result and other types",
+			arguments:     "amount numeric(10, 2), label double precision",
+			signature:     "numeric, double precision",
+			returns:       "TABLE (s status, t text)",
+			wantArguments: "amount numeric(10, 2), label double precision",
+			wantSignature: "numeric, double precision",
+			wantReturns:   "TABLE (s user_status, t text)",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fn := NewFunction(DefaultSchema, "f", tt.signature)
+			fn.Arguments, fn.Returns = tt.arguments, tt.returns
+			fn.RenameType(resolvePublic, "public.status", "public.user_status")
+
+			if fn.Arguments != tt.wantArguments {
+				t.Errorf("Arguments = %q, want %q", fn.Arguments, tt.wantArguments)
+			}
+			if fn.Signature != tt.wantSignature {
+				t.Errorf("Signature = %q, want %q", fn.Signature, tt.wantSignature)
+			}
+			if fn.Returns != tt.wantReturns {
+				t.Errorf("Returns = %q, want %q", fn.Returns, tt.wantReturns)
+			}
+		})
+	}
+}
+
+func TestRenameTypeRekeysFunctions(t *testing.T) {
+	ds := NewDatabaseState()
+	ds.AddOrUpdateEnum(NewEnum(DefaultSchema, "status"))
+	fn := NewFunction(DefaultSchema, "is_new", "status")
+	fn.Arguments, fn.Returns = "x status", "boolean"
+	ds.AddOrUpdateFunction(fn)
+
+	if !ds.RenameType("public.status", "user_status", resolvePublic) {
+		t.Fatal("RenameType() = false, want true")
+	}
+	if _, ok := ds.GetFunction("public.is_new(status)"); ok {
+		t.Error("function still keyed by the old type")
+	}
+	if got, ok := ds.GetFunction("public.is_new(user_status)"); !ok || got.Arguments != "x user_status" {
+		t.Errorf("GetFunction(public.is_new(user_status)) = %+v, %v", got, ok)
+	}
+}
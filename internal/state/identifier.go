@@ -0,0 +1,130 @@
+package state
+
+import (
+	"regexp"
+	"strings"
+)
+
+// plainIdentifier matches identifiers PostgreSQL accepts without quoting
+var plainIdentifier = regexp.MustCompile(`^[a-z_][a-z0-9_$]*$`)
+
+// reservedKeywords are keywords that cannot be used as unquoted column or table names
+var reservedKeywords = map[string]bool{
+	"all": true, "analyse": true, "analyze": true, "and": true, "any": true,
+	"array": true, "as": true, "asc": true, "asymmetric": true, "authorization": true,
+	"binary": true, "both": true, "case": true, "cast": true, "check": true,
+	"collate": true, "collation": true, "column": true, "concurrently": true, "constraint": true,
+	"create": true, "cross": true, "current_catalog": true, "current_date": true, "current_role": true,
+	"current_schema": true, "current_time": true, "current_timestamp": true, "current_user": true, "default": true,
+	"deferrable": true, "desc": true, "distinct": true, "do": true, "else": true,
+	"end": true, "except": true, "false": true, "fetch": true, "for": true,
+	"foreign": true, "freeze": true, "from": true, "full": true, "grant": true,
+	"group": true, "having": true, "ilike": true, "in": true, "initially": true,
+	"inner": true, "intersect": true, "into": true, "is": true, "isnull": true,
+	"join": true, "lateral": true, "leading": true, "left": true, "like": true,
+	"limit": true, "localtime": true, "localtimestamp": true, "natural": true, "not": true,
+	"notnull": true, "null": true, "offset": true, "on": true, "only": true,
+	"or": true, "order": true, "outer": true, "overlaps": true, "placing": true,
+	"primary": true, "references": true, "returning": true, "right": true, "select": true,
+	"session_user": true, "similar": true, "some": true, "symmetric": true, "system_user": true,
+	"table": true, "tablesample": true, "then": true, "to": true, "trailing": true,
+	"true": true, "union": true, "unique": true, "user": true, "using": true,
+	"variadic": true, "verbose": true, "when": true, "where": true, "window": true,
+	"with": true,
+}
+
+// NeedsQuoting reports whether an identifier must be quoted to keep its exact spelling
+func NeedsQuoting(name string) bool {
+	return !plainIdentifier.MatchString(name) || reservedKeywords[name]
+}
+
+// QuoteIdentifier quotes an identifier for output when PostgreSQL requires it
+func QuoteIdentifier(name string) string {
+	if !NeedsQuoting(name) {
+		return name
+	}
+	return `"` + strings.ReplaceAll(name, `"`, `""`) + `"`
+}
+
+// QuoteIdentifiers quotes each identifier in a list for output
+func QuoteIdentifiers(names []string) []string {
+	quoted := make([]string, len(names))
+	for i, name := range names {
+		quoted[i] = QuoteIdentifier(name)
+	}
+	return quoted
+}
+
+// QuoteRole quotes a role for output, leaving PUBLIC, CURRENT_USER, CURRENT_ROLE and SESSION_USER as keywords
+func QuoteRole(role string) string {
+	switch role {
+	case "PUBLIC", "CURRENT_USER", "CURRENT_ROLE", "SESSION_USER":
+		return role
+	}
+	return QuoteIdentifier(role)
+}
+
+// SplitQualifiedName splits a schema-qualified state key into its schema and name
+func SplitQualifiedName(key string) (string, string) {
+	if idx := strings.Index(key, "."); idx != -1 {
+		return key[:idx], key[idx+1:]
+	}
+	return DefaultSchema, key
+}
+
+// typeReferencePattern matches the possibly schema-qualified name at the start of a type, and the rest of it
+var typeReferencePattern = regexp.MustCompile(`(?s)^(\s*)(` + referencePattern + `)(.*)$`)
+
+// splitTypeReference splits a type as written into its name and the rest, such as type modifiers or []
+func splitTypeReference(typ string) (name, rest string) {
+	matches := typeReferencePattern.FindStringSubmatch(typ)
+	if matches == nil {
+		return "", typ
+	}
+	return matches[2], matches[3]
+}
+
+// renameCastReferences rewrites the :: casts of an expression to the renamed type
+// resolve turns a type name as written into a schema-qualified state key
+func renameCastReferences(expr string, resolve func(ref string) string, oldKey, newKey string) string {
+	masked := stringLiteral.ReplaceAllStringFunc(expr, func(literal string) string {
+		return strings.Repeat(" ", len(literal))
+	})
+
+	var result strings.Builder
+	last := 0
+	for _, loc := range castReference.FindAllStringSubmatchIndex(masked, -1) {
+		ref := expr[loc[2]:loc[3]]
+		if renamed := renameTypeReference(ref, resolve, oldKey, newKey, false); renamed != ref {
+			result.WriteString(expr[last:loc[2]])
+			result.WriteString(renamed)
+			last = loc[3]
+		}
+	}
+	result.WriteString(expr[last:])
+
+	return result.String()
+}
+
+// renameTypeReference rewrites a type as written when it names the renamed type
+// resolve turns the type name as written into a schema-qualified state key
+// The new reference is qualified if the old one was, or if qualify is set
+func renameTypeReference(typ string, resolve func(ref string) string, oldKey, newKey string, qualify bool) string {
+	matches := typeReferencePattern.FindStringSubmatch(typ)
+	if matches == nil || resolve(matches[2]) != oldKey {
+		return typ
+	}
+
+	// Keep the reference qualified only if it was written that way
+	schema, name := SplitQualifiedName(newKey)
+	renamed := QuoteIdentifier(name)
+	if qualify || strings.Contains(matches[2], ".") {
+		renamed = QuoteIdentifier(schema) + "." + renamed
+	}
+	return matches[1] + renamed + matches[3]
+}
+
+// quoteLiteral quotes a value as an SQL string constant
+func quoteLiteral(value string) string {
+	return "'" + strings.ReplaceAll(value, "'", "''") + "'"
+}
@@ -0,0 +1,105 @@
+package state
+
+import (
+	"regexp"
+	"strings"
+)
+
+// functionName matches the name of a function call at the start of an index expression
+var functionName = regexp.MustCompile(`^\s*(?:"(?:[^"]|"")+"|[A-Za-z_][\w$]*)\s*\.\s*("(?:[^"]|"")+"|[A-Za-z_][\w$]*)\s*\(|^\s*("(?:[^"]|"")+"|[A-Za-z_][\w$]*)\s*\(`)
+
+// Copy returns a copy of the index that shares no slices with it
+func (i *Index) Copy() *Index {
+	copied := *i
+	copied.Elements = append([]IndexElement(nil), i.Elements...)
+	copied.Include = append([]string(nil), i.Include...)
+	copied.Params = append([]string(nil), i.Params...)
+	return &copied
+}
+
+// ColumnNames returns the key columns of the index
+// The second result is false when any key is an expression
+func (i *Index) ColumnNames() ([]string, bool) {
+	columns := make([]string, 0, len(i.Elements))
+	for _, element := range i.Elements {
+		if element.Column == "" {
+			return nil, false
+		}
+		columns = append(columns, element.Column)
+	}
+	return columns, true
+}
+
+// Expressions returns the expressions of the index that may reference other objects
+// These are key expressions, operator classes and the partial index predicate
+func (i *Index) Expressions() []string {
+	var exprs []string
+	for _, element := range i.Elements {
+		for _, expr := range []string{element.Expression, element.Collation, element.Opclass} {
+			if expr != "" {
+				exprs = append(exprs, expr)
+			}
+		}
+	}
+	if i.Where != "" {
+		exprs = append(exprs, i.Where)
+	}
+	return exprs
+}
+
+// ReferencesColumn checks if the index uses a column as key, in an expression, as INCLUDE column or in its predicate
+func (i *Index) ReferencesColumn(name string) bool {
+	for _, element := range i.Elements {
+		if element.Column == name || containsColumn(expressionIdentifiers(element.Expression), name) {
+			return true
+		}
+	}
+	return containsColumn(i.Include, name) || containsColumn(expressionIdentifiers(i.Where), name)
+}
+
+// RenameColumn replaces every reference to a column in the index
+func (i *Index) RenameColumn(oldName, newName string) {
+	for j := range i.Elements {
+		element := &i.Elements[j]
+		if element.Column == oldName {
+			element.Column = newName
+		}
+		if element.Expression != "" {
+			element.Expression = renameExpressionIdentifier(element.Expression, oldName, newName)
+		}
+	}
+	renameInList(i.Include, oldName, newName)
+	if i.Where != "" {
+		i.Where = renameExpressionIdentifier(i.Where, oldName, newName)
+	}
+}
+
+// DefaultName returns the name PostgreSQL assigns to an unnamed index on the given table
+// Expressions are named after the function they call, or expr, and INCLUDE columns are part of the name
+func (i *Index) DefaultName(tableName string) string {
+	parts := []string{tableName}
+	for _, element := range i.Elements {
+		parts = append(parts, element.name())
+	}
+	parts = append(parts, i.Include...)
+	return strings.Join(parts, "_") + "_idx"
+}
+
+// name returns the name PostgreSQL derives from an index element
+func (e IndexElement) name() string {
+	if e.Column != "" {
+		return e.Column
+	}
+
+	expression := strings.TrimSpace(e.Expression)
+	for strings.HasPrefix(expression, "(") && strings.HasSuffix(expression, ")") {
+		expression = strings.TrimSpace(expression[1 : len(expression)-1])
+	}
+	if match := functionName.FindStringSubmatch(expression); match != nil {
+		name := match[1] + match[2]
+		if names := expressionIdentifiers(name); len(names) == 1 {
+			return names[0]
+		}
+	}
+	return "expr"
+}
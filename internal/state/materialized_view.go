@@ -0,0 +1,50 @@
+package state
+
+// MaterializedView represents a materialized view
+// Unlike a plain view it stores its rows, so it can carry indexes of its own
+type MaterializedView struct {
+	Schema     string
+	Name       string
+	Definition string // Full view SQL, without the WITH [NO] DATA clause
+	WithData   bool   // False when created WITH NO DATA
+	Indexes    []*Index
+	DependsOn  []string
+	SearchPath []string // Search path the definition was written against, nil for the default path
+	Privileges Privileges
+	Comment    string
+	CreatedIn  int
+}
+
+// NewMaterializedView creates a new materialized view
+func NewMaterializedView(schema, name string) *MaterializedView {
+	return &MaterializedView{
+		Schema:    schema,
+		Name:      name,
+		WithData:  true,
+		Indexes:   []*Index{},
+		DependsOn: []string{},
+	}
+}
+
+// QualifiedName returns the schema-qualified materialized view name
+func (m *MaterializedView) QualifiedName() string {
+	return QualifiedName(m.Schema, m.Name)
+}
+
+// ExtractDependencies analyzes the view definition to find the tables and views it reads from
+// resolve turns each reference as written into a schema-qualified state key
+func (m *MaterializedView) ExtractDependencies(resolve func(ref string) string) {
+	m.DependsOn = TableReferences(m.Definition, resolve)
+}
+
+// RenameDependency rewrites FROM and JOIN references to a renamed table or view
+// resolve turns each reference as written into a schema-qualified state key
+func (m *MaterializedView) RenameDependency(resolve func(ref string) string, oldKey, newKey string) {
+	m.Definition = renameTableReferences(m.Definition, resolve, oldKey, newKey)
+	renameInList(m.DependsOn, oldKey, newKey)
+}
+
+// AddIndex adds an index to the materialized view
+func (m *MaterializedView) AddIndex(idx *Index) {
+	m.Indexes = append(m.Indexes, idx)
+}
@@ -0,0 +1,93 @@
+package state
+
+// IsPartitioned reports whether the table is partitioned with PARTITION BY
+func (t *Table) IsPartitioned() bool {
+	return t.PartitionStrategy != ""
+}
+
+// IsPartition reports whether the table is a partition of another table
+func (t *Table) IsPartition() bool {
+	return t.PartitionOf != ""
+}
+
+// AttachPartition makes a table a partition of a partitioned table, with the given FOR VALUES clause or DEFAULT
+// The parent may be unknown, e.g. when no migration creates it
+func (ds *DatabaseState) AttachPartition(parentName, childName, bound string) {
+	child, ok := ds.Tables[childName]
+	if !ok {
+		return
+	}
+
+	if child.IsPartition() {
+		ds.DetachPartition(child.PartitionOf, childName)
+	}
+	child.PartitionOf = parentName
+	child.PartitionBound = bound
+	if parent, ok := ds.Tables[parentName]; ok && !contains(parent.Partitions, childName) {
+		parent.Partitions = append(parent.Partitions, childName)
+	}
+}
+
+// DetachPartition turns a partition into a table of its own
+// A partition created with PARTITION OF takes its columns and checks from the parent, so they are copied to it
+func (ds *DatabaseState) DetachPartition(parentName, childName string) {
+	child, ok := ds.Tables[childName]
+	if !ok || child.PartitionOf != parentName {
+		return
+	}
+
+	if parent := ds.PartitionRoot(child); len(child.ColumnOrder) == 0 && parent != child {
+		for _, colName := range parent.ColumnOrder {
+			col := *parent.Columns[colName]
+			col.IdentityOptions = append([]string(nil), col.IdentityOptions...)
+			col.Options = append([]string(nil), col.Options...)
+			child.AddColumn(&col)
+		}
+		for _, check := range parent.Checks {
+			copied := *check
+			child.Checks = append(child.Checks, &copied)
+		}
+	}
+
+	child.PartitionOf = ""
+	child.PartitionBound = ""
+	if parent, ok := ds.Tables[parentName]; ok {
+		parent.Partitions = removeFromList(parent.Partitions, childName)
+	}
+}
+
+// PartitionRoot returns the table a partition takes its columns from: the nearest ancestor
+// that has columns of its own, or the table itself when it is not a partition or has its own columns
+func (ds *DatabaseState) PartitionRoot(table *Table) *Table {
+	for len(table.ColumnOrder) == 0 && table.IsPartition() {
+		parent, ok := ds.Tables[table.PartitionOf]
+		if !ok {
+			break
+		}
+		table = parent
+	}
+	return table
+}
+
+// AllPartitions returns the partitions below a table at any depth, parents before their own partitions
+func (ds *DatabaseState) AllPartitions(table *Table) []*Table {
+	var partitions []*Table
+	for _, name := range table.Partitions {
+		if child, ok := ds.Tables[name]; ok {
+			partitions = append(partitions, child)
+			partitions = append(partitions, ds.AllPartitions(child)...)
+		}
+	}
+	return partitions
+}
+
+// removeFromList returns the list without the given item
+func removeFromList(list []string, item string) []string {
+	var kept []string
+	for _, existing := range list {
+		if existing != item {
+			kept = append(kept, existing)
+		}
+	}
+	return kept
+}
@@ -0,0 +1,59 @@
+package state
+
+// Policy represents a row-level security policy on a table
+// Policies always live with their table
+type Policy struct {
+	Name        string
+	Restrictive bool     // AS RESTRICTIVE, otherwise permissive
+	Command     string   // ALL, SELECT, INSERT, UPDATE or DELETE
+	Roles       []string // Roles the policy applies to, empty for PUBLIC
+	Using       string   // USING expression without parentheses
+	WithCheck   string   // WITH CHECK expression without parentheses
+}
+
+// AddPolicy adds a policy, replacing an existing policy of the same name
+func (t *Table) AddPolicy(policy *Policy) {
+	for i, existing := range t.Policies {
+		if existing.Name == policy.Name {
+			t.Policies[i] = policy
+			return
+		}
+	}
+	t.Policies = append(t.Policies, policy)
+}
+
+// GetPolicy returns the policy with the given name
+func (t *Table) GetPolicy(name string) (*Policy, bool) {
+	for _, policy := range t.Policies {
+		if policy.Name == name {
+			return policy, true
+		}
+	}
+	return nil, false
+}
+
+// DropPolicy removes the policy with the given name
+// Returns false when the table has no such policy
+func (t *Table) DropPolicy(name string) bool {
+	for i, policy := range t.Policies {
+		if policy.Name == name {
+			t.Policies = append(t.Policies[:i], t.Policies[i+1:]...)
+			return true
+		}
+	}
+	return false
+}
+
+// SetRowSecurity applies ENABLE, DISABLE, FORCE or NO FORCE ROW LEVEL SECURITY
+func (t *Table) SetRowSecurity(state string) {
+	switch state {
+	case "ENABLE":
+		t.RowSecurity = true
+	case "DISABLE":
+		t.RowSecurity = false
+	case "FORCE":
+		t.ForceSecurity = true
+	case "NO FORCE":
+		t.ForceSecurity = false
+	}
+}
@@ -0,0 +1,316 @@
+package state
+
+import "sort"
+
+// Grant is a privilege held by a role on an object, or on one column of a table
+type Grant struct {
+	Grantee     string // Role, or PUBLIC for every role
+	Privilege   string // Upper-case privilege, e.g. SELECT, EXECUTE or USAGE
+	Column      string // Column the privilege is limited to, empty for the whole object
+	GrantOption bool   // WITH GRANT OPTION
+}
+
+// Grants is the list of privileges granted on an object, in the order they were first granted
+type Grants []*Grant
+
+// Privileges is the owner of an object and the privileges granted on it
+// Privileges live with their object, so they follow renames and go away with drops
+type Privileges struct {
+	Owner  string // Owning role, empty for the role running the migrations
+	Grants Grants
+}
+
+// DefaultPrivileges is what ALTER DEFAULT PRIVILEGES grants on objects created later
+type DefaultPrivileges struct {
+	Role       string // FOR ROLE, empty for the role running the migrations
+	Schema     string // IN SCHEMA, empty for every schema
+	ObjectType string // TABLES, SEQUENCES, FUNCTIONS, TYPES or SCHEMAS
+	Grants     Grants // For every schema the full list, including what PostgreSQL grants PUBLIC
+}
+
+// privilegeClasses maps object kinds to the kind whose privileges they take
+var privilegeClasses = map[string]string{
+	"TABLE":             "TABLE",
+	"VIEW":              "TABLE",
+	"MATERIALIZED VIEW": "TABLE",
+	"SEQUENCE":          "SEQUENCE",
+	"FUNCTION":          "FUNCTION",
+	"PROCEDURE":         "FUNCTION",
+	"TYPE":              "TYPE",
+	"DOMAIN":            "TYPE",
+	"SCHEMA":            "SCHEMA",
+}
+
+// privilegeNames are the privileges ALL grants on each kind, in the order they are written out
+var privilegeNames = map[string][]string{
+	"TABLE":    {"SELECT", "INSERT", "UPDATE", "DELETE", "TRUNCATE", "REFERENCES", "TRIGGER"},
+	"SEQUENCE": {"USAGE", "SELECT", "UPDATE"},
+	"FUNCTION": {"EXECUTE"},
+	"TYPE":     {"USAGE"},
+	"SCHEMA":   {"USAGE", "CREATE"},
+}
+
+// publicPrivileges are the privileges PostgreSQL grants PUBLIC on every new object of a kind
+var publicPrivileges = map[string][]string{
+	"FUNCTION": {"EXECUTE"},
+	"TYPE":     {"USAGE"},
+}
+
+// PrivilegeClass returns the kind whose privileges an object kind takes, e.g. TABLE for VIEW
+func PrivilegeClass(kind string) string {
+	return privilegeClasses[kind]
+}
+
+// PrivilegeNames returns the privileges ALL grants on an object of the given kind
+func PrivilegeNames(kind string) []string {
+	return privilegeNames[PrivilegeClass(kind)]
+}
+
+// PublicPrivileges returns the privileges PostgreSQL grants PUBLIC on a new object of the given kind
+func PublicPrivileges(kind string) []string {
+	return publicPrivileges[PrivilegeClass(kind)]
+}
+
+// Find returns the grant of a privilege to a role, on a column or on the whole object
+func (g Grants) Find(grantee, privilege, column string) *Grant {
+	for _, grant := range g {
+		if grant.Grantee == grantee && grant.Privilege == privilege && grant.Column == column {
+			return grant
+		}
+	}
+	return nil
+}
+
+// Grant adds a privilege; granting one already held can only add the grant option
+func (g *Grants) Grant(grantee, privilege, column string, grantOption bool) {
+	if existing := g.Find(grantee, privilege, column); existing != nil {
+		existing.GrantOption = existing.GrantOption || grantOption
+		return
+	}
+	*g = append(*g, &Grant{Grantee: grantee, Privilege: privilege, Column: column, GrantOption: grantOption})
+}
+
+// Revoke removes a privilege, or only its grant option when grantOptionOnly is set
+// Revoking a privilege on the whole object revokes it on every column as well, as PostgreSQL does
+func (g *Grants) Revoke(grantee, privilege, column string, grantOptionOnly bool) {
+	kept := (*g)[:0]
+	for _, grant := range *g {
+		matches := grant.Grantee == grantee && grant.Privilege == privilege &&
+			(grant.Column == column || column == "")
+		switch {
+		case !matches:
+			kept = append(kept, grant)
+		case grantOptionOnly:
+			grant.GrantOption = false
+			kept = append(kept, grant)
+		}
+	}
+	*g = kept
+}
+
+// RenameColumn updates the column privileges of a renamed column
+func (g Grants) RenameColumn(oldName, newName string) {
+	for _, grant := range g {
+		if grant.Column == oldName {
+			grant.Column = newName
+		}
+	}
+}
+
+// DropColumn removes the privileges on a dropped column
+func (g *Grants) DropColumn(name string) {
+	kept := (*g)[:0]
+	for _, grant := range *g {
+		if grant.Column != name {
+			kept = append(kept, grant)
+		}
+	}
+	*g = kept
+}
+
+// Copy returns a copy of the grants that can be changed independently
+func (g Grants) Copy() Grants {
+	copied := make(Grants, len(g))
+	for i, grant := range g {
+		c := *grant
+		copied[i] = &c
+	}
+	return copied
+}
+
+// publicGrants returns the grants PostgreSQL makes on a new object of the given kind
+func publicGrants(kind string) Grants {
+	var grants Grants
+	for _, privilege := range PublicPrivileges(kind) {
+		grants.Grant("PUBLIC", privilege, "", false)
+	}
+	return grants
+}
+
+// findDefaultPrivileges returns the ALTER DEFAULT PRIVILEGES entry for a role, schema and object type
+func (ds *DatabaseState) findDefaultPrivileges(role, schema, objectType string) *DefaultPrivileges {
+	for _, dp := range ds.DefaultPrivileges {
+		if dp.Role == role && dp.Schema == schema && dp.ObjectType == objectType {
+			return dp
+		}
+	}
+	return nil
+}
+
+// GetDefaultPrivileges returns the ALTER DEFAULT PRIVILEGES entry for a role, schema and object type,
+// adding one that changes nothing yet when there is none
+func (ds *DatabaseState) GetDefaultPrivileges(role, schema, objectType string) *DefaultPrivileges {
+	if dp := ds.findDefaultPrivileges(role, schema, objectType); dp != nil {
+		return dp
+	}
+
+	// Entries for a schema only add to those for every schema, so they start out empty
+	dp := &DefaultPrivileges{Role: role, Schema: schema, ObjectType: objectType}
+	if schema == "" {
+		dp.Grants = publicGrants(DefaultPrivilegesKind(objectType))
+	}
+	ds.DefaultPrivileges = append(ds.DefaultPrivileges, dp)
+	return dp
+}
+
+// DefaultPrivilegesKind returns the object kind of an ALTER DEFAULT PRIVILEGES object type, e.g. TABLE for TABLES
+func DefaultPrivilegesKind(objectType string) string {
+	if objectType == "ROUTINES" {
+		return "FUNCTION"
+	}
+	return objectType[:len(objectType)-1]
+}
+
+// NewPrivileges returns the privileges a new object of the given kind in a schema starts out with:
+// what PostgreSQL grants PUBLIC, as changed by ALTER DEFAULT PRIVILEGES for the role running the migrations
+func (ds *DatabaseState) NewPrivileges(kind, schema string) Privileges {
+	objectType := PrivilegeClass(kind) + "S"
+
+	grants := publicGrants(kind)
+	if dp := ds.findDefaultPrivileges("", "", objectType); dp != nil {
+		grants = dp.Grants.Copy()
+	}
+	if dp := ds.findDefaultPrivileges("", schema, objectType); dp != nil && schema != "" {
+		for _, grant := range dp.Grants {
+			grants.Grant(grant.Grantee, grant.Privilege, grant.Column, grant.GrantOption)
+		}
+	}
+
+	return Privileges{Grants: grants}
+}
+
+// ObjectPrivileges returns the privileges of an object and the kind of object it is
+// kind is the object type a statement names: TABLE also finds views, materialized views and sequences,
+// TYPE also finds domains, and ROUTINE finds functions and procedures alike
+// Functions are looked up by function key, see Function.Key, and schemas by name
+func (ds *DatabaseState) ObjectPrivileges(kind, key string) (*Privileges, string, bool) {
+	switch kind {
+	case "TABLE", "VIEW", "MATERIALIZED VIEW", "SEQUENCE":
+		if table, ok := ds.Tables[key]; ok && kind == "TABLE" {
+			return &table.Privileges, "TABLE", true
+		}
+		if view, ok := ds.Views[key]; ok && (kind == "TABLE" || kind == "VIEW") {
+			return &view.Privileges, "VIEW", true
+		}
+		if mview, ok := ds.MaterializedViews[key]; ok && (kind == "TABLE" || kind == "MATERIALIZED VIEW") {
+			return &mview.Privileges, "MATERIALIZED VIEW", true
+		}
+		if seq, ok := ds.Sequences[key]; ok && (kind == "TABLE" || kind == "SEQUENCE") {
+			return &seq.Privileges, "SEQUENCE", true
+		}
+	case "TYPE", "DOMAIN":
+		if domain, ok := ds.Domains[key]; ok {
+			return &domain.Privileges, "DOMAIN", true
+		}
+		if kind == "DOMAIN" {
+			break
+		}
+		if enum, ok := ds.Enums[key]; ok {
+			return &enum.Privileges, "TYPE", true
+		}
+		if ct, ok := ds.CompositeTypes[key]; ok {
+			return &ct.Privileges, "TYPE", true
+		}
+		if rt, ok := ds.RangeTypes[key]; ok {
+			return &rt.Privileges, "TYPE", true
+		}
+	case "FUNCTION", "PROCEDURE", "ROUTINE":
+		if fn, ok := ds.Functions[key]; ok && (kind == "ROUTINE" || kind == fn.Kind) {
+			return &fn.Privileges, fn.Kind, true
+		}
+	case "SCHEMA":
+		if schema, ok := ds.Schemas[key]; ok {
+			return &schema.Privileges, "SCHEMA", true
+		}
+	}
+	return nil, "", false
+}
+
+// Roles returns the roles that own objects, hold privileges or are named by policies, sorted
+// PUBLIC, CURRENT_USER and the like name no role of their own and are left out
+func (ds *DatabaseState) Roles() []string {
+	seen := make(map[string]bool)
+	add := func(role string) {
+		switch role {
+		case "", "PUBLIC", "CURRENT_USER", "CURRENT_ROLE", "SESSION_USER":
+			return
+		}
+		seen[role] = true
+	}
+	addPrivileges := func(privileges Privileges) {
+		add(privileges.Owner)
+		for _, grant := range privileges.Grants {
+			add(grant.Grantee)
+		}
+	}
+
+	for _, table := range ds.Tables {
+		addPrivileges(table.Privileges)
+		for _, policy := range table.Policies {
+			for _, role := range policy.Roles {
+				add(role)
+			}
+		}
+	}
+	for _, view := range ds.Views {
+		addPrivileges(view.Privileges)
+	}
+	for _, mview := range ds.MaterializedViews {
+		addPrivileges(mview.Privileges)
+	}
+	for _, seq := range ds.Sequences {
+		addPrivileges(seq.Privileges)
+	}
+	for _, fn := range ds.Functions {
+		addPrivileges(fn.Privileges)
+	}
+	for _, enum := range ds.Enums {
+		addPrivileges(enum.Privileges)
+	}
+	for _, domain := range ds.Domains {
+		addPrivileges(domain.Privileges)
+	}
+	for _, ct := range ds.CompositeTypes {
+		addPrivileges(ct.Privileges)
+	}
+	for _, rt := range ds.RangeTypes {
+		addPrivileges(rt.Privileges)
+	}
+	for _, schema := range ds.Schemas {
+		add(schema.Authorization)
+		addPrivileges(schema.Privileges)
+	}
+	for _, dp := range ds.DefaultPrivileges {
+		add(dp.Role)
+		for _, grant := range dp.Grants {
+			add(grant.Grantee)
+		}
+	}
+
+	roles := make([]string, 0, len(seen))
+	for role := range seen {
+		roles = append(roles, role)
+	}
+	sort.Strings(roles)
+	return roles
+}
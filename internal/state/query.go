@@ -0,0 +1,211 @@
+package state
+
+import (
+	"regexp"
+	"strings"
+)
+
+var (
+	// Queries are matched against text with string literals blanked
+	fromClause        = regexp.MustCompile(`(?i)\b(FROM|JOIN)\s+`)
+	fromReference     = regexp.MustCompile(`^` + referencePattern)
+	fromListSeparator = regexp.MustCompile(`^\s*,\s*`)
+
+	// A column reference is a name qualified with any number of names, or a * selecting every column
+	columnReference = regexp.MustCompile(`(?:(?:"(?:[^"]|"")+"|[A-Za-z_][\w$]*)\s*\.\s*)*(?:"(?:[^"]|"")+"|[A-Za-z_][\w$]*|\*)`)
+	referencePart   = regexp.MustCompile(`"(?:[^"]|"")+"|[A-Za-z_][\w$]*|\*`)
+
+	// What may surround a reference that is not a column: an output name, or a function call
+	outputAlias  = regexp.MustCompile(`(?i)\bAS\s*$`)
+	functionCall = regexp.MustCompile(`^\s*\(`)
+
+	// A select list entry starts after SELECT or a comma, and ends at a comma, FROM or the end of the query
+	selectKeyword  = regexp.MustCompile(`(?i)\bSELECT(?:\s+(?:DISTINCT|ALL))?\s*$`)
+	selectEntryEnd = regexp.MustCompile(`(?i)^\s*(?:,|\bFROM\b|\)|;|$)`)
+	selectListEnd  = regexp.MustCompile(`(?i)\b(?:FROM|WHERE|GROUP|HAVING|ORDER|LIMIT|ON|JOIN)\b`)
+)
+
+// queryTable is a table or view a query reads from
+type queryTable struct {
+	key        string // State key of the table or view
+	alias      string // Name its columns are qualified with: its alias, or its own name without one
+	start, end int    // Offsets of the reference and its alias
+}
+
+// columnUse is how a query uses a column of one of its tables
+type columnUse struct {
+	refs      []columnRef // References that can only mean the column
+	star      bool        // Whether a * or alias.* selects it
+	ambiguous bool        // Whether an unqualified reference may mean it or a column of another table
+}
+
+// columnRef is a reference to a column in a query
+type columnRef struct {
+	start, end  int  // Offsets of the column name, without its qualifier
+	selectEntry bool // Whether it is a select list entry of its own, whose output name is the column name
+}
+
+// queryTables finds the tables and views a query reads from in its FROM and JOIN clauses, with their aliases
+// resolve turns each reference as written into a schema-qualified state key
+func queryTables(masked string, resolve func(ref string) string) []queryTable {
+	var tables []queryTable
+	for _, loc := range fromClause.FindAllStringSubmatchIndex(masked, -1) {
+		pos := loc[1]
+		for {
+			ref := fromReference.FindString(masked[pos:])
+			if ref == "" {
+				break
+			}
+			names := expressionIdentifiers(ref)
+			table := queryTable{key: resolve(ref), alias: names[len(names)-1], start: pos, end: pos + len(ref)}
+			if rest := masked[table.end:]; hasTableAlias(rest) {
+				match := tableAlias.FindStringSubmatchIndex(rest)
+				table.alias = expressionIdentifiers(rest[match[4]:match[5]])[0]
+				table.end += match[1]
+			}
+			tables = append(tables, table)
+
+			// Only FROM lists tables separated by commas
+			sep := fromListSeparator.FindString(masked[table.end:])
+			if sep == "" || !strings.EqualFold(masked[loc[2]:loc[3]], "FROM") {
+				break
+			}
+			pos = table.end + len(sep)
+		}
+	}
+	return tables
+}
+
+// queryColumnUse finds how a query uses a column of a table it reads from
+// Qualified references count when their qualifier is the alias of the table, unqualified ones when the
+// table is the only one the query reads from
+// resolve turns each table reference as written into a schema-qualified state key
+func queryColumnUse(query string, resolve func(ref string) string, tableKey, column string) columnUse {
+	masked := stringLiteral.ReplaceAllStringFunc(query, func(literal string) string {
+		return strings.Repeat(" ", len(literal))
+	})
+
+	var use columnUse
+	tables := queryTables(masked, resolve)
+	aliases := map[string]string{}
+	reads, others := false, false
+	for _, table := range tables {
+		aliases[table.alias] = table.key
+		if table.key == tableKey {
+			reads = true
+		} else {
+			others = true
+		}
+	}
+	if !reads {
+		return use
+	}
+
+	for _, loc := range columnReference.FindAllStringIndex(masked, -1) {
+		if isTableReference(tables, loc[0]) || outputAlias.MatchString(masked[:loc[0]]) || functionCall.MatchString(masked[loc[1]:]) {
+			continue
+		}
+
+		parts := referencePart.FindAllStringIndex(masked[loc[0]:loc[1]], -1)
+		last := parts[len(parts)-1]
+		qualifier := ""
+		if len(parts) > 1 {
+			before := parts[len(parts)-2]
+			qualifier = expressionIdentifiers(masked[loc[0]+before[0] : loc[0]+before[1]])[0]
+		}
+
+		if masked[loc[0]+last[0]:loc[1]] == "*" {
+			if qualifier != "" {
+				use.star = use.star || aliases[qualifier] == tableKey
+			} else {
+				use.star = use.star || isSelectEntry(masked, loc[0], loc[1])
+			}
+			continue
+		}
+		if expressionIdentifiers(masked[loc[0]+last[0] : loc[1]])[0] != column {
+			continue
+		}
+
+		switch {
+		case qualifier != "" && aliases[qualifier] != tableKey:
+			continue
+		case qualifier == "" && others:
+			use.ambiguous = true
+			continue
+		}
+		use.refs = append(use.refs, columnRef{start: loc[0] + last[0], end: loc[1], selectEntry: isSelectEntry(masked, loc[0], loc[1])})
+	}
+	return use
+}
+
+// renameQueryColumn renames the references to a column of a table in a query
+// A select list entry of the column alone is given the old name as alias, so the output keeps its name
+// Returns false when an unqualified reference may also mean a column of another table; those are left as they are
+// resolve turns each table reference as written into a schema-qualified state key
+func renameQueryColumn(query string, resolve func(ref string) string, tableKey, oldName, newName string) (string, bool) {
+	use := queryColumnUse(query, resolve, tableKey, oldName)
+
+	var result strings.Builder
+	last := 0
+	for _, ref := range use.refs {
+		result.WriteString(query[last:ref.start])
+		result.WriteString(QuoteIdentifier(newName))
+		if ref.selectEntry {
+			result.WriteString(" AS " + QuoteIdentifier(oldName))
+		}
+		last = ref.end
+	}
+	result.WriteString(query[last:])
+
+	return result.String(), !use.ambiguous
+}
+
+// isTableReference reports whether an offset falls within a FROM or JOIN reference or its alias
+func isTableReference(tables []queryTable, offset int) bool {
+	for _, table := range tables {
+		if offset >= table.start && offset < table.end {
+			return true
+		}
+	}
+	return false
+}
+
+// isSelectEntry reports whether the text between start and end is a whole select list entry without an alias
+func isSelectEntry(masked string, start, end int) bool {
+	before := strings.TrimRight(masked[:start], " \t\r\n")
+	if !strings.HasSuffix(before, ",") && !selectKeyword.MatchString(before) {
+		return false
+	}
+	if !selectEntryEnd.MatchString(masked[end:]) {
+		return false
+	}
+
+	// Walk back to the SELECT at the same depth, which must come before any other clause
+	depth := 0
+	for i := len(before); i > 0; i-- {
+		switch masked[i-1] {
+		case ')':
+			depth++
+		case '(':
+			if depth == 0 {
+				return false
+			}
+			depth--
+		}
+		if depth != 0 {
+			continue
+		}
+		if selectKeyword.MatchString(masked[:i]) {
+			return true
+		}
+		if loc := selectListEnd.FindStringIndex(masked[i-1:]); loc != nil && loc[0] == 0 && (i == 1 || !isWordByte(masked[i-2])) {
+			return false
+		}
+	}
+	return false
+}
+
+// isWordByte reports whether a byte can be part of an unquoted name
+func isWordByte(b byte) bool {
+	return b == '_' || b == '$' || (b >= '0' && b <= '9') || (b >= 'a' && b <= 'z') || (b >= 'A' && b <= 'Z')
+}
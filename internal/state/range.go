@@ -0,0 +1,40 @@
+package state
+
+import (
+	"strings"
+)
+
+// RangeType represents a range type, CREATE TYPE ... AS RANGE (...)
+type RangeType struct {
+	Schema     string
+	Name       string
+	Options    []string // One entry per option as written, e.g. "SUBTYPE = float8"
+	Privileges Privileges
+	Comment    string
+	CreatedIn  int
+}
+
+// NewRangeType creates a new range type
+func NewRangeType(schema, name string) *RangeType {
+	return &RangeType{
+		Schema:  schema,
+		Name:    name,
+		Options: []string{},
+	}
+}
+
+// QualifiedName returns the schema-qualified type name
+func (r *RangeType) QualifiedName() string {
+	return QualifiedName(r.Schema, r.Name)
+}
+
+// Option returns the value of a range option such as SUBTYPE or SUBTYPE_DIFF, as written
+func (r *RangeType) Option(name string) string {
+	for _, option := range r.Options {
+		key, value, found := strings.Cut(option, "=")
+		if found && strings.EqualFold(strings.TrimSpace(key), name) {
+			return strings.TrimSpace(value)
+		}
+	}
+	return ""
+}
@@ -0,0 +1,51 @@
+package state
+
+import (
+	"strings"
+)
+
+// Schema represents a schema created by the migrations
+// The default schema always exists and is never tracked
+type Schema struct {
+	Name          string
+	Authorization string // Owning role, empty for the role running the migration
+	Privileges    Privileges
+	Comment       string
+	CreatedIn     int
+}
+
+// NewSchema creates a new schema
+func NewSchema(name string) *Schema {
+	return &Schema{Name: name}
+}
+
+// moveObjects rekeys the objects of one state map that live in a renamed schema
+// schemaOf returns the object's schema field so it can be updated in place
+func moveObjects[T any](objects map[string]T, schemaOf func(T) *string, keyOf func(T) string, name, newName string) {
+	for key, obj := range objects {
+		if schema := schemaOf(obj); *schema == name {
+			delete(objects, key)
+			*schema = newName
+			objects[keyOf(obj)] = obj
+		}
+	}
+}
+
+// renameKeySchema moves a schema-qualified state key to a renamed schema
+func renameKeySchema(key, name, newName string) string {
+	if schema, rest := SplitQualifiedName(key); schema == name {
+		return newName + "." + rest
+	}
+	return key
+}
+
+// renameTypeSchema rewrites a type as written when it is qualified with a renamed schema
+// resolve turns the type name as written into a schema-qualified state key
+func renameTypeSchema(typ string, resolve func(ref string) string, name, newName string) string {
+	ref, _ := splitTypeReference(typ)
+	if !strings.Contains(ref, ".") {
+		return typ
+	}
+	key := resolve(ref)
+	return renameTypeReference(typ, resolve, key, renameKeySchema(key, name, newName), false)
+}
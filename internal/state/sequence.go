@@ -0,0 +1,114 @@
+package state
+
+import (
+	"regexp"
+	"strings"
+)
+
+// Sequence represents a standalone sequence, or the implicit sequence of a serial column
+type Sequence struct {
+	Schema     string
+	Name       string
+	Options    []string // One entry per option, e.g. "INCREMENT BY 2"
+	OwnedBy    string   // Schema-qualified key of the owning table, empty if not owned
+	OwnerCol   string   // Owning column, set together with OwnedBy
+	Privileges Privileges
+	Comment    string
+	CreatedIn  int
+}
+
+// NewSequence creates a new sequence
+func NewSequence(schema, name string) *Sequence {
+	return &Sequence{
+		Schema:  schema,
+		Name:    name,
+		Options: []string{},
+	}
+}
+
+// QualifiedName returns the schema-qualified sequence name
+func (s *Sequence) QualifiedName() string {
+	return QualifiedName(s.Schema, s.Name)
+}
+
+// SetOwner records the column that owns the sequence, or clears ownership for an empty table
+func (s *Sequence) SetOwner(tableKey, column string) {
+	s.OwnedBy = tableKey
+	s.OwnerCol = column
+	if tableKey == "" {
+		s.OwnerCol = ""
+	}
+}
+
+// nextvalPattern matches nextval('name') calls, with or without a ::regclass cast
+var nextvalPattern = regexp.MustCompile(`(?i)(\bnextval\s*\(\s*')((?:[^']|'')+)('(?:\s*::\s*regclass)?\s*\))`)
+
+// NextvalReferences returns the sequence names used by nextval() calls in an expression, as written
+func NextvalReferences(expr string) []string {
+	var refs []string
+	for _, match := range nextvalPattern.FindAllStringSubmatch(expr, -1) {
+		refs = append(refs, strings.ReplaceAll(match[2], "''", "'"))
+	}
+	return refs
+}
+
+// renameNextvalReferences rewrites nextval() calls on a renamed sequence
+// resolve turns each sequence name as written into a schema-qualified state key
+// The new reference is qualified if the old one was, or if qualify is set
+func renameNextvalReferences(expr string, resolve func(ref string) string, oldKey, newKey string, qualify bool) string {
+	return nextvalPattern.ReplaceAllStringFunc(expr, func(match string) string {
+		parts := nextvalPattern.FindStringSubmatch(match)
+		ref := strings.ReplaceAll(parts[2], "''", "'")
+		if resolve(ref) != oldKey {
+			return match
+		}
+
+		// Keep the reference qualified only if it was written that way
+		schema, name := SplitQualifiedName(newKey)
+		renamed := QuoteIdentifier(name)
+		if qualify || strings.Contains(ref, ".") {
+			renamed = QuoteIdentifier(schema) + "." + renamed
+		}
+		return parts[1] + strings.ReplaceAll(renamed, "'", "''") + parts[3]
+	})
+}
+
+// serialTypes maps each serial pseudo-type to the integer type of its column
+var serialTypes = map[string]string{
+	"smallserial": "smallint",
+	"serial2":     "smallint",
+	"serial":      "integer",
+	"serial4":     "integer",
+	"bigserial":   "bigint",
+	"serial8":     "bigint",
+}
+
+// integerSerialTypes maps each integer type to the serial pseudo-type of the same size
+var integerSerialTypes = map[string]string{
+	"smallint": "smallserial",
+	"int2":     "smallserial",
+	"integer":  "serial",
+	"int":      "serial",
+	"int4":     "serial",
+	"bigint":   "bigserial",
+	"int8":     "bigserial",
+}
+
+// SerialBaseType returns the integer type behind a serial column type
+// Returns false if the type is not serial
+func SerialBaseType(colType string) (string, bool) {
+	base, ok := serialTypes[strings.ToLower(strings.TrimSpace(colType))]
+	return base, ok
+}
+
+// SerialType returns the serial pseudo-type of the same size as an integer type
+// Returns false if there is none
+func SerialType(colType string) (string, bool) {
+	serial, ok := integerSerialTypes[strings.ToLower(strings.TrimSpace(colType))]
+	return serial, ok
+}
+
+// DefaultSequenceName returns the name PostgreSQL gives the implicit sequence of a serial column
+func (t *Table) DefaultSequenceName(column string) string {
+	return t.Name + "_" + column + "_seq"
+}
@@ -2,6 +2,7 @@ package state
 
 // Table represents a database table with all its properties
 type Table struct {
+	Schema         string
 	Name           string
 	Columns        map[string]*Column
 	ColumnOrder    []string
@@ -10,16 +11,38 @@ type Table struct {
 	Indexes        []*Index
 	Checks         []*CheckConstraint
 	Uniques        []*UniqueConstraint
+	Triggers       []*Trigger
+	RowSecurity    bool // ENABLE ROW LEVEL SECURITY
+	ForceSecurity  bool // FORCE ROW LEVEL SECURITY, which applies the policies to the table owner too
+	Policies       []*Policy
+	Privileges     Privileges
 	TableComment   string
 	ColumnComments map[string]string
 	CreatedIn      int
 	DependsOn      []string
 	RequiredEnums  []string
+
+	// Declarative partitioning
+	PartitionStrategy string   // RANGE, LIST or HASH of PARTITION BY, empty when the table is not partitioned
+	PartitionKey      string   // Columns and expressions of the partition key, as written
+	PartitionOf       string   // Parent of a partition, schema-qualified
+	PartitionBound    string   // FOR VALUES clause of a partition, or DEFAULT
+	Partitions        []string // Partitions of a partitioned table, schema-qualified, in the order they were attached
+
+	// Inheritance, storage and replication
+	Inherits        []string // Parents of INHERITS, schema-qualified
+	Unlogged        bool     // CREATE UNLOGGED TABLE or SET UNLOGGED
+	AccessMethod    string   // USING or SET ACCESS METHOD, empty for the default
+	StorageParams   []string // WITH (...) or SET (...) storage parameters, as name=value
+	Tablespace      string   // TABLESPACE or SET TABLESPACE, empty for the default
+	ReplicaIdentity string   // FULL, NOTHING or INDEX, empty for DEFAULT
+	ReplicaIndex    string   // Index of REPLICA IDENTITY USING INDEX
 }
 
 // NewTable creates a new empty table
-func NewTable(name string) *Table {
+func NewTable(schema, name string) *Table {
 	return &Table{
+		Schema:         schema,
 		Name:           name,
 		Columns:        make(map[string]*Column),
 		ColumnOrder:    []string{},
@@ -27,12 +50,19 @@ func NewTable(name string) *Table {
 		Indexes:        []*Index{},
 		Checks:         []*CheckConstraint{},
 		Uniques:        []*UniqueConstraint{},
+		Triggers:       []*Trigger{},
+		Policies:       []*Policy{},
 		ColumnComments: make(map[string]string),
 		DependsOn:      []string{},
 		RequiredEnums:  []string{},
 	}
 }
 
+// QualifiedName returns the schema-qualified table name
+func (t *Table) QualifiedName() string {
+	return QualifiedName(t.Schema, t.Name)
+}
+
 // AddColumn adds a column to the table
 func (t *Table) AddColumn(col *Column) {
 	if _, exists := t.Columns[col.Name]; !exists {
@@ -55,11 +85,12 @@ func (t *Table) DropColumn(name string) {
 
 	// Remove column comment if exists
 	delete(t.ColumnComments, name)
+	t.Privileges.Grants.DropColumn(name)
 
 	// Remove indexes that reference the dropped column
 	var remainingIndexes []*Index
 	for _, idx := range t.Indexes {
-		if !indexReferencesColumn(idx, name) {
+		if !idx.ReferencesColumn(name) {
 			remainingIndexes = append(remainingIndexes, idx)
 		}
 	}
@@ -73,16 +104,139 @@ func (t *Table) DropColumn(name string) {
 		}
 	}
 	t.Uniques = remainingUniques
+
+	// Remove the primary key if it includes the dropped column
+	if t.PrimaryKey != nil && containsColumn(t.PrimaryKey.Columns, name) {
+		t.PrimaryKey = nil
+	}
+
+	// Remove foreign keys that reference the dropped column
+	var remainingForeignKeys []*ForeignKey
+	for _, fk := range t.ForeignKeys {
+		if !containsColumn(fk.Columns, name) {
+			remainingForeignKeys = append(remainingForeignKeys, fk)
+		}
+	}
+	t.ForeignKeys = remainingForeignKeys
+	t.rebuildDependsOn()
+
+	// Remove check constraints whose expression references the dropped column
+	var remainingChecks []*CheckConstraint
+	for _, check := range t.Checks {
+		if !containsColumn(expressionIdentifiers(check.Expression), name) {
+			remainingChecks = append(remainingChecks, check)
+		}
+	}
+	t.Checks = remainingChecks
+
+	// Remove triggers that fire on updates of the dropped column
+	var remainingTriggers []*Trigger
+	for _, trigger := range t.Triggers {
+		if !containsColumn(trigger.UpdateColumns, name) {
+			remainingTriggers = append(remainingTriggers, trigger)
+		}
+	}
+	t.Triggers = remainingTriggers
 }
 
-// indexReferencesColumn checks if an index references a specific column
-func indexReferencesColumn(idx *Index, colName string) bool {
-	for _, col := range idx.Columns {
-		if col == colName {
-			return true
+// RenameColumn renames a column and every reference to it within the table
+// Constraint names are kept, as PostgreSQL does not rename them either
+func (t *Table) RenameColumn(oldName, newName string) {
+	// A partition without columns of its own still refers to the columns it takes from its parent
+	col, exists := t.Columns[oldName]
+	if !exists && (!t.IsPartition() || len(t.ColumnOrder) > 0) {
+		return
+	}
+
+	if exists {
+		col.Name = newName
+		delete(t.Columns, oldName)
+		t.Columns[newName] = col
+		renameInList(t.ColumnOrder, oldName, newName)
+	}
+	for _, name := range t.ColumnOrder {
+		if other := t.Columns[name]; other.Generated != "" {
+			other.Generated = renameExpressionIdentifier(other.Generated, oldName, newName)
+		}
+	}
+
+	if comment, ok := t.ColumnComments[oldName]; ok {
+		delete(t.ColumnComments, oldName)
+		t.ColumnComments[newName] = comment
+	}
+	t.Privileges.Grants.RenameColumn(oldName, newName)
+	t.PartitionKey = renameExpressionIdentifier(t.PartitionKey, oldName, newName)
+
+	for _, idx := range t.Indexes {
+		idx.RenameColumn(oldName, newName)
+	}
+
+	if t.PrimaryKey != nil {
+		renameInList(t.PrimaryKey.Columns, oldName, newName)
+	}
+	for _, unique := range t.Uniques {
+		renameInList(unique.Columns, oldName, newName)
+	}
+	for _, fk := range t.ForeignKeys {
+		renameInList(fk.Columns, oldName, newName)
+	}
+	for _, check := range t.Checks {
+		check.Expression = renameExpressionIdentifier(check.Expression, oldName, newName)
+	}
+	for _, trigger := range t.Triggers {
+		renameInList(trigger.UpdateColumns, oldName, newName)
+		if trigger.When != "" {
+			trigger.When = renameExpressionIdentifier(trigger.When, oldName, newName)
+		}
+	}
+	for _, policy := range t.Policies {
+		policy.Using = renameExpressionIdentifier(policy.Using, oldName, newName)
+		policy.WithCheck = renameExpressionIdentifier(policy.WithCheck, oldName, newName)
+	}
+}
+
+// tableExpression is an expression of a table, with what it belongs to for diagnostics
+type tableExpression struct {
+	expr  *string
+	owner string
+}
+
+// expressions returns the expressions of the table that may mention its columns, values and types
+func (t *Table) expressions() []tableExpression {
+	var exprs []tableExpression
+	for _, name := range t.ColumnOrder {
+		col := t.Columns[name]
+		exprs = append(exprs,
+			tableExpression{&col.Default, "default of column " + name},
+			tableExpression{&col.Generated, "generated column " + name})
+	}
+	for _, check := range t.Checks {
+		exprs = append(exprs, tableExpression{&check.Expression, "check " + check.Name})
+	}
+	for _, idx := range t.Indexes {
+		for i := range idx.Elements {
+			exprs = append(exprs, tableExpression{&idx.Elements[i].Expression, "index " + idx.Name})
+		}
+		exprs = append(exprs, tableExpression{&idx.Where, "predicate of index " + idx.Name})
+	}
+	for _, policy := range t.Policies {
+		exprs = append(exprs,
+			tableExpression{&policy.Using, "policy " + policy.Name},
+			tableExpression{&policy.WithCheck, "policy " + policy.Name})
+	}
+	for _, trigger := range t.Triggers {
+		exprs = append(exprs, tableExpression{&trigger.When, "trigger " + trigger.Name})
+	}
+	return exprs
+}
+
+// renameInList replaces oldName with newName in place
+func renameInList(list []string, oldName, newName string) {
+	for i, item := range list {
+		if item == oldName {
+			list[i] = newName
 		}
 	}
-	return false
 }
 
 // containsColumn checks if a column list contains a specific column
@@ -102,13 +256,49 @@ func (t *Table) AlterColumn(name string, updates func(*Column)) {
 	}
 }
 
+// SetStorageParams applies name=value storage parameters, replacing existing values of the same name
+func (t *Table) SetStorageParams(params []string) {
+	t.StorageParams = setOptions(t.StorageParams, params)
+}
+
+// ResetStorageParams removes the named storage parameters
+func (t *Table) ResetStorageParams(names []string) {
+	t.StorageParams = resetOptions(t.StorageParams, names)
+}
+
+// SetReplicaIdentity sets REPLICA IDENTITY to DEFAULT, FULL, NOTHING or INDEX, the latter with the index used
+func (t *Table) SetReplicaIdentity(identity, index string) {
+	t.ReplicaIdentity, t.ReplicaIndex = identity, index
+	if identity == "DEFAULT" {
+		t.ReplicaIdentity = ""
+	}
+}
+
+// dropReplicaIndex forgets a dropped index used as replica identity, which leaves the table with NOTHING
+func (t *Table) dropReplicaIndex(name string) {
+	if t.ReplicaIdentity == "INDEX" && t.ReplicaIndex == name {
+		t.SetReplicaIdentity("NOTHING", "")
+	}
+}
+
 // AddIndex adds an index to the table
 func (t *Table) AddIndex(idx *Index) {
 	t.Indexes = append(t.Indexes, idx)
 }
 
+// SetPrimaryKey sets the primary key, naming it the way PostgreSQL would if unnamed
+func (t *Table) SetPrimaryKey(pk *PrimaryKey) {
+	if pk.Name == "" {
+		pk.Name = t.DefaultPrimaryKeyName()
+	}
+	t.PrimaryKey = pk
+}
+
 // AddForeignKey adds a foreign key constraint
 func (t *Table) AddForeignKey(fk *ForeignKey) {
+	if fk.Name == "" {
+		fk.Name = t.DefaultForeignKeyName(fk.Columns)
+	}
 	t.ForeignKeys = append(t.ForeignKeys, fk)
 
 	// Track dependency
@@ -119,11 +309,17 @@ func (t *Table) AddForeignKey(fk *ForeignKey) {
 
 // AddCheck adds a check constraint
 func (t *Table) AddCheck(check *CheckConstraint) {
+	if check.Name == "" {
+		check.Name = t.DefaultCheckName(check.Expression)
+	}
 	t.Checks = append(t.Checks, check)
 }
 
 // AddUnique adds a unique constraint
 func (t *Table) AddUnique(unique *UniqueConstraint) {
+	if unique.Name == "" {
+		unique.Name = t.DefaultUniqueName(unique.Columns)
+	}
 	t.Uniques = append(t.Uniques, unique)
 }
 
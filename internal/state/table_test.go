@@ -0,0 +1,23 @@
+package state
+
+import "testing"
+
+func TestRenameColumnRewritesPolicies(t *testing.T) {
+	table := NewTable(DefaultSchema, "docs")
+	table.AddColumn(&Column{Name: "tenant", Type: "text"})
+	table.Policies = append(table.Policies, &Policy{
+		Name:      "tenant_isolation",
+		Using:     "tenant = current_setting('app.tenant')",
+		WithCheck: "docs.tenant IS NOT NULL AND 'tenant' <> tenant",
+	})
+
+	table.RenameColumn("tenant", "tenant_id")
+
+	policy := table.Policies[0]
+	if want := "tenant_id = current_setting('app.tenant')"; policy.Using != want {
+		t.Errorf("Using = %q, want %q", policy.Using, want)
+	}
+	if want := "docs.tenant_id IS NOT NULL AND 'tenant' <> tenant_id"; policy.WithCheck != want {
+		t.Errorf("WithCheck = %q, want %q", policy.WithCheck, want)
+	}
+}
@@ -0,0 +1,63 @@
+package state
+
+// Trigger represents a trigger on a table
+// Triggers always live in the schema of their table
+type Trigger struct {
+	Name          string
+	Constraint    bool     // CREATE CONSTRAINT TRIGGER
+	Timing        string   // BEFORE, AFTER or INSTEAD OF
+	Events        []string // INSERT, UPDATE, DELETE or TRUNCATE
+	UpdateColumns []string // Columns of UPDATE OF, empty for any column
+	ForEach       string   // ROW or STATEMENT
+	When          string   // Condition of the WHEN clause, without parentheses
+	Options       string   // FROM, DEFERRABLE and REFERENCING clauses, as written
+	Function      string   // Schema-qualified state key of the trigger function
+	Arguments     string   // Arguments passed to the function, as written
+	Enabled       string   // Empty when enabled, otherwise DISABLE, ENABLE REPLICA or ENABLE ALWAYS
+}
+
+// AddTrigger adds a trigger, replacing an existing trigger of the same name
+func (t *Table) AddTrigger(trigger *Trigger) {
+	for i, existing := range t.Triggers {
+		if existing.Name == trigger.Name {
+			t.Triggers[i] = trigger
+			return
+		}
+	}
+	t.Triggers = append(t.Triggers, trigger)
+}
+
+// GetTrigger returns the trigger with the given name
+func (t *Table) GetTrigger(name string) (*Trigger, bool) {
+	for _, trigger := range t.Triggers {
+		if trigger.Name == name {
+			return trigger, true
+		}
+	}
+	return nil, false
+}
+
+// DropTrigger removes the trigger with the given name
+// Returns false when the table has no such trigger
+func (t *Table) DropTrigger(name string) bool {
+	for i, trigger := range t.Triggers {
+		if trigger.Name == name {
+			t.Triggers = append(t.Triggers[:i], t.Triggers[i+1:]...)
+			return true
+		}
+	}
+	return false
+}
+
+// SetTriggerEnabled sets how a trigger fires: "" to enable it, or DISABLE, ENABLE REPLICA or ENABLE ALWAYS
+// The name ALL applies to every trigger and USER to every trigger except constraint triggers
+func (t *Table) SetTriggerEnabled(name, enabled string) bool {
+	found := false
+	for _, trigger := range t.Triggers {
+		if trigger.Name == name || name == "ALL" || (name == "USER" && !trigger.Constraint) {
+			trigger.Enabled = enabled
+			found = true
+		}
+	}
+	return found
+}
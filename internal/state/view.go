@@ -7,51 +7,129 @@ import (
 
 // View represents a database view
 type View struct {
+	Schema     string
 	Name       string
 	Definition string
 	DependsOn  []string
+	SearchPath []string // Search path the definition was written against, nil for the default path
+	Privileges Privileges
 	Comment    string
 	CreatedIn  int
 	Version    int
 }
 
 // NewView creates a new view
-func NewView(name string) *View {
+func NewView(schema, name string) *View {
 	return &View{
+		Schema:    schema,
 		Name:      name,
 		DependsOn: []string{},
 	}
 }
 
+// QualifiedName returns the schema-qualified view name
+func (v *View) QualifiedName() string {
+	return QualifiedName(v.Schema, v.Name)
+}
+
+// referencePattern matches a possibly quoted, possibly schema-qualified name
+const referencePattern = `(?:"(?:[^"]|"")+"|\w+)(?:\s*\.\s*(?:"(?:[^"]|"")+"|\w+))?`
+
 // ExtractDependencies analyzes the view definition to find table/view dependencies
-func (v *View) ExtractDependencies() {
-	v.DependsOn = []string{}
+// resolve turns each reference as written into a schema-qualified state key
+func (v *View) ExtractDependencies(resolve func(ref string) string) {
+	v.DependsOn = TableReferences(v.Definition, resolve)
+}
+
+// TableReferences finds the tables and views a query reads from in its FROM and JOIN clauses
+// resolve turns each reference as written into a schema-qualified state key
+func TableReferences(query string, resolve func(ref string) string) []string {
+	refs := []string{}
 
 	// Look for FROM and JOIN clauses
-	fromRe := regexp.MustCompile(`(?i)\bFROM\s+(\w+)`)
-	joinRe := regexp.MustCompile(`(?i)\bJOIN\s+(\w+)`)
+	fromRe := regexp.MustCompile(`(?i)\bFROM\s+(` + referencePattern + `)`)
+	joinRe := regexp.MustCompile(`(?i)\bJOIN\s+(` + referencePattern + `)`)
 
 	// Find all FROM matches
-	fromMatches := fromRe.FindAllStringSubmatch(v.Definition, -1)
+	fromMatches := fromRe.FindAllStringSubmatch(query, -1)
 	for _, match := range fromMatches {
 		if len(match) >= 2 {
-			tableName := match[1]
-			if !contains(v.DependsOn, tableName) {
-				v.DependsOn = append(v.DependsOn, tableName)
+			tableName := resolve(match[1])
+			if !contains(refs, tableName) {
+				refs = append(refs, tableName)
 			}
 		}
 	}
 
 	// Find all JOIN matches
-	joinMatches := joinRe.FindAllStringSubmatch(v.Definition, -1)
+	joinMatches := joinRe.FindAllStringSubmatch(query, -1)
 	for _, match := range joinMatches {
 		if len(match) >= 2 {
-			tableName := match[1]
-			if !contains(v.DependsOn, tableName) {
-				v.DependsOn = append(v.DependsOn, tableName)
+			tableName := resolve(match[1])
+			if !contains(refs, tableName) {
+				refs = append(refs, tableName)
 			}
 		}
 	}
+
+	return refs
+}
+
+// RenameDependency rewrites FROM and JOIN references to a renamed table or view
+// resolve turns each reference as written into a schema-qualified state key
+func (v *View) RenameDependency(resolve func(ref string) string, oldKey, newKey string) {
+	v.Definition = renameTableReferences(v.Definition, resolve, oldKey, newKey)
+	renameInList(v.DependsOn, oldKey, newKey)
+}
+
+// tableAlias matches the alias that may follow a FROM or JOIN reference
+var tableAlias = regexp.MustCompile(`(?i)^\s+(AS\s+)?("(?:[^"]|"")+"|\w+)`)
+
+// clauseKeywords are the keywords that may follow a FROM or JOIN reference in place of an alias
+var clauseKeywords = map[string]bool{
+	"where": true, "join": true, "inner": true, "left": true, "right": true, "full": true, "cross": true,
+	"natural": true, "on": true, "using": true, "group": true, "having": true, "window": true, "order": true,
+	"limit": true, "offset": true, "fetch": true, "for": true, "union": true, "intersect": true, "except": true,
+	"tablesample": true, "returning": true, "with": true, "lateral": true,
+}
+
+// renameTableReferences rewrites the FROM and JOIN references of a query to a renamed table or view
+// A reference without an alias is aliased with the old name, so columns qualified with it still resolve
+func renameTableReferences(query string, resolve func(ref string) string, oldKey, newKey string) string {
+	refRe := regexp.MustCompile(`(?i)(\b(?:FROM|JOIN)\s+)(` + referencePattern + `)`)
+	_, oldName := SplitQualifiedName(oldKey)
+	schema, name := SplitQualifiedName(newKey)
+
+	var result strings.Builder
+	last := 0
+	for _, loc := range refRe.FindAllStringSubmatchIndex(query, -1) {
+		ref := query[loc[4]:loc[5]]
+		if resolve(ref) != oldKey {
+			continue
+		}
+
+		// Keep the reference qualified only if it was written that way
+		renamed := QuoteIdentifier(name)
+		if strings.Contains(ref, ".") {
+			renamed = QuoteIdentifier(schema) + "." + renamed
+		}
+		if name != oldName && !hasTableAlias(query[loc[5]:]) {
+			renamed += " AS " + QuoteIdentifier(oldName)
+		}
+
+		result.WriteString(query[last:loc[4]])
+		result.WriteString(renamed)
+		last = loc[5]
+	}
+	result.WriteString(query[last:])
+
+	return result.String()
+}
+
+// hasTableAlias reports whether the text after a FROM or JOIN reference starts with an alias
+func hasTableAlias(rest string) bool {
+	match := tableAlias.FindStringSubmatch(rest)
+	return match != nil && (match[1] != "" || !clauseKeywords[strings.ToLower(match[2])])
 }
 
 // SetColumnComment sets a comment for a view column